@@ -0,0 +1,25 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func main() {
+	methods, err := jrpc.LoadPlugin("methods.so")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	manager := jrpc.NewManagerBuilder().
+		SetTimeout(2 * time.Second).
+		AddMethods(methods).
+		Build()
+	http.HandleFunc("/rpc", jrpc.HTTPHandleFunc(&manager))
+	if err := http.ListenAndServe("localhost:8000", nil); err != nil {
+		log.Fatal(err)
+	}
+}
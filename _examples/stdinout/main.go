@@ -48,11 +48,8 @@ func main() {
 
 	ctx := context.Background()
 
-	for {
-		if err := manager.Handle(ctx, stdin, stdout); err != nil {
-			if _, err := stdout.WriteString(err.Error()); err != nil {
-				log.Fatal(err)
-			}
-		}
+	// The malformed requests are replied on stdout, the errors are only logged
+	if err := manager.HandleStream(ctx, stdin, stdout); err != nil {
+		log.Fatal(err)
 	}
 }
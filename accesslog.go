@@ -0,0 +1,150 @@
+package jrpc2go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// AccessRecord is the structured record written by the access log for each request, see
+// ManagerBuilder.SetAccessLog.
+//
+// BytesIn - The size in bytes of the raw params.
+//
+// BytesOut - The size in bytes of the encoded result or error.
+//
+// ErrorCode - The code of the error replied or 0 on success.
+type AccessRecord struct {
+	Time          time.Time
+	Transport     string
+	Method        string
+	ID            string
+	CorrelationID string
+	Duration      time.Duration
+	BytesIn       int
+	BytesOut      int
+	ErrorCode     ErrorCode
+}
+
+// AccessLogEncoder writes an AccessRecord to w, it's the pluggable format of the access log.
+type AccessLogEncoder func(w io.Writer, rec AccessRecord) error
+
+// JSONLinesEncoder writes each record as a JSON object on its own line.
+func JSONLinesEncoder(w io.Writer, rec AccessRecord) error {
+	return json.NewEncoder(w).Encode(struct {
+		Time          string    `json:"time"`
+		Transport     string    `json:"transport,omitempty"`
+		Method        string    `json:"method"`
+		ID            string    `json:"id,omitempty"`
+		CorrelationID string    `json:"correlationId,omitempty"`
+		Duration      float64   `json:"durationMs"`
+		BytesIn       int       `json:"bytesIn"`
+		BytesOut      int       `json:"bytesOut"`
+		ErrorCode     ErrorCode `json:"errorCode,omitempty"`
+	}{
+		Time:          rec.Time.UTC().Format(time.RFC3339Nano),
+		Transport:     rec.Transport,
+		Method:        rec.Method,
+		ID:            rec.ID,
+		CorrelationID: rec.CorrelationID,
+		Duration:      float64(rec.Duration) / float64(time.Millisecond),
+		BytesIn:       rec.BytesIn,
+		BytesOut:      rec.BytesOut,
+		ErrorCode:     rec.ErrorCode,
+	})
+}
+
+// LogfmtEncoder writes each record as a logfmt line of key=value pairs.
+func LogfmtEncoder(w io.Writer, rec AccessRecord) error {
+	_, err := fmt.Fprintf(w, "time=%s transport=%s method=%s id=%s correlation_id=%s duration_ms=%s bytes_in=%d bytes_out=%d error_code=%d\n",
+		rec.Time.UTC().Format(time.RFC3339Nano),
+		logfmtValue(rec.Transport),
+		logfmtValue(rec.Method),
+		logfmtValue(rec.ID),
+		logfmtValue(rec.CorrelationID),
+		strconv.FormatFloat(float64(rec.Duration)/float64(time.Millisecond), 'f', 3, 64),
+		rec.BytesIn,
+		rec.BytesOut,
+		rec.ErrorCode,
+	)
+	return err
+}
+
+// logfmtValue quotes the value if it's empty or has spaces, quotes, equal signs, backslashes,
+// control characters or invalid UTF-8, so a value from the client can't inject a record.
+func logfmtValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \"=\\") || strings.IndexFunc(v, unsafeLogfmtRune) >= 0 {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// unsafeLogfmtRune returns true if the rune must be escaped on a logfmt value.
+func unsafeLogfmtRune(r rune) bool {
+	return r == utf8.RuneError || !unicode.IsPrint(r)
+}
+
+// SetAccessLog allows to write one record per request to w with the encoder enc, if enc is nil
+// the JSONLinesEncoder is used. The writes to w are serialized.
+//
+// The record is written with the response replied, so the requests rejected before the method
+// is executed, like the methods not found, and the requests that timeout are logged too.
+//
+// Default is nil, which means the requests are not logged.
+func (mb *ManagerBuilder) SetAccessLog(w io.Writer, enc AccessLogEncoder) *ManagerBuilder {
+	if w == nil {
+		mb.accessLog = nil
+		return mb
+	}
+	if enc == nil {
+		enc = JSONLinesEncoder
+	}
+	mb.accessLog = &accessLogger{w: w, enc: enc}
+	return mb
+}
+
+// accessLogger writes the access records of a Manager.
+type accessLogger struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc AccessLogEncoder
+}
+
+// logAccess will write the access record of the request replied with res, if enabled.
+func (m *Manager) logAccess(ctx context.Context, req *Request, res *Response, start time.Time, elapsed time.Duration) {
+	if m.accessLog == nil {
+		return
+	}
+	rec := AccessRecord{
+		Time:          start,
+		Transport:     transportKind(ctx),
+		Method:        req.Method,
+		CorrelationID: CorrelationIDFromContext(ctx),
+		Duration:      elapsed,
+	}
+	if req.ID != nil {
+		rec.ID = idString(*req.ID)
+	}
+	if req.Params != nil {
+		rec.BytesIn = len(*req.Params)
+	}
+	var out []byte
+	if res.Error != nil {
+		rec.ErrorCode = res.Error.Code
+		out, _ = json.Marshal(res.Error)
+	} else {
+		out, _ = json.Marshal(res.Result)
+	}
+	rec.BytesOut = len(out)
+
+	m.accessLog.mu.Lock()
+	defer m.accessLog.mu.Unlock()
+	_ = m.accessLog.enc(m.accessLog.w, rec)
+}
@@ -0,0 +1,155 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestAccessLog(t *testing.T) {
+	var log bytes.Buffer
+	m := jrpc.NewManagerBuilder().
+		SetAccessLog(&log, nil).
+		Add("echo", &echoMethod{}).
+		Build()
+
+	body := `{"jsonrpc":"2.0","method":"echo","params":"hello","id":"a"}`
+	r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Correlation-ID", "c1")
+	jrpc.HTTPHandleFunc(&m)(httptest.NewRecorder(), r)
+
+	var rec map[string]interface{}
+	if err := json.Unmarshal(log.Bytes(), &rec); err != nil {
+		t.Fatalf("AccessLog() invalid record %q: %v", log.String(), err)
+	}
+	want := map[string]interface{}{
+		"transport":     "http",
+		"method":        "echo",
+		"id":            "a",
+		"correlationId": "c1",
+		"bytesIn":       float64(7),
+		"bytesOut":      float64(7),
+	}
+	for k, v := range want {
+		if rec[k] != v {
+			t.Errorf("AccessLog() %s = %v, want %v", k, rec[k], v)
+		}
+	}
+	if _, ok := rec["errorCode"]; ok {
+		t.Errorf("AccessLog() errorCode = %v, want none", rec["errorCode"])
+	}
+}
+
+func TestManagerBuilder_SetAccessLog_Rejected(t *testing.T) {
+	clock := newFakeClock()
+	block := &blockMethod{release: make(chan struct{})}
+	defer close(block.release)
+
+	var log bytes.Buffer
+	m := jrpc.NewManagerBuilder().
+		SetClock(clock).
+		SetTimeout(time.Minute).
+		SetAccessLog(&log, jrpc.LogfmtEncoder).
+		Add("block", block).
+		Build()
+
+	var out bytes.Buffer
+	if err := m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"missing","id":1}`), &out); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"block","id":2}`), &out)
+	}()
+	// Wait for the timeout to be set before moving the clock
+	<-clock.added
+	clock.Advance(time.Minute)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Handle() didn't return after the timeout expired")
+	}
+
+	lines := strings.Split(strings.TrimSuffix(log.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("access log = %q, want 2 records", log.String())
+	}
+	wants := [][]string{
+		{`time=1970-01-01T00:00:00Z `, ` method=missing `, ` id=1 `, ` duration_ms=0.000 `, ` error_code=-32601`},
+		{`time=1970-01-01T00:00:00Z `, ` method=block `, ` id=2 `, ` duration_ms=60000.000 `, ` error_code=-32002`},
+	}
+	for i, want := range wants {
+		for _, w := range want {
+			if !strings.Contains(lines[i], w) {
+				t.Errorf("access record %d = %q, want %q", i, lines[i], w)
+			}
+		}
+	}
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	var log bytes.Buffer
+	m := jrpc.NewManagerBuilder().
+		SetAccessLog(&log, jrpc.LogfmtEncoder).
+		Add("echo", &echoMethod{}).
+		Build()
+
+	var out bytes.Buffer
+	in := strings.NewReader(`{"jsonrpc":"2.0","method":"echo","params":1,"id":2}`)
+	if err := m.Handle(context.Background(), in, &out); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	got := log.String()
+	for _, want := range []string{` transport="" `, ` method=echo `, ` id=2 `, ` bytes_in=1 `, ` error_code=-32602` + "\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("LogfmtEncoder() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestLogfmtEncoder_Escape(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		want   string
+	}{
+		{name: "Plain", method: "users/get", want: ` method=users/get `},
+		{name: "Empty", method: "", want: ` method="" `},
+		{name: "Equal Sign", method: "a=b", want: ` method="a=b" `},
+		{name: "New Line", method: "echo\ntime=x method=forged", want: ` method="echo\ntime=x method=forged" `},
+		{name: "Carriage Return", method: "echo\r", want: ` method="echo\r" `},
+		{name: "Tab", method: "echo\tx", want: ` method="echo\tx" `},
+		{name: "Escape Sequence", method: "\x1b[31mred", want: ` method="\x1b[31mred" `},
+		{name: "Backslash", method: `a\nb`, want: ` method="a\\nb" `},
+		{name: "Invalid UTF-8", method: "a\xffb", want: ` method="a\xffb" `},
+		{name: "Line Separator", method: "a\u2028b", want: ` method="a\u2028b" `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log bytes.Buffer
+			if err := jrpc.LogfmtEncoder(&log, jrpc.AccessRecord{Method: tt.method}); err != nil {
+				t.Fatalf("LogfmtEncoder() error = %v", err)
+			}
+			got := log.String()
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("LogfmtEncoder() = %q, want %q", got, tt.want)
+			}
+			if strings.Count(got, "\n") != 1 || !strings.HasSuffix(got, "\n") {
+				t.Errorf("LogfmtEncoder() = %q, want a single line", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package jrpc2go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFdsStart is the first file descriptor passed by systemd socket activation.
+const listenFdsStart = 3
+
+// ErrNotActivated is returned when the process was not started by socket activation.
+var ErrNotActivated = errors.New("jsonrpc: process was not socket activated")
+
+// ActivationListeners returns the listeners of the sockets passed by systemd socket activation,
+// using the LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES environment variables, which are removed so
+// child processes don't inherit them.
+//
+// It returns ErrNotActivated if the sockets were not passed to this process.
+func ActivationListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, ErrNotActivated
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, ErrNotActivated
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	for _, k := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		_ = os.Unsetenv(k)
+	}
+
+	ls := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(listenFdsStart+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(listenFdsStart+i), name)
+		l, err := net.FileListener(f)
+		// The listener has its own copy of the file descriptor
+		f.Close()
+		if err != nil {
+			for _, l := range ls {
+				l.Close()
+			}
+			return nil, fmt.Errorf("jsonrpc: fail to use activated socket %s: %v", name, err)
+		}
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// ServeActivated will serve the Manager on all the sockets passed by systemd socket activation,
+// TCP or Unix stream sockets, until the ctx is done. The server is then shut down waiting for
+// the requests being executed.
+//
+// It returns ErrNotActivated if the process was not socket activated, ErrServerClosed once the
+// ctx is done or the error of a listener that failed.
+func ServeActivated(ctx context.Context, m *Manager, opts ...ServerOption) error {
+	ls, err := ActivationListeners()
+	if err != nil {
+		return err
+	}
+
+	srv := NewServer(m, opts...)
+	errs := make(chan error, len(ls))
+	for _, l := range ls {
+		go func(l net.Listener) {
+			errs <- srv.Serve(l)
+		}(l)
+	}
+
+	select {
+	case <-ctx.Done():
+		err = ErrServerClosed
+	case err = <-errs:
+	}
+	_ = srv.Shutdown(context.Background())
+	return err
+}
@@ -0,0 +1,209 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// batch collects the responses of the requests of a batch executed with the concurrency and
+// the ordering policy of the Manager.
+//
+// The responses are in the order of the requests, unless the batch is unordered, then they are
+// in the order the executions finished. When emit is set the responses are given to it as soon
+// as they can be replied, keeping the same ordering, instead of being collected.
+type batch struct {
+	sem       chan struct{}
+	unordered bool
+	wg        sync.WaitGroup
+
+	mu    sync.Mutex
+	slots []*batchSlot
+	resp  []*Response
+	emit  func(*Response)
+	next  int
+}
+
+// batchSlot keeps the response of one request of the batch.
+type batchSlot struct {
+	req  *Request
+	resp *Response
+}
+
+// newBatch returns an empty batch with the Manager policies.
+func (m *Manager) newBatch() *batch {
+	b := &batch{unordered: m.unorderedBatch}
+	if m.batchConcurrency > 1 {
+		b.sem = make(chan struct{}, m.batchConcurrency)
+	}
+	return b
+}
+
+// add will run exec to get the response of the request, in the background if the batch is
+// concurrent, waiting while the concurrency limit is reached.
+func (b *batch) add(req *Request, exec func() *Response) {
+	slot := &batchSlot{req: req}
+	b.mu.Lock()
+	b.slots = append(b.slots, slot)
+	b.mu.Unlock()
+	if b.sem == nil {
+		b.done(slot, exec())
+		return
+	}
+
+	b.sem <- struct{}{}
+	b.wg.Add(1)
+	go func() {
+		defer func() {
+			<-b.sem
+			b.wg.Done()
+		}()
+		b.done(slot, exec())
+	}()
+}
+
+// done will keep the response of the request.
+func (b *batch) done(slot *batchSlot, resp *Response) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	slot.resp = resp
+	// If no ID means it's a notification and the server shouldn't reply
+	// if we have an error it should return anyway
+	if b.emit != nil {
+		b.flush(slot)
+		return
+	}
+	if b.unordered && reply(slot.req, resp) {
+		b.resp = append(b.resp, resp)
+	}
+}
+
+// flush will emit the responses that can be replied after the slot finished, only the slot if
+// the batch is unordered or the finished slots that follow the last emitted one otherwise.
+func (b *batch) flush(slot *batchSlot) {
+	if b.unordered {
+		if reply(slot.req, slot.resp) {
+			b.emit(slot.resp)
+		}
+		return
+	}
+	for ; b.next < len(b.slots) && b.slots[b.next].resp != nil; b.next++ {
+		if s := b.slots[b.next]; reply(s.req, s.resp) {
+			b.emit(s.resp)
+		}
+	}
+}
+
+// wait will wait for all the executions and return the responses to reply.
+func (b *batch) wait() []*Response {
+	b.wg.Wait()
+	if b.unordered || b.emit != nil {
+		return b.resp
+	}
+	for _, slot := range b.slots {
+		if reply(slot.req, slot.resp) {
+			b.resp = append(b.resp, slot.resp)
+		}
+	}
+	return b.resp
+}
+
+// reply returns true if the response should be sent to the client.
+func reply(req *Request, resp *Response) bool {
+	return req.ID != nil || resp.Error != nil
+}
+
+// batchStream writes the responses of a batch as a JSON array, one element at a time, flushing
+// each one so the client can read the results as they complete.
+type batchStream struct {
+	m     *Manager
+	w     io.Writer
+	start func()
+
+	started bool
+	err     error
+
+	delivered   []*json.RawMessage
+	undelivered []*json.RawMessage
+}
+
+// write will write the response as the next element of the array, the batch serializes the calls.
+func (s *batchStream) write(resp *Response) {
+	if s.err != nil {
+		s.undelivered = appendID(s.undelivered, resp)
+		return
+	}
+	var buf bytes.Buffer
+	if s.err = s.m.encoder.encode(&buf, resp); s.err != nil {
+		s.undelivered = appendID(s.undelivered, resp)
+		return
+	}
+	sep := byte(',')
+	if !s.started {
+		s.started = true
+		s.start()
+		sep = '['
+	}
+	elem := append([]byte{sep}, bytes.TrimSuffix(buf.Bytes(), []byte{'\n'})...)
+	if _, err := s.w.Write(elem); err != nil {
+		s.err = &TransportError{Op: "write", Err: err}
+		s.undelivered = appendID(s.undelivered, resp)
+		return
+	}
+	if err := flush(s.w); err != nil {
+		s.err = &TransportError{Op: "write", Err: err}
+		s.undelivered = appendID(s.undelivered, resp)
+		return
+	}
+	s.delivered = appendID(s.delivered, resp)
+}
+
+// abort will write the error of an invalid request as the last element and close the array, it
+// returns the error.
+func (s *batchStream) abort(err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		s.write(&Response{Version: version, Error: e})
+	}
+	if cerr := s.close(); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// close will write the end of the array.
+func (s *batchStream) close() error {
+	if s.err != nil {
+		return s.writeError(s.err)
+	}
+	end := []byte{']'}
+	if s.m.encoder.newline {
+		end = append(end, '\n')
+	}
+	if _, err := s.w.Write(end); err != nil {
+		return s.writeError(&TransportError{Op: "write", Err: err})
+	}
+	if err := flush(s.w); err != nil {
+		return s.writeError(&TransportError{Op: "write", Err: err})
+	}
+	return nil
+}
+
+// writeError returns err with the delivered and undelivered responses if the write failed.
+func (s *batchStream) writeError(err error) error {
+	var te *TransportError
+	if !errors.As(err, &te) {
+		return err
+	}
+	return &BatchWriteError{Delivered: s.delivered, Undelivered: s.undelivered, Err: err}
+}
+
+// appendID returns ids with the ID of the response, the responses without ID are skipped.
+func appendID(ids []*json.RawMessage, resp *Response) []*json.RawMessage {
+	if resp.ID == nil {
+		return ids
+	}
+	return append(ids, resp.ID)
+}
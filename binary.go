@@ -0,0 +1,99 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ErrBytesTooLarge is returned by CopyBytes when the decoded data is over the limit.
+var ErrBytesTooLarge = errors.New("jsonrpc: binary data too large")
+
+// Bytes is binary data encoded on JSON as a base64 string with padding, null is decoded as nil.
+//
+// It's the same encoding of []byte by encoding/json, but it's also accepted on the params with
+// the URL alphabet or without padding, which other JSON-RPC implementations send.
+type Bytes []byte
+
+// MarshalJSON encodes the data as a base64 string or null if it's nil.
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	if b == nil {
+		return []byte("null"), nil
+	}
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(b))+2)
+	out[0] = '"'
+	base64.StdEncoding.Encode(out[1:], b)
+	out[len(out)-1] = '"'
+	return out, nil
+}
+
+// UnmarshalJSON decodes a base64 string or null.
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*b = nil
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	d, err := base64Encoding([]byte(s)).DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*b = d
+	return nil
+}
+
+// base64Encoding returns the encoding of s, with the standard or the URL alphabet and with or
+// without padding.
+func base64Encoding(s []byte) *base64.Encoding {
+	enc := base64.StdEncoding
+	if bytes.ContainsAny(s, "-_") {
+		enc = base64.URLEncoding
+	}
+	if len(s)%4 != 0 {
+		enc = enc.WithPadding(base64.NoPadding)
+	}
+	return enc
+}
+
+// CopyBytes will decode the raw base64 JSON string, like a field of the params kept as
+// json.RawMessage, and write the data to w without holding the decoded data in memory, so
+// file contents can be stored as they are decoded.
+//
+// It returns the number of bytes written and ErrBytesTooLarge if there are more than limit
+// bytes, nothing after the limit is written. A limit of 0 or less means no limit.
+func CopyBytes(w io.Writer, raw json.RawMessage, limit int64) (int64, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return 0, errors.New("jsonrpc: binary data must be a base64 string")
+	}
+	content := raw[1 : len(raw)-1]
+	// Escaped strings are rare on base64, like "\/", they're unescaped in memory
+	if bytes.IndexByte(content, '\\') >= 0 {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return 0, err
+		}
+		content = []byte(s)
+	}
+
+	r := base64.NewDecoder(base64Encoding(content), bytes.NewReader(content))
+	if limit <= 0 {
+		return io.Copy(w, r)
+	}
+
+	n, err := io.Copy(w, io.LimitReader(r, limit))
+	if err != nil {
+		return n, err
+	}
+	// Any byte left means the data is over the limit
+	var extra [1]byte
+	if m, _ := r.Read(extra[:]); m > 0 {
+		return n, ErrBytesTooLarge
+	}
+	return n, nil
+}
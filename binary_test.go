@@ -0,0 +1,61 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestBytes_JSON(t *testing.T) {
+	b, err := json.Marshal(struct {
+		Data jrpc.Bytes `json:"data"`
+		Nil  jrpc.Bytes `json:"nil"`
+	}{Data: jrpc.Bytes("hi?")})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if want := `{"data":"aGk/","nil":null}`; string(b) != want {
+		t.Errorf("json.Marshal() = %s, want %s", b, want)
+	}
+
+	for _, in := range []string{`"aGk/"`, `"aGk_"`, `"aGk\/"`} {
+		var got jrpc.Bytes
+		if err := json.Unmarshal([]byte(in), &got); err != nil || string(got) != "hi?" {
+			t.Errorf("json.Unmarshal(%s) = %q, %v, want hi?", in, got, err)
+		}
+	}
+	var got jrpc.Bytes
+	if err := json.Unmarshal([]byte(`"aA"`), &got); err != nil || string(got) != "h" {
+		t.Errorf("json.Unmarshal() without padding = %q, %v, want h", got, err)
+	}
+}
+
+func TestCopyBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		limit   int64
+		want    string
+		wantErr bool
+	}{
+		{name: "No Limit", raw: `"aGVsbG8gd29ybGQ="`, want: "hello world"},
+		{name: "Within Limit", raw: `"aGVsbG8gd29ybGQ="`, limit: 11, want: "hello world"},
+		{name: "Over Limit", raw: `"aGVsbG8gd29ybGQ="`, limit: 5, want: "hello", wantErr: true},
+		{name: "Escaped", raw: `"aGk\/"`, want: "hi?"},
+		{name: "Not a String", raw: `123`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w bytes.Buffer
+			n, err := jrpc.CopyBytes(&w, json.RawMessage(tt.raw), tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CopyBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if w.String() != tt.want || n != int64(len(tt.want)) {
+				t.Errorf("CopyBytes() = %d, %q, want %q", n, w.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package jrpc2go
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// methodsMethodName is the name of the built-in method that lists the registered methods.
+const methodsMethodName = "rpc.methods"
+
+// healthMethodName is the name of the built-in method that reports the state of the server.
+const healthMethodName = "rpc.health"
+
+// cancelMethodName is the name of the built-in method that cancels a request being executed.
+const cancelMethodName = "rpc.cancel"
+
+// Feature is a bundle of built-in methods and hooks that is enabled on the builder with
+// ManagerBuilder.Enable.
+type Feature func(mb *ManagerBuilder)
+
+// Enable will enable the features on the builder, like
+// Enable(jrpc.Introspection(), jrpc.Health(), jrpc.Cancellation()).
+func (mb *ManagerBuilder) Enable(features ...Feature) *ManagerBuilder {
+	for _, f := range features {
+		f(mb)
+	}
+	return mb
+}
+
+// Introspection registers rpc.methods, which replies with the sorted names of the methods added
+// with Add and AddVersion, and rpc.inflight, see ManagerBuilder.EnableInFlight.
+//
+// rpc.inflight only lists the requests of the connection or session of the caller, see AdminScope.
+func Introspection() Feature {
+	return func(mb *ManagerBuilder) {
+		mb.introspection = true
+		mb.inFlight = true
+	}
+}
+
+// Health registers rpc.ping, see ManagerBuilder.EnablePing, and rpc.health, which replies with
+// a HealthStatus.
+func Health() Feature {
+	return func(mb *ManagerBuilder) {
+		mb.health = true
+		mb.ping = true
+	}
+}
+
+// Cancellation registers rpc.cancel, which cancels the requests being executed with the ID of
+// the params {"id": ...} like Manager.CancelRequest, and replies if any request was canceled.
+//
+// Only the requests of the connection or session of the caller, like a Server connection, are
+// canceled so a client can't cancel the requests of the others by guessing their IDs. The
+// callers without one, like the plain HTTP requests, can't cancel any request, see AdminScope.
+func Cancellation() Feature {
+	return func(mb *ManagerBuilder) {
+		mb.cancellation = true
+	}
+}
+
+// AdminScope makes rpc.cancel and rpc.inflight act on the requests of all the callers, of every
+// connection, session and transport. It's meant for a Manager only reachable by the operators,
+// like one served on an internal port, since any caller can list and cancel the requests of the
+// others with it.
+func AdminScope() Feature {
+	return func(mb *ManagerBuilder) {
+		mb.adminScope = true
+	}
+}
+
+// registerFeatures will add the methods of the enabled features to the table.
+func (mb *ManagerBuilder) registerFeatures(table *methodTable, tracker *inFlightTracker) {
+	if mb.introspection {
+		table.methods[methodsMethodName] = &methodsMethod{table: table}
+	}
+	if mb.health {
+		table.methods[healthMethodName] = &healthMethod{tracker: tracker}
+	}
+	if mb.cancellation {
+		table.methods[cancelMethodName] = &cancelMethod{tracker: tracker, all: mb.adminScope}
+	}
+}
+
+// methodsMethod replies to rpc.methods with the names of the registered methods.
+type methodsMethod struct {
+	table *methodTable
+}
+
+// Execute will reply with the sorted names of the methods.
+func (m *methodsMethod) Execute(req *Request, resp *Response) {
+	m.table.mu.RLock()
+	names := make([]string, 0, len(m.table.methods)+len(m.table.versions))
+	for name := range m.table.methods {
+		names = append(names, name)
+	}
+	for name := range m.table.versions {
+		if _, ok := m.table.methods[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	m.table.mu.RUnlock()
+	sort.Strings(names)
+	resp.Result = names
+}
+
+// HealthStatus is the result of rpc.health.
+//
+// Status - Always "ok" while the server is able to execute methods.
+//
+// InFlight - The number of requests being executed.
+type HealthStatus struct {
+	Status   string `json:"status"`
+	InFlight int    `json:"inFlight"`
+}
+
+// healthMethod replies to rpc.health with the HealthStatus.
+type healthMethod struct {
+	tracker *inFlightTracker
+}
+
+// Execute will reply with the HealthStatus, the rpc.health request itself is not counted.
+func (m *healthMethod) Execute(req *Request, resp *Response) {
+	resp.Result = HealthStatus{Status: "ok", InFlight: len(m.tracker.list()) - 1}
+}
+
+// cancelMethod cancels the requests with the ID of the params.
+type cancelMethod struct {
+	tracker *inFlightTracker
+	all     bool
+}
+
+// cancelParams are the params of rpc.cancel.
+type cancelParams struct {
+	ID *json.RawMessage `json:"id"`
+}
+
+// Execute will cancel the requests of the caller, or of all the callers with AdminScope, and reply
+// true if any was canceled.
+func (m *cancelMethod) Execute(req *Request, resp *Response) {
+	// The connections that didn't negotiate the cancellation don't have the method
+	if off, _ := req.Context().Value(noCancelKey).(bool); off {
+		resp.Error = newError(errCodeMethodNotFound, req.Method)
+		return
+	}
+	var p cancelParams
+	if err := req.ParseParams(&p); err != nil {
+		resp.Error = err
+		return
+	}
+	if p.ID == nil {
+		resp.Error = newError(errCodeInvalidParams, "id is required")
+		return
+	}
+	resp.Result = m.tracker.cancelOwned(idString(*p.ID), NotifierFromContext(req.Context()), m.all) > 0
+}
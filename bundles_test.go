@@ -0,0 +1,55 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestManagerBuilder_Enable(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Enable(jrpc.Introspection(), jrpc.Health(), jrpc.Cancellation()).
+		Build()
+
+	tests := []struct {
+		name  string
+		r     string
+		wantW string
+	}{
+		{
+			name:  "Methods",
+			r:     `{"jsonrpc":"2.0","method":"rpc.methods","id":1}`,
+			wantW: `{"jsonrpc":"2.0","id":1,"result":["add","rpc.cancel","rpc.health","rpc.inflight","rpc.methods","rpc.ping"]}`,
+		},
+		{
+			name:  "Health",
+			r:     `{"jsonrpc":"2.0","method":"rpc.health","id":2}`,
+			wantW: `{"jsonrpc":"2.0","id":2,"result":{"status":"ok","inFlight":0}}`,
+		},
+		{
+			name:  "Cancel Unknown Request",
+			r:     `{"jsonrpc":"2.0","method":"rpc.cancel","params":{"id":"x"},"id":3}`,
+			wantW: `{"jsonrpc":"2.0","id":3,"result":false}`,
+		},
+		{
+			name:  "Cancel Without ID",
+			r:     `{"jsonrpc":"2.0","method":"rpc.cancel","params":{},"id":4}`,
+			wantW: `{"jsonrpc":"2.0","id":4,"error":{"code":-32602,"message":"Invalid method parameter(s)","data":"id is required"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			if err := m.Handle(context.Background(), strings.NewReader(tt.r), w); err != nil {
+				t.Fatalf("Manager.Handle() error = %v", err)
+			}
+			if gotW := strings.TrimSpace(w.String()); gotW != tt.wantW {
+				t.Errorf("Manager.Handle() result = %v, want %v", gotW, tt.wantW)
+			}
+		})
+	}
+}
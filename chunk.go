@@ -0,0 +1,144 @@
+package jrpc2go
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ChunkMethod is the method of the notifications with the chunks of a result.
+const ChunkMethod = "rpc.chunk"
+
+// ErrChunkOutOfOrder is returned by ChunkAssembler.Add when a chunk is missing.
+var ErrChunkOutOfOrder = errors.New("jsonrpc: chunk out of order")
+
+// errChunkWriterClosed is returned when writing to the chunk writer after the method returned.
+var errChunkWriterClosed = errors.New("jsonrpc: chunk writer closed")
+
+// Chunk is the params of the rpc.chunk notifications.
+//
+// ID - The identifier of the request the chunk belongs to.
+//
+// Seq - The position of the chunk, starting on 0.
+//
+// Data - The bytes of the chunk, encoded as base64.
+//
+// Done - It's true on the last chunk, which can be empty, the response of the request follows.
+type Chunk struct {
+	ID   *json.RawMessage `json:"id"`
+	Seq  int              `json:"seq"`
+	Data []byte           `json:"data"`
+	Done bool             `json:"done,omitempty"`
+}
+
+// ChunkWriter returns the writer where the method writes a large result to be streamed to the
+// client as rpc.chunk notifications, it's false if chunking is not enabled with
+// ManagerBuilder.EnableChunking, the request is a notification or the transport doesn't support
+// server-initiated notifications.
+func ChunkWriter(ctx context.Context) (io.Writer, bool) {
+	w, ok := ctx.Value(chunkKey).(*chunkWriter)
+	return w, ok
+}
+
+// chunkWriter buffers the data written by a method and notifies it in chunks of the size.
+type chunkWriter struct {
+	ctx      context.Context
+	notifier Notifier
+	id       *json.RawMessage
+	size     int
+
+	mu     sync.Mutex
+	buf    []byte
+	seq    int
+	closed bool
+}
+
+// newChunkWriter returns the writer for the request with the id.
+func newChunkWriter(ctx context.Context, n Notifier, id *json.RawMessage, size int) *chunkWriter {
+	return &chunkWriter{ctx: ctx, notifier: n, id: id, size: size}
+}
+
+// Write will notify the full chunks and keep the rest until the next write or the close.
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, errChunkWriterClosed
+	}
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= w.size {
+		if err := w.send(w.buf[:w.size], false); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[w.size:]
+	}
+	return len(p), nil
+}
+
+// close will notify the last chunk if anything was written.
+func (w *chunkWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	w.closed = true
+	if w.seq > 0 || len(w.buf) > 0 {
+		_ = w.send(w.buf, true)
+	}
+	w.buf = nil
+}
+
+// send will notify the chunk, w.mu must be held.
+func (w *chunkWriter) send(data []byte, done bool) error {
+	c := &Chunk{ID: w.id, Seq: w.seq, Data: append([]byte{}, data...), Done: done}
+	w.seq++
+	return w.notifier.Notify(w.ctx, ChunkMethod, c)
+}
+
+// ChunkAssembler joins the chunks of the results received by a client.
+type ChunkAssembler struct {
+	mu    sync.Mutex
+	parts map[string]*Chunk
+}
+
+// NewChunkAssembler returns an empty ChunkAssembler.
+func NewChunkAssembler() *ChunkAssembler {
+	return &ChunkAssembler{parts: make(map[string]*Chunk)}
+}
+
+// Add will join the params of a rpc.chunk notification to the previous chunks of the same
+// request, when it's the last chunk it returns the complete data and true.
+//
+// It returns ErrChunkOutOfOrder if a chunk is missing, the chunks of that request are discarded.
+func (a *ChunkAssembler) Add(params json.RawMessage) ([]byte, bool, error) {
+	var c Chunk
+	if err := json.Unmarshal(params, &c); err != nil {
+		return nil, false, err
+	}
+	key := "null"
+	if c.ID != nil {
+		key = string(*c.ID)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	part, ok := a.parts[key]
+	if !ok {
+		part = &Chunk{}
+	}
+	if c.Seq != part.Seq {
+		delete(a.parts, key)
+		return nil, false, ErrChunkOutOfOrder
+	}
+	part.Data = append(part.Data, c.Data...)
+	part.Seq++
+	if c.Done {
+		delete(a.parts, key)
+		return part.Data, true, nil
+	}
+	a.parts[key] = part
+	return nil, false, nil
+}
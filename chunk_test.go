@@ -0,0 +1,75 @@
+package jrpc2go_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestManagerBuilder_EnableChunking(t *testing.T) {
+	export := jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+		w, ok := jrpc.ChunkWriter(req.Context())
+		if !ok {
+			resp.Result = "not chunked"
+			return
+		}
+		_, _ = io.WriteString(w, "abcdef")
+		_, _ = io.WriteString(w, "ghij")
+		resp.Result = "done"
+	})
+	m := jrpc.NewManagerBuilder().
+		EnableChunking(4).
+		Add("export", export).
+		Build()
+	srv, c := startServer(t, &m)
+	defer srv.Shutdown(context.Background())
+	defer c.Close()
+
+	if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"export","id":7}` + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	r := bufio.NewReader(c)
+	_ = c.SetReadDeadline(time.Now().Add(time.Second))
+	a := jrpc.NewChunkAssembler()
+	var data []byte
+	for done := false; !done; {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("ReadBytes() error = %v", err)
+		}
+		var n struct {
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(line, &n); err != nil || n.Method != jrpc.ChunkMethod {
+			t.Fatalf("got %s, want a chunk", line)
+		}
+		if data, done, err = a.Add(n.Params); err != nil {
+			t.Fatalf("ChunkAssembler.Add() error = %v", err)
+		}
+	}
+	if string(data) != "abcdefghij" {
+		t.Errorf("chunks = %q, want abcdefghij", data)
+	}
+
+	got, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+	if want := `{"jsonrpc":"2.0","id":7,"result":"done"}` + "\n"; got != want {
+		t.Errorf("response = %v, want %v", got, want)
+	}
+}
+
+func TestChunkAssembler_OutOfOrder(t *testing.T) {
+	a := jrpc.NewChunkAssembler()
+	if _, _, err := a.Add(json.RawMessage(`{"id":1,"seq":1,"data":"YQ=="}`)); err != jrpc.ErrChunkOutOfOrder {
+		t.Errorf("ChunkAssembler.Add() error = %v, want %v", err, jrpc.ErrChunkOutOfOrder)
+	}
+}
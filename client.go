@@ -0,0 +1,223 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// ErrNoResponse is returned by Client.Call when the server doesn't reply to a request.
+var ErrNoResponse = errors.New("jsonrpc: server didn't reply to the request")
+
+// Transport sends the encoded requests to a server and returns the encoded response, it's
+// empty if the server doesn't reply like for notifications.
+type Transport interface {
+	RoundTrip(ctx context.Context, body []byte) ([]byte, error)
+}
+
+// Client calls the methods of a JSON RPC server using a Transport.
+type Client struct {
+	// seq is accessed atomically and it's the first field to keep it 64-bit aligned
+	seq       uint64
+	transport Transport
+	contract  *OpenRPC
+	call      ClientCall
+}
+
+// ClientOption configures the Client.
+type ClientOption func(*Client)
+
+// ClientCall is the execution of a Client.Call.
+type ClientCall func(ctx context.Context, method string, params, result interface{}) error
+
+// ClientInterceptor wraps the calls of a Client to run logic before and after them, like
+// TokenRefresh, it should call next to send the call. It can call next more than once to
+// retry the call.
+type ClientInterceptor func(next ClientCall) ClientCall
+
+// WithInterceptors wraps the calls of Client.Call with the interceptors, the first interceptor
+// is the outermost one. The notifications are not intercepted.
+func WithInterceptors(interceptors ...ClientInterceptor) ClientOption {
+	return func(c *Client) {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			c.call = interceptors[i](c.call)
+		}
+	}
+}
+
+// WithResponseValidation validates the results received against the result schemas of the
+// methods on the OpenRPC document, a result that doesn't match is returned as a *ContractError
+// by Client.Call, so contract violations of third-party servers are caught on the boundary.
+func WithResponseValidation(doc *OpenRPC) ClientOption {
+	return func(c *Client) {
+		c.contract = doc
+	}
+}
+
+// NewClient returns a Client that sends the requests with the transport.
+func NewClient(t Transport, opts ...ClientOption) *Client {
+	if t == nil {
+		panic("jsonrpc: client transport should not be nil")
+	}
+	c := &Client{transport: t}
+	c.call = c.roundTrip
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Call will execute the method with the params on the server and stores the result in the value
+// pointed to by result, if result is nil the result is discarded.
+//
+// The errors replied by the server are returned as *Error.
+func (c *Client) Call(ctx context.Context, method string, params, result interface{}) error {
+	return c.call(ctx, method, params, result)
+}
+
+// roundTrip will send the call with a new ID and decode its response.
+func (c *Client) roundTrip(ctx context.Context, method string, params, result interface{}) error {
+	id := json.RawMessage(strconv.FormatUint(atomic.AddUint64(&c.seq, 1), 10))
+	req, err := newClientRequest(method, &id, params)
+	if err != nil {
+		return err
+	}
+
+	body, err := c.transport.RoundTrip(ctx, req)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ErrNoResponse
+	}
+
+	var resp struct {
+		ID     *json.RawMessage `json:"id"`
+		Result json.RawMessage  `json:"result"`
+		Error  *Error           `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("jsonrpc: invalid response: %v", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if resp.ID == nil || !bytes.Equal(*resp.ID, id) {
+		return fmt.Errorf("jsonrpc: response id doesn't match the request id %s", id)
+	}
+	if c.contract != nil {
+		if err := c.contract.validateResult(method, resp.Result); err != nil {
+			return err
+		}
+	}
+	if result == nil || resp.Result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// Notify will send a notification of the method with the params, the server doesn't reply to
+// notifications unless there is an error that is returned as *Error.
+func (c *Client) Notify(ctx context.Context, method string, params interface{}) error {
+	req, err := newClientRequest(method, nil, params)
+	if err != nil {
+		return err
+	}
+
+	body, err := c.transport.RoundTrip(ctx, req)
+	if err != nil || len(bytes.TrimSpace(body)) == 0 {
+		return err
+	}
+
+	var resp struct {
+		Error *Error `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("jsonrpc: invalid response: %v", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+// newClientRequest returns the encoded request.
+func newClientRequest(method string, id *json.RawMessage, params interface{}) ([]byte, error) {
+	req := &Request{
+		Version: version,
+		Method:  method,
+		ID:      id,
+	}
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("jsonrpc: fail to encode params: %v", err)
+		}
+		raw := json.RawMessage(b)
+		req.Params = &raw
+	}
+	return json.Marshal(req)
+}
+
+// HTTPTransport is a Transport that posts the requests to a JSON RPC HTTP endpoint.
+//
+// On js/wasm builds the requests are sent with the browser Fetch API.
+type HTTPTransport struct {
+	// URL is the address of the endpoint.
+	URL string
+	// Client is used to send the requests, if nil http.DefaultClient is used.
+	Client *http.Client
+	// Header is added to every request.
+	Header http.Header
+	// FetchMode is the Fetch API request mode, like "cors", it's only used on js/wasm builds.
+	FetchMode string
+	// FetchCredentials is the Fetch API credentials mode, like "include", it's only used on
+	// js/wasm builds.
+	FetchCredentials string
+}
+
+// RoundTrip will post the body to the endpoint and return the response body.
+func (t *HTTPTransport) RoundTrip(ctx context.Context, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range t.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if token := bearerTokenFromContext(ctx); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set(contentTypeKey, contentTypeValue)
+	setFetchOptions(req, t.FetchMode, t.FetchCredentials)
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return b, nil
+	case http.StatusUnauthorized:
+		return nil, fmt.Errorf("%w: %s", ErrUnauthorized, resp.Status)
+	}
+	return nil, fmt.Errorf("jsonrpc: unexpected http status %s", resp.Status)
+}
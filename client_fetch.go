@@ -0,0 +1,9 @@
+//go:build !js || !wasm
+// +build !js !wasm
+
+package jrpc2go
+
+import "net/http"
+
+// setFetchOptions does nothing, the Fetch API is only used on js/wasm builds.
+func setFetchOptions(req *http.Request, mode, credentials string) {}
@@ -0,0 +1,17 @@
+//go:build js && wasm
+// +build js,wasm
+
+package jrpc2go
+
+import "net/http"
+
+// setFetchOptions sets the Fetch API options of the request, net/http reads them from these
+// special headers on js/wasm builds.
+func setFetchOptions(req *http.Request, mode, credentials string) {
+	if mode != "" {
+		req.Header.Set("js.fetch:mode", mode)
+	}
+	if credentials != "" {
+		req.Header.Set("js.fetch:credentials", credentials)
+	}
+}
@@ -0,0 +1,181 @@
+package jrpc2go_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestClient(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Build()
+	srv := httptest.NewServer(http.HandlerFunc(jrpc.HTTPHandleFunc(&m)))
+	defer srv.Close()
+
+	c := jrpc.NewClient(&jrpc.HTTPTransport{URL: srv.URL})
+
+	var got int64
+	if err := c.Call(context.Background(), "add", map[string]int{"v1": 1, "v2": 2}, &got); err != nil {
+		t.Fatalf("Client.Call() error = %v", err)
+	}
+	if got != 3 {
+		t.Errorf("Client.Call() result = %d, want 3", got)
+	}
+
+	err := c.Call(context.Background(), "sub", nil, &got)
+	if e, ok := err.(*jrpc.Error); !ok || e.Code != -32601 {
+		t.Errorf("Client.Call() error = %v, want method not found", err)
+	}
+
+	if err := c.Notify(context.Background(), "add", map[string]int{"v1": 1, "v2": 2}); err != nil {
+		t.Errorf("Client.Notify() error = %v", err)
+	}
+}
+
+func TestResolvingTransport(t *testing.T) {
+	var urls []string
+	for _, name := range []string{"a", "b"} {
+		name := name
+		m := jrpc.NewManagerBuilder().
+			Add("name", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) { resp.Result = name })).
+			Build()
+		srv := httptest.NewServer(http.HandlerFunc(jrpc.HTTPHandleFunc(&m)))
+		defer srv.Close()
+		urls = append(urls, srv.URL)
+	}
+
+	resolved := urls
+	r := jrpc.ResolverFunc(func(ctx context.Context) ([]string, error) { return resolved, nil })
+	tr := jrpc.NewResolvingTransport(r, 0, nil)
+	c := jrpc.NewClient(tr)
+
+	call := func() string {
+		var got string
+		if err := c.Call(context.Background(), "name", nil, &got); err != nil {
+			t.Fatalf("Client.Call() error = %v", err)
+		}
+		return got
+	}
+	if got := call() + call() + call(); got != "aba" {
+		t.Errorf("Client.Call() servers = %v, want aba", got)
+	}
+
+	resolved = urls[1:]
+	if err := tr.Refresh(context.Background()); err != nil {
+		t.Fatalf("ResolvingTransport.Refresh() error = %v", err)
+	}
+	if got := call() + call(); got != "bb" {
+		t.Errorf("Client.Call() servers after refresh = %v, want bb", got)
+	}
+
+	empty := jrpc.NewClient(jrpc.NewResolvingTransport(jrpc.StaticResolver{}, 0, nil))
+	if err := empty.Call(context.Background(), "name", nil, nil); err != jrpc.ErrNoAddresses {
+		t.Errorf("Client.Call() error = %v, want %v", err, jrpc.ErrNoAddresses)
+	}
+}
+
+func TestClient_WithResponseValidation(t *testing.T) {
+	doc, err := jrpc.LoadOpenRPC(strings.NewReader(`{
+		"openrpc": "1.2.6",
+		"methods": [
+			{"name": "add", "result": {"name": "sum", "schema": {"type": "integer"}}},
+			{"name": "user", "result": {"name": "user", "schema": {"$ref": "#/components/schemas/User"}}}
+		],
+		"components": {"schemas": {"User": {
+			"type": "object",
+			"required": ["name"],
+			"properties": {"name": {"type": "string"}, "tags": {"type": "array", "items": {"type": "string"}}}
+		}}}
+	}`))
+	if err != nil {
+		t.Fatalf("LoadOpenRPC() error = %v", err)
+	}
+
+	user := jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+		resp.Result = map[string]interface{}{"name": "ana", "tags": []interface{}{"a", 1}}
+	})
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Add("user", user).
+		Build()
+	srv := httptest.NewServer(http.HandlerFunc(jrpc.HTTPHandleFunc(&m)))
+	defer srv.Close()
+
+	c := jrpc.NewClient(&jrpc.HTTPTransport{URL: srv.URL}, jrpc.WithResponseValidation(doc))
+	var sum int64
+	if err := c.Call(context.Background(), "add", map[string]int{"v1": 1, "v2": 2}, &sum); err != nil {
+		t.Errorf("Client.Call() error = %v", err)
+	}
+
+	err = c.Call(context.Background(), "user", nil, nil)
+	var ce *jrpc.ContractError
+	if !errors.As(err, &ce) || !errors.Is(err, jrpc.ErrContractViolation) {
+		t.Fatalf("Client.Call() error = %v, want a ContractError", err)
+	}
+	if ce.Method != "user" || ce.Path != "$.tags[1]" {
+		t.Errorf("ContractError = %+v, want user at $.tags[1]", ce)
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Build()
+	handle := jrpc.HTTPHandleFunc(&m)
+
+	// reject tells how the server rejects a token, it accepts the tokens not in it
+	var reject map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch reject[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")] {
+		case "status":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "code":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":4001,"message":"Token expired"}}`))
+		default:
+			handle(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var refreshes int
+	refresh := func(ctx context.Context) (string, error) {
+		refreshes++
+		return "token-" + strconv.Itoa(refreshes), nil
+	}
+	c := jrpc.NewClient(&jrpc.HTTPTransport{URL: srv.URL}, jrpc.WithInterceptors(jrpc.TokenRefresh(refresh, 4001)))
+
+	tests := []struct {
+		name      string
+		reject    map[string]string
+		refreshes int
+		code      jrpc.ErrorCode
+	}{
+		{name: "Unauthorized Status", reject: map[string]string{"token-1": "status"}, refreshes: 2},
+		{name: "Token Kept", refreshes: 2},
+		{name: "Expired Code", reject: map[string]string{"token-2": "code"}, refreshes: 3},
+		{name: "Retried Once", reject: map[string]string{"token-3": "code", "token-4": "code"}, refreshes: 4, code: 4001},
+	}
+	for _, tt := range tests {
+		reject = tt.reject
+		var got int64
+		err := c.Call(context.Background(), "add", map[string]int{"v1": 1, "v2": 2}, &got)
+		if tt.code != 0 {
+			if e, ok := err.(*jrpc.Error); !ok || e.Code != tt.code {
+				t.Errorf("%v: Client.Call() error = %v, want code %v", tt.name, err, tt.code)
+			}
+		} else if err != nil || got != 3 {
+			t.Errorf("%v: Client.Call() = %v, %v, want 3", tt.name, got, err)
+		}
+		if refreshes != tt.refreshes {
+			t.Errorf("%v: refreshes = %v, want %v", tt.name, refreshes, tt.refreshes)
+		}
+	}
+}
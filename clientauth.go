@@ -0,0 +1,105 @@
+package jrpc2go
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrUnauthorized is returned by HTTPTransport when the server replies with 401 Unauthorized.
+var ErrUnauthorized = errors.New("jsonrpc: unauthorized")
+
+// ContextWithBearerToken returns a copy of ctx with the token that HTTPTransport sends on the
+// Authorization header of the request, it overrides the one of the Header field.
+func ContextWithBearerToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, bearerTokenKey, token)
+}
+
+// bearerTokenFromContext returns the token set with ContextWithBearerToken or empty if none.
+func bearerTokenFromContext(ctx context.Context) string {
+	t, _ := ctx.Value(bearerTokenKey).(string)
+	return t
+}
+
+// TokenRefresh returns a ClientInterceptor that sends the calls with the token returned by
+// refresh, with ContextWithBearerToken, and keeps it for the next calls. When a call fails with
+// ErrUnauthorized or an *Error with one of the codes, like the auth-expired code of the API,
+// refresh is called for a new token and the call is retried once with it.
+//
+// The token is first obtained on the first call. The calls failing at the same time share the
+// token of the first refresh, so a burst of expired calls refreshes the token only once.
+func TokenRefresh(refresh func(ctx context.Context) (string, error), codes ...ErrorCode) ClientInterceptor {
+	if refresh == nil {
+		panic("jsonrpc: token refresh should not be nil")
+	}
+	r := &tokenRefresher{refresh: refresh, codes: codes}
+	return func(next ClientCall) ClientCall {
+		return func(ctx context.Context, method string, params, result interface{}) error {
+			token, err := r.current(ctx)
+			if err != nil {
+				return err
+			}
+			err = next(ContextWithBearerToken(ctx, token), method, params, result)
+			if !r.expired(err) {
+				return err
+			}
+			if token, err = r.renew(ctx, token); err != nil {
+				return err
+			}
+			return next(ContextWithBearerToken(ctx, token), method, params, result)
+		}
+	}
+}
+
+// tokenRefresher keeps the token of TokenRefresh.
+type tokenRefresher struct {
+	refresh func(ctx context.Context) (string, error)
+	codes   []ErrorCode
+
+	mu    sync.Mutex
+	token string
+}
+
+// current returns the token, it's refreshed if there is none yet.
+func (r *tokenRefresher) current(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	token := r.token
+	r.mu.Unlock()
+	if token != "" {
+		return token, nil
+	}
+	return r.renew(ctx, "")
+}
+
+// renew returns a new token to replace the expired one, if it was already replaced by another
+// call the replacement is returned without a refresh.
+func (r *tokenRefresher) renew(ctx context.Context, expired string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token != expired {
+		return r.token, nil
+	}
+	token, err := r.refresh(ctx)
+	if err != nil {
+		return "", err
+	}
+	r.token = token
+	return token, nil
+}
+
+// expired returns true if the error means the token is no longer valid.
+func (r *tokenRefresher) expired(err error) bool {
+	if errors.Is(err, ErrUnauthorized) {
+		return true
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	for _, code := range r.codes {
+		if e.Code == code {
+			return true
+		}
+	}
+	return false
+}
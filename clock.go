@@ -0,0 +1,44 @@
+package jrpc2go
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time used by the Manager to measure the executions and to expire the
+// timeouts, a fake Clock allows tests to simulate timeouts without waiting for them.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock of the time package.
+type systemClock struct{}
+
+// Now returns time.Now().
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After returns time.After(d).
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// withTimeout returns a copy of ctx that is canceled once the timeout expires on the Clock.
+func (m *Manager) withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := m.clock.(systemClock); ok {
+		return context.WithTimeout(ctx, timeout)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	expired := m.clock.After(timeout)
+	go func() {
+		select {
+		case <-expired:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,102 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+// fakeClock is a Clock that only moves forward with Advance.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+	added   chan struct{}
+}
+
+type fakeWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0), added: make(chan struct{}, 16)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := fakeWaiter{at: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.waiters = append(c.waiters, w)
+	c.added <- struct{}{}
+	return w.c
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.c <- c.now
+	}
+	c.waiters = pending
+}
+
+func TestManagerBuilder_SetClock(t *testing.T) {
+	clock := newFakeClock()
+	block := &blockMethod{release: make(chan struct{})}
+	defer close(block.release)
+
+	m := jrpc.NewManagerBuilder().
+		SetClock(clock).
+		SetTimeout(time.Hour).
+		Add("block", block).
+		Build()
+
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		r := strings.NewReader(`{"jsonrpc":"2.0","method":"block","id":1}`)
+		done <- m.Handle(context.Background(), r, &out)
+	}()
+
+	// Wait for the timeout to be set before moving the clock
+	<-clock.added
+	clock.Advance(59 * time.Minute)
+	select {
+	case <-done:
+		t.Fatal("Handle() returned before the timeout expired")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Minute)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Handle() didn't return after the timeout expired")
+	}
+
+	want := `{"jsonrpc":"2.0","id":1,"error":{"code":-32002,"message":"Method execution timeout","data":{"method":"block","id":1,"timeout":3600000,"elapsed":3600000}}}` + "\n"
+	if out.String() != want {
+		t.Errorf("Handle() = %v, want %v", out.String(), want)
+	}
+}
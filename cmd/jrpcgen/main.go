@@ -0,0 +1,48 @@
+// Command jrpcgen generates the Go structs of the params and results from an OpenRPC document or
+// a JSON Schema.
+//
+// Usage:
+//
+//	jrpcgen -pkg api -in openrpc.json -out types.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func main() {
+	pkg := flag.String("pkg", "main", "package name of the generated source")
+	in := flag.String("in", "", "OpenRPC document or JSON Schema file, stdin if empty")
+	out := flag.String("out", "", "generated source file, stdout if empty")
+	flag.Parse()
+
+	src := os.Stdin
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		src = f
+	}
+
+	var buf bytes.Buffer
+	if err := jrpc.GenerateStructs(&buf, *pkg, src); err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := ioutil.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
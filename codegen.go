@@ -0,0 +1,265 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// GenerateStructs will write the Go source of the package pkg with a type for each schema of the
+// document, so schema-first APIs can decode the params and encode the results with Go structs.
+//
+// The document is an OpenRPC document, where the types are the components schemas and the
+// params and result of each method, named like SearchParams and SearchResult, or a standalone
+// JSON Schema, where the types are its definitions and the root schema named by its title.
+//
+// The struct fields have the json tag and the required ones the validate:"required" tag.
+func GenerateStructs(w io.Writer, pkg string, doc io.Reader) error {
+	b, err := ioutil.ReadAll(doc)
+	if err != nil {
+		return err
+	}
+	var top struct {
+		Methods []struct {
+			Name   string `json:"name"`
+			Params []struct {
+				Name     string          `json:"name"`
+				Required bool            `json:"required"`
+				Schema   json.RawMessage `json:"schema"`
+			} `json:"params"`
+			Result *struct {
+				Schema json.RawMessage `json:"schema"`
+			} `json:"result"`
+		} `json:"methods"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+		Title       string                     `json:"title"`
+		Definitions map[string]json.RawMessage `json:"definitions"`
+		Defs        map[string]json.RawMessage `json:"$defs"`
+	}
+	if err := json.Unmarshal(b, &top); err != nil {
+		return fmt.Errorf("jsonrpc: invalid schema document: %v", err)
+	}
+
+	g := &structGenerator{types: make(map[string]string)}
+	for name, s := range top.Components.Schemas {
+		g.define(goName(name), s)
+	}
+	for name, s := range top.Definitions {
+		g.define(goName(name), s)
+	}
+	for name, s := range top.Defs {
+		g.define(goName(name), s)
+	}
+	for _, m := range top.Methods {
+		base := goName(m.Name)
+		if len(m.Params) > 0 {
+			var fields []string
+			for _, p := range m.Params {
+				fields = append(fields, g.field(base+"Params", p.Name, p.Schema, p.Required))
+			}
+			g.types[base+"Params"] = "struct {\n" + strings.Join(fields, "\n") + "\n}"
+		}
+		if m.Result != nil && len(m.Result.Schema) > 0 {
+			g.define(base+"Result", m.Result.Schema)
+		}
+	}
+	if len(top.Methods) == 0 && top.Title != "" {
+		g.define(goName(top.Title), b)
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by jrpc2go GenerateStructs. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if g.usesTime {
+		src.WriteString("import \"time\"\n\n")
+	}
+	names := make([]string, 0, len(g.types))
+	for name := range g.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&src, "type %s %s\n\n", name, g.types[name])
+	}
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("jsonrpc: fail to format the generated source: %v", err)
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// structGenerator keeps the Go types generated from the schemas.
+type structGenerator struct {
+	types    map[string]string
+	usesTime bool
+}
+
+// genSchema is the subset of the JSON Schema keywords used to generate the types.
+type genSchema struct {
+	Ref                  string                     `json:"$ref"`
+	Type                 interface{}                `json:"type"`
+	Format               string                     `json:"format"`
+	Properties           map[string]json.RawMessage `json:"properties"`
+	Required             []string                   `json:"required"`
+	Items                json.RawMessage            `json:"items"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+}
+
+// define will add the named type of the schema.
+func (g *structGenerator) define(name string, raw json.RawMessage) {
+	// Reserved before the fields so the recursive references don't define it again
+	if _, ok := g.types[name]; ok {
+		return
+	}
+	g.types[name] = "interface{}"
+	g.types[name] = g.goType(name, raw)
+}
+
+// goType returns the Go type of the schema, the nested objects are defined as named types
+// prefixed by the parent name.
+func (g *structGenerator) goType(name string, raw json.RawMessage) string {
+	var s genSchema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "interface{}"
+	}
+	if s.Ref != "" {
+		return goName(s.Ref[strings.LastIndexByte(s.Ref, '/')+1:])
+	}
+
+	typ, nullable := schemaType(s.Type)
+	var t string
+	switch typ {
+	case "string":
+		t = "string"
+		if s.Format == "date-time" {
+			g.usesTime = true
+			t = "time.Time"
+		} else if s.Format == "byte" {
+			t = "[]byte"
+		}
+	case "integer":
+		t = "int64"
+	case "number":
+		t = "float64"
+	case "boolean":
+		t = "bool"
+	case "array":
+		t = "[]" + g.named(name+"Item", g.goType(name+"Item", s.Items))
+	case "object":
+		if len(s.Properties) == 0 {
+			elem := "interface{}"
+			if b := bytes.TrimSpace(s.AdditionalProperties); len(b) > 0 && b[0] == '{' {
+				elem = g.named(name+"Value", g.goType(name+"Value", s.AdditionalProperties))
+			}
+			t = "map[string]" + elem
+			break
+		}
+		required := make(map[string]bool, len(s.Required))
+		for _, r := range s.Required {
+			required[r] = true
+		}
+		props := make([]string, 0, len(s.Properties))
+		for p := range s.Properties {
+			props = append(props, p)
+		}
+		sort.Strings(props)
+		fields := make([]string, 0, len(props))
+		for _, p := range props {
+			fields = append(fields, g.field(name, p, s.Properties[p], required[p]))
+		}
+		t = "struct {\n" + strings.Join(fields, "\n") + "\n}"
+	default:
+		return "interface{}"
+	}
+	if nullable && !strings.HasPrefix(t, "[]") && !strings.HasPrefix(t, "map[") {
+		return "*" + t
+	}
+	return t
+}
+
+// field returns the struct field of the property, the nested objects are named types.
+func (g *structGenerator) field(parent, prop string, raw json.RawMessage, required bool) string {
+	name := goName(prop)
+	t := g.named(parent+name, g.goType(parent+name, raw))
+	// The optional objects are pointers, which also breaks the recursion of the references
+	if !required && isNamedType(t) {
+		t = "*" + t
+	}
+	tag := `json:"` + prop + `,omitempty"`
+	if required {
+		tag = `json:"` + prop + `" validate:"required"`
+	}
+	return fmt.Sprintf("%s %s `%s`", name, t, tag)
+}
+
+// named returns the name of a new type for the struct type t, the other types are returned as
+// they are so they're written inline.
+func (g *structGenerator) named(name, t string) string {
+	if !strings.HasPrefix(t, "struct {") {
+		return t
+	}
+	g.types[name] = t
+	return name
+}
+
+// isNamedType reports if t is a generated type.
+func isNamedType(t string) bool {
+	switch t {
+	case "string", "int64", "float64", "bool", "interface{}", "time.Time":
+		return false
+	}
+	return !strings.HasPrefix(t, "[]") && !strings.HasPrefix(t, "map[") && !strings.HasPrefix(t, "*")
+}
+
+// schemaType returns the type of the schema and if it's nullable, like ["string", "null"].
+func schemaType(t interface{}) (string, bool) {
+	switch v := t.(type) {
+	case string:
+		return v, false
+	case []interface{}:
+		typ, nullable := "", false
+		for _, e := range v {
+			if s, _ := e.(string); s == "null" {
+				nullable = true
+			} else if typ == "" {
+				typ = s
+			}
+		}
+		return typ, nullable
+	}
+	return "", false
+}
+
+// commonInitialisms are written in upper case on the Go names.
+var commonInitialisms = map[string]bool{"id": true, "url": true, "uri": true, "http": true, "json": true, "api": true, "ip": true, "uuid": true}
+
+// goName returns the exported Go name of the JSON name, like "user_id" to "UserID".
+func goName(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, w := range words {
+		if commonInitialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "T" + name
+	}
+	return name
+}
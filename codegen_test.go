@@ -0,0 +1,60 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestGenerateStructs(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{
+			name: "OpenRPC",
+			doc: `{"methods":[{"name":"get_user","params":[{"name":"user_id","required":true,"schema":{"type":"integer"}}],
+				"result":{"schema":{"$ref":"#/components/schemas/User"}}}],
+				"components":{"schemas":{"User":{"type":"object","required":["name"],"properties":{
+					"name":{"type":"string"},"born":{"type":"string","format":"date-time"},"boss":{"$ref":"#/components/schemas/User"}}}}}}`,
+			want: []string{
+				"package api",
+				`import "time"`,
+				"type GetUserParams struct {\n\tUserID int64 `json:\"user_id\" validate:\"required\"`\n}",
+				"type GetUserResult User",
+				"Boss *User      `json:\"boss,omitempty\"`",
+				"Born time.Time  `json:\"born,omitempty\"`",
+				"Name string     `json:\"name\" validate:\"required\"`",
+			},
+		},
+		{
+			name: "JSON Schema",
+			doc: `{"title":"order","type":"object","properties":{"items":{"type":"array","items":{"type":"object","properties":{"sku":{"type":"string"}}}},
+				"notes":{"type":["string","null"]},"meta":{"type":"object","additionalProperties":{"type":"number"}}}}`,
+			want: []string{
+				"Items []OrderItemsItem    `json:\"items,omitempty\"`",
+				"Meta  map[string]float64 `json:\"meta,omitempty\"`",
+				"Notes *string            `json:\"notes,omitempty\"`",
+				"type OrderItemsItem struct {\n\tSku string `json:\"sku,omitempty\"`\n}",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w bytes.Buffer
+			if err := jrpc.GenerateStructs(&w, "api", strings.NewReader(tt.doc)); err != nil {
+				t.Fatalf("GenerateStructs() error = %v", err)
+			}
+			// The alignment of the fields is left to gofmt
+			got := strings.Join(strings.Fields(w.String()), " ")
+			for _, want := range tt.want {
+				if !strings.Contains(got, strings.Join(strings.Fields(want), " ")) {
+					t.Errorf("GenerateStructs() = %s\nwant it to contain %s", w.String(), want)
+				}
+			}
+		})
+	}
+}
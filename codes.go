@@ -0,0 +1,101 @@
+package jrpc2go
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrReservedErrorCode is returned by RegisterErrorCode for the codes reserved by the JSON-RPC
+// spec, from -32768 to -32000.
+var ErrReservedErrorCode = errors.New("jsonrpc: error code reserved by the spec")
+
+// ErrDuplicateErrorCode is returned by RegisterErrorCode for a code already registered with
+// another name or message.
+var ErrDuplicateErrorCode = errors.New("jsonrpc: error code already registered")
+
+// errorCodeInfo is the name and the default message of an error code.
+type errorCodeInfo struct {
+	name    string
+	message string
+}
+
+// builtinErrorCodes are the names of the codes used by this package.
+var builtinErrorCodes = map[ErrorCode]string{
+	errCodeParseError:        "ParseError",
+	errCodeInvalidRequest:    "InvalidRequest",
+	errCodeMethodNotFound:    "MethodNotFound",
+	errCodeInvalidParams:     "InvalidParams",
+	errCodeInternal:          "InternalError",
+	errCodeInvalidRPCVersion: "InvalidRPCVersion",
+	errCodeExecutionTimeout:  "ExecutionTimeout",
+	errCodeServerOverloaded:  "ServerOverloaded",
+	errCodeExecutionCanceled: "ExecutionCanceled",
+	errCodeClientCanceled:    "ClientCanceled",
+	errCodeResponseTooLarge:  "ResponseTooLarge",
+	errCodeMethodDisabled:    "MethodDisabled",
+	errCodeQuotaExceeded:     "QuotaExceeded",
+}
+
+// errorCodes are the codes registered by the application.
+var errorCodes = struct {
+	sync.RWMutex
+	m map[ErrorCode]errorCodeInfo
+}{m: make(map[ErrorCode]errorCodeInfo)}
+
+// RegisterErrorCode will declare an application error code with its name and default message,
+// so the errors created with NewError are consistent across all the methods. It's meant to be
+// called on the package initialization.
+//
+// It returns ErrReservedErrorCode for the codes reserved by the spec and ErrDuplicateErrorCode
+// if the code is already registered with another name or message.
+func RegisterErrorCode(code ErrorCode, name, message string) error {
+	if code >= -32768 && code <= -32000 {
+		return fmt.Errorf("%w: %d", ErrReservedErrorCode, code)
+	}
+	errorCodes.Lock()
+	defer errorCodes.Unlock()
+	if info, ok := errorCodes.m[code]; ok {
+		if info.name == name && info.message == message {
+			return nil
+		}
+		return fmt.Errorf("%w: %d is %s", ErrDuplicateErrorCode, code, info.name)
+	}
+	errorCodes.m[code] = errorCodeInfo{name: name, message: message}
+	return nil
+}
+
+// NewError returns an Error with the code, its default message and the data, the default
+// messages are the ones of the spec for the reserved codes and the ones declared with
+// RegisterErrorCode for the application codes.
+func NewError(code ErrorCode, data interface{}) *Error {
+	if code >= -32768 && code <= -32000 {
+		e := newError(code, data)
+		if e.Message == "" {
+			e.Message = "Server error"
+		}
+		return e
+	}
+	errorCodes.RLock()
+	info, ok := errorCodes.m[code]
+	errorCodes.RUnlock()
+	if !ok {
+		info.message = fmt.Sprintf("Error %d", code)
+	}
+	return &Error{Code: code, Message: info.message, Data: data}
+}
+
+// String returns the name of the code, like "MethodNotFound" or the name declared with
+// RegisterErrorCode, or the number when the code is unknown.
+func (c ErrorCode) String() string {
+	if name, ok := builtinErrorCodes[c]; ok {
+		return name
+	}
+	errorCodes.RLock()
+	info, ok := errorCodes.m[c]
+	errorCodes.RUnlock()
+	if ok {
+		return info.name
+	}
+	return fmt.Sprintf("ErrorCode(%d)", int(c))
+}
@@ -0,0 +1,78 @@
+package jrpc2go_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestRegisterErrorCode(t *testing.T) {
+	if err := jrpc.RegisterErrorCode(1001, "QuotaExceeded", "quota exceeded"); err != nil {
+		t.Fatalf("RegisterErrorCode() error = %v", err)
+	}
+	if err := jrpc.RegisterErrorCode(1001, "QuotaExceeded", "quota exceeded"); err != nil {
+		t.Errorf("RegisterErrorCode() same declaration error = %v", err)
+	}
+	if err := jrpc.RegisterErrorCode(1001, "Other", "other"); !errors.Is(err, jrpc.ErrDuplicateErrorCode) {
+		t.Errorf("RegisterErrorCode() error = %v, want %v", err, jrpc.ErrDuplicateErrorCode)
+	}
+	if err := jrpc.RegisterErrorCode(-32050, "Reserved", "reserved"); !errors.Is(err, jrpc.ErrReservedErrorCode) {
+		t.Errorf("RegisterErrorCode() error = %v, want %v", err, jrpc.ErrReservedErrorCode)
+	}
+
+	tests := []struct {
+		code        jrpc.ErrorCode
+		wantMessage string
+		wantName    string
+	}{
+		{code: 1001, wantMessage: "quota exceeded", wantName: "QuotaExceeded"},
+		{code: -32601, wantMessage: "Method not found", wantName: "MethodNotFound"},
+		{code: -32099, wantMessage: "Server error", wantName: "ErrorCode(-32099)"},
+		{code: 7, wantMessage: "Error 7", wantName: "ErrorCode(7)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantName, func(t *testing.T) {
+			e := jrpc.NewError(tt.code, "data")
+			if e.Code != tt.code || e.Message != tt.wantMessage || e.Data != "data" {
+				t.Errorf("NewError() = %+v, want message %v", e, tt.wantMessage)
+			}
+			if got := tt.code.String(); got != tt.wantName {
+				t.Errorf("ErrorCode.String() = %v, want %v", got, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestError_DecodeData(t *testing.T) {
+	e := jrpc.NewInvalidParamsError(jrpc.FieldViolation{Field: "address.zip", Description: "required"})
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	// The client receives the data as a generic JSON value
+	var got jrpc.Error
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	var br jrpc.BadRequest
+	if err := got.DecodeData(&br); err != nil {
+		t.Fatalf("DecodeData() error = %v", err)
+	}
+	if got.Code != -32602 || len(br.FieldViolations) != 1 || br.FieldViolations[0].Field != "address.zip" {
+		t.Errorf("DecodeData() = %+v, %+v, want the address.zip violation", got, br)
+	}
+
+	var ri jrpc.RetryInfo
+	if err := jrpc.NewRetryError(1001, 2*time.Second).DecodeData(&ri); err != nil || ri.RetryAfter != 2000 {
+		t.Errorf("NewRetryError() data = %+v, %v, want retryAfter 2000", ri, err)
+	}
+
+	var di jrpc.DebugInfo
+	if err := jrpc.NewDebugError(errors.New("boom"), true).DecodeData(&di); err != nil || di.Detail != "boom" || len(di.StackEntries) == 0 {
+		t.Errorf("NewDebugError() data = %+v, %v, want the detail and the stack", di, err)
+	}
+}
@@ -0,0 +1,160 @@
+package jrpc2go
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// CompressionMethod is the method of the request that negotiates the compression of a Server
+// connection, it must be the first message sent by the client.
+//
+// The params are the names of the codecs supported by the client in order of preference and the
+// result is the name of the codec selected by the server, null if none is supported. Once the
+// response is received all the messages in both directions are compressed frames, the 4 bytes
+// big-endian length of the compressed message followed by the compressed message.
+const CompressionMethod = "rpc.compression"
+
+// maxFrameSize is the upper bound of WithMaxFrameSize.
+const maxFrameSize = 64 << 20
+
+// defaultMaxFrameSize is the maximum size of a compressed frame and of the message it
+// decompresses to if none is specified.
+const defaultMaxFrameSize = 4 << 20
+
+// errFrameTooLarge is returned when a compressed frame or its message exceeds the maximum size.
+var errFrameTooLarge = errors.New("jsonrpc: compressed frame too large")
+
+// Codec compresses the messages of a Server connection, the zero dependencies package only
+// provides GzipCodec but any other, like zstd, can be adapted to it.
+type Codec interface {
+	// Name returns the name used to negotiate the codec, like "gzip" or "zstd".
+	Name() string
+
+	// NewWriter returns a writer that compresses to w, the message is complete after Close.
+	NewWriter(w io.Writer) io.WriteCloser
+
+	// NewReader returns a reader that decompresses from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// GzipCodec returns the Codec of the gzip compression with the default level.
+func GzipCodec() Codec {
+	return gzipCodec{}
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string {
+	return "gzip"
+}
+
+func (gzipCodec) NewWriter(w io.Writer) io.WriteCloser {
+	return gzip.NewWriter(w)
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// WithCompression enables the negotiation of the compression with CompressionMethod or
+// HelloMethod, the first codec of the client also in codecs is selected. The connections that
+// don't start with the negotiation are not compressed.
+func WithCompression(codecs ...Codec) ServerOption {
+	return func(s *Server) {
+		s.codecs = make(map[string]Codec, len(codecs))
+		for _, c := range codecs {
+			s.codecs[c.Name()] = c
+		}
+	}
+}
+
+// WithMaxFrameSize sets the maximum size of a compressed frame and of the message it
+// decompresses to, the connection is closed when a frame is larger. It's ignored if it's not
+// between 1 and 64MB.
+//
+// Default is 4MB.
+func WithMaxFrameSize(n int) ServerOption {
+	return func(s *Server) {
+		if n > 0 && n <= maxFrameSize {
+			s.maxFrameSize = n
+		}
+	}
+}
+
+// readFrame returns the message of the next compressed frame of at most max bytes, an *Error if
+// the frame doesn't decompress, the stream is still in sync after it.
+//
+// The whitespace before the frame, like the newline after the negotiation, is skipped, the
+// first byte of the length is never whitespace since the frames are limited to maxFrameSize.
+//
+// The frame is buffered as it's read, so the length sent by the peer doesn't allocate the
+// memory before the bytes are received.
+func readFrame(r *bufio.Reader, c Codec, max int) (json.RawMessage, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+			_ = r.UnreadByte()
+			break
+		}
+	}
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n > uint32(max) {
+		return nil, errFrameTooLarge
+	}
+	var frame bytes.Buffer
+	if _, err := io.CopyN(&frame, r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	zr, err := c.NewReader(&frame)
+	if err != nil {
+		return nil, newError(errCodeParseError, err.Error())
+	}
+	defer zr.Close()
+	msg, err := ioutil.ReadAll(io.LimitReader(zr, int64(max)+1))
+	if err != nil {
+		return nil, newError(errCodeParseError, err.Error())
+	}
+	if len(msg) > max {
+		return nil, errFrameTooLarge
+	}
+	return msg, nil
+}
+
+// writeFrame will write the message b as a compressed frame.
+func writeFrame(w io.Writer, c Codec, b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	buf.Write([]byte{0, 0, 0, 0})
+	zw := c.NewWriter(&buf)
+	if _, err := zw.Write(bytes.TrimSuffix(b, []byte{'\n'})); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	frame := buf.Bytes()
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+	if _, err := w.Write(frame); err != nil {
+		return err
+	}
+	return flush(w)
+}
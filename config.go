@@ -0,0 +1,138 @@
+package jrpc2go
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Duration is a time.Duration that is represented on JSON as a string like "1.5s" or "300ms".
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string using time.ParseDuration.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("jsonrpc: duration must be a string like \"10s\": %v", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("jsonrpc: %v", err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON returns the duration as a string like "1.5s".
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// MethodFactory creates a Method from the options of its configuration.
+type MethodFactory func(options json.RawMessage) (Method, error)
+
+// Config represents the configuration of a Manager that can be loaded from a file with LoadConfig,
+// so the policies can change without recompiling.
+//
+// Timeout, BatchTimeout, MaxInFlight and RetryAfter - The same as the ManagerBuilder setters.
+//
+// Methods - The methods to register, only these methods are available.
+//
+// Transports - The bindings where the application should serve the Manager.
+type Config struct {
+	Timeout      Duration          `json:"timeout,omitempty"`
+	BatchTimeout Duration          `json:"batchTimeout,omitempty"`
+	MaxInFlight  int               `json:"maxInFlight,omitempty"`
+	RetryAfter   Duration          `json:"retryAfter,omitempty"`
+	Methods      []MethodConfig    `json:"methods"`
+	Transports   []TransportConfig `json:"transports,omitempty"`
+}
+
+// MethodConfig represents the configuration of one method.
+//
+// Name - The method name used on the requests.
+//
+// Factory - The name of the MethodFactory that creates the method, it's the Name if empty.
+//
+// Disabled - If true the method is not registered.
+//
+// Timeout - The timeout for the method execution, if empty the Config.Timeout is used.
+//
+// Options - Any value passed to the MethodFactory.
+type MethodConfig struct {
+	Name     string          `json:"name"`
+	Factory  string          `json:"factory,omitempty"`
+	Disabled bool            `json:"disabled,omitempty"`
+	Timeout  Duration        `json:"timeout,omitempty"`
+	Options  json.RawMessage `json:"options,omitempty"`
+}
+
+// TransportConfig represents a binding where the Manager should be served.
+//
+// Type - The transport type, like "http".
+//
+// Address - The address to listen on, like "localhost:8000".
+//
+// Path - The HTTP path of the endpoint, it's only used by HTTP transports.
+type TransportConfig struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	Path    string `json:"path,omitempty"`
+}
+
+// LoadConfig will read the JSON configuration from r, unknown members are rejected to catch
+// typos on the configuration file.
+func LoadConfig(r io.Reader) (*Config, error) {
+	var c Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("jsonrpc: invalid configuration: %v", err)
+	}
+	return &c, nil
+}
+
+// Builder returns a ManagerBuilder with the configuration, the methods are created with the
+// factories registered by name.
+//
+// It returns an error if a method doesn't have a name, the factory is not registered or the
+// factory fails to create the method.
+func (c *Config) Builder(factories map[string]MethodFactory) (*ManagerBuilder, error) {
+	mb := NewManagerBuilder()
+	if c.Timeout > 0 {
+		mb.SetTimeout(time.Duration(c.Timeout))
+	}
+	mb.SetBatchTimeout(time.Duration(c.BatchTimeout)).
+		SetMaxInFlight(c.MaxInFlight).
+		SetRetryAfter(time.Duration(c.RetryAfter))
+
+	for _, mc := range c.Methods {
+		if mc.Name == "" {
+			return nil, fmt.Errorf("jsonrpc: method name should not be empty")
+		}
+		if mc.Disabled {
+			continue
+		}
+		name := mc.Factory
+		if name == "" {
+			name = mc.Name
+		}
+		f, ok := factories[name]
+		if !ok {
+			return nil, fmt.Errorf("jsonrpc: method factory %q for method %q not registered", name, mc.Name)
+		}
+		method, err := f(mc.Options)
+		if err != nil {
+			return nil, fmt.Errorf("jsonrpc: fail to create method %q: %v", mc.Name, err)
+		}
+		if method == nil {
+			return nil, fmt.Errorf("jsonrpc: method factory %q returned a nil method", name)
+		}
+		mb.Add(mc.Name, method)
+		if mc.Timeout > 0 {
+			mb.SetMethodTimeout(mc.Name, time.Duration(mc.Timeout))
+		}
+	}
+	return mb, nil
+}
@@ -0,0 +1,88 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestConfig_Builder(t *testing.T) {
+	factories := map[string]jrpc.MethodFactory{
+		"add": func(options json.RawMessage) (jrpc.Method, error) {
+			return &addMethod{}, nil
+		},
+		"name": func(options json.RawMessage) (jrpc.Method, error) {
+			m := &nameMethod{}
+			return m, json.Unmarshal(options, &m.tag)
+		},
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		r       string
+		wantW   string
+		wantErr bool
+	}{
+		{
+			name:   "Valid Config",
+			config: `{"timeout":"1s","methods":[{"name":"add"},{"name":"hello","factory":"name","options":"tag","timeout":"50ms"}]}`,
+			r:      `[{"jsonrpc":"2.0","method":"add","id":1,"params":{"v1":1,"v2":2}},{"jsonrpc":"2.0","method":"hello","id":2}]`,
+			wantW:  `[{"jsonrpc":"2.0","id":1,"result":3},{"jsonrpc":"2.0","id":2,"result":"tag:hello"}]`,
+		},
+		{
+			name:   "Disabled Method",
+			config: `{"methods":[{"name":"add","disabled":true}]}`,
+			r:      `{"jsonrpc":"2.0","method":"add","id":1,"params":{"v1":1,"v2":2}}`,
+			wantW:  `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found","data":"add"}}`,
+		},
+		{
+			name:    "Unknown Member",
+			config:  `{"timeuot":"1s","methods":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid Duration",
+			config:  `{"timeout":"1 second","methods":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Factory Not Registered",
+			config:  `{"methods":[{"name":"sub"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Factory Fails",
+			config:  `{"methods":[{"name":"hello","factory":"name","options":1}]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := jrpc.LoadConfig(strings.NewReader(tt.config))
+			var mb *jrpc.ManagerBuilder
+			if err == nil {
+				mb, err = c.Builder(factories)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Config.Builder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			m := mb.Build()
+			w := &bytes.Buffer{}
+			if err := m.Handle(context.Background(), strings.NewReader(tt.r), w); err != nil {
+				t.Fatalf("Manager.Handle() error = %v", err)
+			}
+			if gotW := strings.TrimSpace(w.String()); gotW != tt.wantW {
+				t.Errorf("Manager.Handle() result = %v, want %v", gotW, tt.wantW)
+			}
+		})
+	}
+}
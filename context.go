@@ -0,0 +1,71 @@
+package jrpc2go
+
+import (
+	"context"
+	"net"
+)
+
+// contextKey is the type of the keys used by this package to store values on a context.
+type contextKey int
+
+const (
+	versionKey contextKey = iota
+	notifierKey
+	correlationKey
+	localeKey
+	txKey
+	connIDKey
+	chunkKey
+	remoteAddrKey
+	peerCredKey
+	transportInfoKey
+	costKey
+	bearerTokenKey
+	memoryKey
+	noCancelKey
+)
+
+// ContextWithVersion returns a copy of ctx that selects the version of the methods registered
+// with ManagerBuilder.AddVersion, so transports can select a version from request metadata
+// like an HTTP header.
+func ContextWithVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, versionKey, version)
+}
+
+// versionFromContext returns the method version selected on the context or empty if none.
+func versionFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(versionKey).(string)
+	return v
+}
+
+// ContextWithCorrelationID returns a copy of ctx with the correlation ID adopted by the methods
+// executed with it, so transports can propagate the ID received from other services.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID of the request being executed or empty
+// if there is none, the Manager generates one for each call that doesn't have it.
+func CorrelationIDFromContext(ctx context.Context) string {
+	switch id := ctx.Value(correlationKey).(type) {
+	case string:
+		return id
+	case *lazyCorrelationID:
+		return id.get()
+	}
+	return ""
+}
+
+// ContextWithRemoteAddr returns a copy of ctx with the address of the client that sent the
+// request, the socket Server sets it on the requests of its connections.
+func ContextWithRemoteAddr(ctx context.Context, addr net.Addr) context.Context {
+	return context.WithValue(ctx, remoteAddrKey, addr)
+}
+
+// RemoteAddrFromContext returns the address of the client that sent the request or nil if it's
+// unknown. Behind a load balancer it's the client address of the PROXY header when the Server
+// is configured with WithProxyProtocol.
+func RemoteAddrFromContext(ctx context.Context) net.Addr {
+	addr, _ := ctx.Value(remoteAddrKey).(net.Addr)
+	return addr
+}
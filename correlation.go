@@ -0,0 +1,93 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+)
+
+// CorrelationIDHeader is the HTTP header from where the correlation ID is adopted and where it's
+// echoed on the response.
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// CorrelationData is the error data when ManagerBuilder.EchoCorrelationID is used, it carries
+// the correlation ID together with the original data of the error.
+type CorrelationData struct {
+	CorrelationID string      `json:"correlationId"`
+	Data          interface{} `json:"data,omitempty"`
+}
+
+// newCorrelationID returns a random 128-bit hex encoded ID.
+func newCorrelationID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// lazyCorrelationID is the correlation ID generated on the first use, so the calls that never
+// read it don't read the random source.
+type lazyCorrelationID struct {
+	once sync.Once
+	id   string
+}
+
+// get returns the ID, it's generated on the first call.
+func (l *lazyCorrelationID) get() string {
+	l.once.Do(func() { l.id = newCorrelationID() })
+	return l.id
+}
+
+// correlationMeta is the meta member of the params from where the correlation ID is adopted.
+type correlationMeta struct {
+	Meta struct {
+		CorrelationID string `json:"correlationId"`
+	} `json:"_meta"`
+}
+
+// metaCorrelationID returns the correlation ID of the `_meta` member of the params object, empty
+// if there is none.
+func metaCorrelationID(req *Request) string {
+	if req.Params == nil || !bytes.Contains(*req.Params, []byte(`"_meta"`)) {
+		return ""
+	}
+	var p correlationMeta
+	if err := json.Unmarshal(*req.Params, &p); err != nil {
+		return ""
+	}
+	return p.Meta.CorrelationID
+}
+
+// correlate returns the context with the correlation ID of the request, the one of ctx, the one
+// of the `_meta` member of the params or one generated on the first use.
+func correlate(ctx context.Context, req *Request) context.Context {
+	switch id := ctx.Value(correlationKey).(type) {
+	case *lazyCorrelationID:
+		return ctx
+	case string:
+		if id != "" {
+			return ctx
+		}
+	}
+	if id := metaCorrelationID(req); id != "" {
+		return ContextWithCorrelationID(ctx, id)
+	}
+	return context.WithValue(ctx, correlationKey, &lazyCorrelationID{})
+}
+
+// echoCorrelation will replace the error of res with a copy that carries the correlation ID.
+func (m *Manager) echoCorrelation(ctx context.Context, res *Response) {
+	if !m.echoCorrelationID || res.Error == nil {
+		return
+	}
+	id := CorrelationIDFromContext(ctx)
+	res.Error = &Error{
+		Code:    res.Error.Code,
+		Message: res.Error.Message,
+		Data:    CorrelationData{CorrelationID: id, Data: res.Error.Data},
+	}
+}
@@ -0,0 +1,86 @@
+package jrpc2go
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// CostReport is the cost reported by the execution of a request with AddCost.
+//
+// Costs - The total of each kind of cost reported, like "rows" or "bytes".
+type CostReport struct {
+	Method string
+	ID     *json.RawMessage
+	Costs  map[string]int64
+}
+
+// OnCost registers fn to receive the cost of each request whose method reported one with
+// AddCost, so quota systems can be built on top of the rate limiter, like charging the cost to
+// the API key set on the context with WithContextFunc. It can be called more than once to register
+// more callbacks.
+//
+// The callbacks are called on the goroutine of the request after the method finishes so they
+// must not block. The cost is also aggregated per method on the Stats when they are enabled.
+func (mb *ManagerBuilder) OnCost(fn func(ctx context.Context, r CostReport)) *ManagerBuilder {
+	mb.costFuncs = append(mb.costFuncs, fn)
+	return mb
+}
+
+// AddCost will add n to the kind of cost of the request being executed, the methods call it with
+// the context of the request to report the work done, like the rows scanned. It does nothing when
+// the Manager is not collecting the cost, Stats, OnCost and SetQuotas are all disabled.
+func AddCost(ctx context.Context, kind string, n int64) {
+	if c, ok := ctx.Value(costKey).(*costMeter); ok {
+		c.add(kind, n)
+	}
+}
+
+// costMeter accumulates the cost reported by a method, it can be still running after a timeout.
+type costMeter struct {
+	mu    sync.Mutex
+	costs map[string]int64
+}
+
+func (c *costMeter) add(kind string, n int64) {
+	c.mu.Lock()
+	if c.costs == nil {
+		c.costs = make(map[string]int64)
+	}
+	c.costs[kind] += n
+	c.mu.Unlock()
+}
+
+// snapshot returns a copy of the cost reported so far, nil if none.
+func (c *costMeter) snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.costs) == 0 {
+		return nil
+	}
+	costs := make(map[string]int64, len(c.costs))
+	for kind, n := range c.costs {
+		costs[kind] = n
+	}
+	return costs
+}
+
+// withCostMeter returns a copy of ctx with a new meter if the cost is being collected.
+func (m *Manager) withCostMeter(ctx context.Context) (context.Context, *costMeter) {
+	if m.stats == nil && len(m.costFuncs) == 0 && m.quota == nil {
+		return ctx, nil
+	}
+	c := &costMeter{}
+	return context.WithValue(ctx, costKey, c), c
+}
+
+// reportCost will send the cost of the request to the OnCost callbacks.
+func (m *Manager) reportCost(ctx context.Context, req *Request, costs map[string]int64) {
+	if costs == nil {
+		return
+	}
+	r := CostReport{Method: req.Method, ID: req.ID, Costs: costs}
+	for _, fn := range m.costFuncs {
+		fn(ctx, r)
+	}
+}
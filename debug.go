@@ -0,0 +1,170 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CapturedRequest is a request and its response captured for debugging, the params and the result
+// are redacted by the Manager Redactor.
+//
+// Time - When the request was received.
+//
+// Duration - The execution time in milliseconds.
+type CapturedRequest struct {
+	Time          time.Time        `json:"time"`
+	Method        string           `json:"method"`
+	ID            *json.RawMessage `json:"id"`
+	CorrelationID string           `json:"correlationId"`
+	Params        json.RawMessage  `json:"params,omitempty"`
+	Result        json.RawMessage  `json:"result,omitempty"`
+	Error         *Error           `json:"error,omitempty"`
+	Duration      int64            `json:"duration"`
+}
+
+// captureRing keeps the most recent captured requests.
+type captureRing struct {
+	mu      sync.Mutex
+	entries []CapturedRequest
+	next    int
+	full    bool
+}
+
+// newCaptureRing returns an empty ring with capacity for n requests.
+func newCaptureRing(n int) *captureRing {
+	return &captureRing{entries: make([]CapturedRequest, n)}
+}
+
+// add will keep the request replacing the oldest one if the ring is full.
+func (r *captureRing) add(c CapturedRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = c
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns the captured requests from the oldest to the most recent.
+func (r *captureRing) list() []CapturedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]CapturedRequest(nil), r.entries[:r.next]...)
+	}
+	return append(append([]CapturedRequest(nil), r.entries[r.next:]...), r.entries[:r.next]...)
+}
+
+// captureRequest returns the redacted capture of the request and its response.
+func (m *Manager) captureRequest(req *Request, res *Response, id string, elapsed time.Duration) CapturedRequest {
+	c := CapturedRequest{
+		Time:          m.clock.Now().Add(-elapsed),
+		Method:        req.Method,
+		ID:            req.ID,
+		CorrelationID: id,
+		Error:         res.Error,
+		Duration:      elapsed.Milliseconds(),
+	}
+	if req.Params != nil {
+		c.Params = m.Redact(req.Method, req.Params)
+	}
+	if res.Error == nil {
+		c.Result = m.Redact(req.Method, res.Result)
+	}
+	return c
+}
+
+// debugConfig is the Manager configuration exposed on the DebugHandler.
+type debugConfig struct {
+	Timeout      string `json:"timeout"`
+	BatchTimeout string `json:"batchTimeout"`
+	MaxInFlight  int64  `json:"maxInFlight"`
+	RetryAfter   string `json:"retryAfter"`
+	Middleware   int    `json:"middleware"`
+	Redacted     bool   `json:"redacted"`
+}
+
+// debugMethods is the method table exposed on the DebugHandler.
+type debugMethods struct {
+	Methods  []string            `json:"methods"`
+	Versions map[string][]string `json:"versions,omitempty"`
+	Patterns int                 `json:"patterns"`
+	Timeouts map[string]string   `json:"timeouts,omitempty"`
+}
+
+// DebugHandler returns an HTTP handler for live troubleshooting, like /debug/vars, that replies
+// with the Manager configuration, the method table, the requests being executed, the stats when
+// enabled and the recent requests captured when ManagerBuilder.SetCaptureSize is used.
+//
+// It exposes internal details and must only be served on a private address or behind
+// authorization.
+func DebugHandler(m *Manager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		debug := map[string]interface{}{
+			"config": debugConfig{
+				Timeout:      m.timeout.String(),
+				BatchTimeout: m.batchTimeout.String(),
+				MaxInFlight:  m.maxInFlight,
+				RetryAfter:   m.retryAfter.String(),
+				Middleware:   len(m.middleware),
+				Redacted:     m.redactor != nil,
+			},
+			"methods":  m.table.describe(),
+			"inFlight": m.InFlight(),
+		}
+		if m.stats != nil {
+			debug["stats"] = m.stats.snapshot()
+		}
+		if m.capture != nil {
+			debug["recent"] = m.capture.list()
+		}
+
+		var out bytes.Buffer
+		enc := json.NewEncoder(&out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(debug); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(contentTypeKey, contentTypeValue)
+		if _, err := w.Write(out.Bytes()); err != nil {
+			//TODO not sure what to do here
+		}
+	})
+}
+
+// describe returns the registered methods for debugging.
+func (t *methodTable) describe() debugMethods {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	d := debugMethods{
+		Methods:  make([]string, 0, len(t.methods)),
+		Versions: make(map[string][]string, len(t.versions)),
+		Patterns: len(t.patterns),
+		Timeouts: make(map[string]string, len(t.timeouts)),
+	}
+	for name := range t.methods {
+		d.Methods = append(d.Methods, name)
+	}
+	sort.Strings(d.Methods)
+	for name, vs := range t.versions {
+		for _, v := range vs {
+			d.Versions[name] = append(d.Versions[name], v.version)
+		}
+	}
+	for name, timeout := range t.timeouts {
+		d.Timeouts[name] = timeout.String()
+	}
+	return d
+}
@@ -0,0 +1,67 @@
+package jrpc2go_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestDebugHandler(t *testing.T) {
+	manager := jrpc.NewManagerBuilder().
+		SetCaptureSize(2).
+		SetRedactor(jrpc.FieldMask{"*": {"password"}}).
+		Add("echo", &echoMethod{}).
+		Add("login", &echoMethod{}).
+		Build()
+
+	reqs := []string{
+		`{"jsonrpc":"2.0","method":"echo","params":"first","id":1}`,
+		`{"jsonrpc":"2.0","method":"echo","params":"second","id":2}`,
+		`{"jsonrpc":"2.0","method":"login","params":{"password":"secret"},"id":3}`,
+	}
+	for _, r := range reqs {
+		var out strings.Builder
+		if err := manager.Handle(context.Background(), strings.NewReader(r), &out); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	jrpc.DebugHandler(&manager).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/rpc", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("DebugHandler() status = %v, want %v", rec.Code, http.StatusOK)
+	}
+
+	var got struct {
+		Methods struct {
+			Methods []string `json:"methods"`
+		} `json:"methods"`
+		Recent []jrpc.CapturedRequest `json:"recent"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("DebugHandler() invalid body = %v", err)
+	}
+	if strings.Join(got.Methods.Methods, ",") != "echo,login" {
+		t.Errorf("DebugHandler() methods = %v, want [echo login]", got.Methods.Methods)
+	}
+	if len(got.Recent) != 2 {
+		t.Fatalf("DebugHandler() recent = %v, want 2", len(got.Recent))
+	}
+	if string(got.Recent[0].Result) != `"second"` {
+		t.Errorf("DebugHandler() oldest result = %s, want \"second\"", got.Recent[0].Result)
+	}
+	if strings.Contains(string(got.Recent[1].Params), "secret") {
+		t.Errorf("DebugHandler() params not redacted = %s", got.Recent[1].Params)
+	}
+
+	rec = httptest.NewRecorder()
+	jrpc.DebugHandler(&manager).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/rpc", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DebugHandler() status = %v, want %v", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
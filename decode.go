@@ -0,0 +1,190 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// decodeRequest decodes the request from the raw JSON of one element, only the jsonrpc and the
+// method members are decoded, the id and the params are kept as sub-slices of raw without a
+// copy until they are used, the ID is echoed as it is on the response.
+//
+// The raw must be a valid JSON value, like the ones returned by json.Decoder. The requests that
+// the fast path doesn't handle, like the ones with members of the wrong type, are decoded with
+// json.Unmarshal so the errors are the same, the null element is an Invalid Request Error.
+func decodeRequest(raw json.RawMessage) (*Request, error) {
+	req, ok := scanRequest(raw)
+	if !ok {
+		if err := json.Unmarshal(raw, &req); err != nil {
+			return nil, err
+		}
+	}
+	// The null element is decoded as a nil request by json.Unmarshal
+	if req == nil {
+		return nil, newError(errCodeInvalidRequest, "request must be an object")
+	}
+	req.values = newValues()
+	return req, nil
+}
+
+// scanRequest scans the members of the request object, ok is false if the fast path can't
+// decode it.
+func scanRequest(data []byte) (req *Request, ok bool) {
+	i := skipSpace(data, 0)
+	if i >= len(data) || data[i] != '{' {
+		return nil, false
+	}
+	req = &Request{}
+	i = skipSpace(data, i+1)
+	if i < len(data) && data[i] == '}' {
+		return req, true
+	}
+	for i < len(data) {
+		// The member name, the ones with escapes are left to json.Unmarshal
+		if data[i] != '"' {
+			return nil, false
+		}
+		end := bytes.IndexByte(data[i+1:], '"')
+		if end < 0 {
+			return nil, false
+		}
+		name := data[i+1 : i+1+end]
+		if bytes.IndexByte(name, '\\') >= 0 {
+			return nil, false
+		}
+		i = skipSpace(data, i+end+2)
+		if i >= len(data) || data[i] != ':' {
+			return nil, false
+		}
+		i = skipSpace(data, i+1)
+		vend := skipValue(data, i)
+		if vend < 0 {
+			return nil, false
+		}
+		if !req.setMember(name, data[i:vend:vend]) {
+			return nil, false
+		}
+		i = skipSpace(data, vend)
+		if i >= len(data) {
+			return nil, false
+		}
+		if data[i] == '}' {
+			return req, true
+		}
+		if data[i] != ',' {
+			return nil, false
+		}
+		i = skipSpace(data, i+1)
+	}
+	return nil, false
+}
+
+// setMember sets the field of the request with the value of the member, the names are matched
+// like json.Unmarshal does, preferring an exact match but accepting any case.
+func (r *Request) setMember(name, value []byte) bool {
+	null := string(value) == "null"
+	switch {
+	case memberIs(name, "jsonrpc"):
+		return null || scanString(value, &r.Version)
+	case memberIs(name, "method"):
+		return null || scanString(value, &r.Method)
+	case memberIs(name, "id"):
+		r.ID = nil
+		if !null {
+			id := json.RawMessage(value)
+			r.ID = &id
+		}
+	case memberIs(name, "params"):
+		r.Params = nil
+		if !null {
+			params := json.RawMessage(value)
+			r.Params = &params
+		}
+	}
+	return true
+}
+
+// memberIs returns true if the member name matches the field name.
+func memberIs(name []byte, field string) bool {
+	return string(name) == field || strings.EqualFold(string(name), field)
+}
+
+// scanString decodes the JSON string value into s, it returns false for the other types.
+func scanString(value []byte, s *string) bool {
+	if len(value) < 2 || value[0] != '"' {
+		return false
+	}
+	// The invalid UTF-8 is replaced by json.Unmarshal
+	if bytes.IndexByte(value, '\\') < 0 && utf8.Valid(value) {
+		*s = string(value[1 : len(value)-1])
+		return true
+	}
+	return json.Unmarshal(value, s) == nil
+}
+
+// skipSpace returns the index of the first byte from i that is not JSON whitespace.
+func skipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// skipValue returns the index after the JSON value that starts at i or -1 if it's truncated.
+func skipValue(data []byte, i int) int {
+	if i >= len(data) {
+		return -1
+	}
+	switch data[i] {
+	case '"':
+		return skipString(data, i)
+	case '{', '[':
+		depth := 0
+		for i < len(data) {
+			switch data[i] {
+			case '"':
+				if i = skipString(data, i); i < 0 {
+					return -1
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return i + 1
+				}
+			}
+			i++
+		}
+		return -1
+	}
+	// Numbers and the literals end on the next delimiter
+	for i < len(data) {
+		switch data[i] {
+		case ',', '}', ']', ' ', '\t', '\n', '\r':
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+// skipString returns the index after the JSON string that starts at i or -1 if it's truncated.
+func skipString(data []byte, i int) int {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
@@ -0,0 +1,158 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// SetDeduplication will reply the retransmissions of a request received within the window with
+// the response of the first one instead of executing it again, for the clients that retry on
+// flaky transports. A retransmission has the same client identity, method, ID and params.
+//
+// The identity returns the client of the request, when it's nil the remote address of the
+// connection is used, so it must be provided for the HTTP transports, like the API key set with
+// WithContextFunc. The requests with an empty identity are not deduplicated.
+//
+// When methods is empty all the methods are deduplicated. The timeout, overload and cancel errors
+// are not kept so the retries of those requests are executed.
+//
+// Default is 0, which means the requests are not deduplicated.
+func (mb *ManagerBuilder) SetDeduplication(window time.Duration, identity func(ctx context.Context) string, methods ...string) *ManagerBuilder {
+	mb.dedupWindow = window
+	mb.dedupIdentity = identity
+	mb.dedupMethods = methods
+	return mb
+}
+
+// dedupCache keeps the responses of the requests received within the window.
+type dedupCache struct {
+	window   time.Duration
+	identity func(ctx context.Context) string
+	methods  map[string]bool
+
+	mu      sync.Mutex
+	entries map[dedupKey]*dedupEntry
+	calls   int
+}
+
+// dedupKey identifies the request of a client.
+type dedupKey struct {
+	client string
+	method string
+	id     string
+}
+
+// dedupEntry is the response of a request, done is closed once it's set.
+type dedupEntry struct {
+	params  []byte
+	expires time.Time
+	done    chan struct{}
+	res     *Response
+}
+
+// newDedupCache returns the cache or nil if the window is not positive.
+func newDedupCache(window time.Duration, identity func(ctx context.Context) string, methods []string) *dedupCache {
+	if window <= 0 {
+		return nil
+	}
+	if identity == nil {
+		identity = remoteIdentity
+	}
+	c := &dedupCache{window: window, identity: identity, entries: make(map[dedupKey]*dedupEntry)}
+	if len(methods) > 0 {
+		c.methods = make(map[string]bool, len(methods))
+		for _, name := range methods {
+			c.methods[name] = true
+		}
+	}
+	return c
+}
+
+// remoteIdentity returns the remote address of the connection of the request.
+func remoteIdentity(ctx context.Context) string {
+	if addr := RemoteAddrFromContext(ctx); addr != nil {
+		return addr.String()
+	}
+	return ""
+}
+
+// dedupe returns the response of the first request with the same key received within the window,
+// waiting for it if it's still executing, or the response of exec.
+func (m *Manager) dedupe(ctx context.Context, req *Request, exec func() *Response) *Response {
+	c := m.dedup
+	if c == nil || req.ID == nil || c.methods != nil && !c.methods[req.Method] {
+		return exec()
+	}
+	client := c.identity(ctx)
+	if client == "" {
+		return exec()
+	}
+	key := dedupKey{client: client, method: req.Method, id: string(*req.ID)}
+	var params []byte
+	if req.Params != nil {
+		params = *req.Params
+	}
+
+	now := m.clock.Now()
+	c.mu.Lock()
+	c.expire(now)
+	if e, ok := c.entries[key]; ok && now.Before(e.expires) && bytes.Equal(e.params, params) {
+		c.mu.Unlock()
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return exec()
+		}
+		if e.res == nil {
+			return exec()
+		}
+		res := *e.res
+		return &res
+	}
+	e := &dedupEntry{params: params, expires: now.Add(c.window), done: make(chan struct{})}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	res := exec()
+	if res.Error != nil && transientError(res.Error.Code) {
+		c.mu.Lock()
+		if c.entries[key] == e {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+	} else {
+		kept := *res
+		e.res = &kept
+	}
+	close(e.done)
+	return res
+}
+
+// transientError returns true for the errors that can succeed on a retry.
+func transientError(code ErrorCode) bool {
+	switch code {
+	case errCodeExecutionTimeout, errCodeServerOverloaded, errCodeExecutionCanceled, errCodeClientCanceled:
+		return true
+	}
+	return false
+}
+
+// expire will remove the entries out of the window, it runs once every 1024 calls so the cost
+// is shared by the requests.
+func (c *dedupCache) expire(now time.Time) {
+	if c.calls++; c.calls < 1024 {
+		return
+	}
+	c.calls = 0
+	for key, e := range c.entries {
+		if !now.Before(e.expires) {
+			select {
+			case <-e.done:
+				delete(c.entries, key)
+			default:
+			}
+		}
+	}
+}
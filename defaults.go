@@ -0,0 +1,90 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// SetDefaultParams will merge the defaults, encoded as a JSON object, under the params of the
+// method, so new optional params can be introduced on the server without breaking the clients
+// that omit them. The nested objects are merged too and the params sent always win.
+//
+// The positional params, a JSON array, are not changed.
+func (mb *ManagerBuilder) SetDefaultParams(name string, defaults interface{}) *ManagerBuilder {
+	if name == "" {
+		panic("jsonrpc: method name should not be empty")
+	}
+	b, err := json.Marshal(defaults)
+	if err != nil {
+		panic("jsonrpc: fail to encode the default params: " + err.Error())
+	}
+	obj, ok := decodeObject(b)
+	if !ok {
+		panic("jsonrpc: default params should be a JSON object")
+	}
+	mb.defaults[name] = obj
+	return mb
+}
+
+// defaultParams returns the default params of the method, if any.
+func (t *methodTable) defaultParams(name string) (map[string]interface{}, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	d, ok := t.defaults[name]
+	return d, ok
+}
+
+// withDefaults returns a copy of the request with the default params of the method merged under
+// its params, the request is returned as it is if there are no defaults or the params are not
+// an object.
+func (t *methodTable) withDefaults(req *Request) *Request {
+	defaults, ok := t.defaultParams(req.Method)
+	if !ok {
+		return req
+	}
+	params := map[string]interface{}{}
+	if req.Params != nil && string(bytes.TrimSpace(*req.Params)) != "null" {
+		if params, ok = decodeObject(*req.Params); !ok {
+			// Positional or invalid params are left for the method
+			return req
+		}
+	}
+	b, err := json.Marshal(mergeObjects(defaults, params))
+	if err != nil {
+		return req
+	}
+	raw := json.RawMessage(b)
+	r2 := new(Request)
+	*r2 = *req
+	r2.Params = &raw
+	return r2
+}
+
+// decodeObject decodes the JSON object with the numbers kept as they are.
+func decodeObject(b []byte) (map[string]interface{}, bool) {
+	var obj map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&obj); err != nil || obj == nil {
+		return nil, false
+	}
+	return obj, true
+}
+
+// mergeObjects returns a new object with the values of over merged on top of base.
+func mergeObjects(base, over map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(over))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range over {
+		bo, bok := merged[k].(map[string]interface{})
+		oo, ook := v.(map[string]interface{})
+		if bok && ook {
+			merged[k] = mergeObjects(bo, oo)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
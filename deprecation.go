@@ -0,0 +1,45 @@
+package jrpc2go
+
+import "context"
+
+// deprecatedMethodName is the method of the notification sent to the clients that call a
+// deprecated method.
+const deprecatedMethodName = "rpc.deprecated"
+
+// DeprecationNotice is the params of the rpc.deprecated notification sent to the clients that
+// call a deprecated method on transports that support server-initiated notifications.
+type DeprecationNotice struct {
+	Method  string `json:"method"`
+	Message string `json:"message,omitempty"`
+}
+
+// Deprecate will mark the method with the name as deprecated, the calls still succeed but the
+// clients receive an rpc.deprecated notification with the message, when the transport supports
+// them, the calls are counted on Manager.Stats and the method is flagged on the OpenAPI document.
+//
+// If the name is empty this function will panic.
+func (mb *ManagerBuilder) Deprecate(name, message string) *ManagerBuilder {
+	if name == "" {
+		panic("jsonrpc: method name should not be empty")
+	}
+	mb.deprecated[name] = message
+	return mb
+}
+
+// deprecation returns the message of the method if it's deprecated.
+func (t *methodTable) deprecation(name string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	msg, ok := t.deprecated[name]
+	return msg, ok
+}
+
+// warnDeprecated will count the call of the deprecated method and notify the client.
+func (m *Manager) warnDeprecated(ctx context.Context, req *Request, msg string) {
+	if m.stats != nil {
+		m.stats.addDeprecated(req.Method)
+	}
+	if n := NotifierFromContext(ctx); n != nil {
+		_ = n.Notify(ctx, deprecatedMethodName, DeprecationNotice{Method: req.Method, Message: msg})
+	}
+}
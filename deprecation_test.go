@@ -0,0 +1,74 @@
+package jrpc2go_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestManagerBuilder_Deprecate(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		EnableStats().
+		Add("echo", &echoMethod{}).
+		Add("echo2", &echoMethod{}).
+		Deprecate("echo", "use echo2").
+		Build()
+	srv, c := startServer(t, &m)
+	defer func() {
+		c.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}()
+
+	if _, err := io.WriteString(c, `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`+"\n"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	r := bufio.NewReader(c)
+	want := []string{
+		`{"jsonrpc":"2.0","method":"rpc.deprecated","params":{"method":"echo","message":"use echo2"}}` + "\n",
+		`{"jsonrpc":"2.0","id":1,"result":"a"}` + "\n",
+	}
+	for _, w := range want {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("ReadString() error = %v", err)
+		}
+		if line != w {
+			t.Errorf("Server() = %v, want %v", line, w)
+		}
+	}
+
+	var out strings.Builder
+	if err := m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"echo2","params":"b","id":2}`), &out); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	st := m.Stats()
+	if st.Deprecated != 1 || st.Methods["echo"].Deprecated != 1 || st.Methods["echo2"].Deprecated != 0 {
+		t.Errorf("Stats() deprecated = %v %+v, want only 1 echo call", st.Deprecated, st.Methods)
+	}
+
+	doc, err := m.OpenAPI("/rpc", "test", "1.0")
+	if err != nil {
+		t.Fatalf("OpenAPI() error = %v", err)
+	}
+	var spec struct {
+		Paths map[string]struct {
+			Post struct {
+				Deprecated bool `json:"deprecated"`
+			} `json:"post"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(doc, &spec); err != nil {
+		t.Fatalf("OpenAPI() invalid document = %v", err)
+	}
+	if !spec.Paths["/rpc/echo"].Post.Deprecated || spec.Paths["/rpc/echo2"].Post.Deprecated {
+		t.Errorf("OpenAPI() deprecated = %+v, want only echo", spec.Paths)
+	}
+}
@@ -0,0 +1,72 @@
+package jrpc2go
+
+import (
+	"encoding/json"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// FieldViolation describes a field of the params that is not valid.
+//
+// Field - The path of the field, like "address.zip" or "items[2].qty".
+//
+// Description - Why the value is not valid.
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// BadRequest is the error data of the params that failed the validation, it lists all the fields
+// not valid so the client can fix them at once.
+type BadRequest struct {
+	FieldViolations []FieldViolation `json:"fieldViolations"`
+}
+
+// DebugInfo is the error data with the details of an internal failure, it's meant for the
+// development environments since it exposes the internals of the server.
+//
+// Detail - The message of the error that caused the failure.
+//
+// StackEntries - The stack trace of the goroutine that failed, one frame per entry.
+type DebugInfo struct {
+	Detail       string   `json:"detail"`
+	StackEntries []string `json:"stackEntries,omitempty"`
+}
+
+// NewInvalidParamsError returns an Invalid Params Error with the violations as BadRequest data.
+func NewInvalidParamsError(violations ...FieldViolation) *Error {
+	if violations == nil {
+		violations = []FieldViolation{}
+	}
+	return newError(errCodeInvalidParams, &BadRequest{FieldViolations: violations})
+}
+
+// NewRetryError returns an Error with the code and a RetryInfo data asking the client to retry
+// after the delay, the QueueDepth is 0 since it's only known by the Manager.
+func NewRetryError(code ErrorCode, after time.Duration) *Error {
+	return NewError(code, &RetryInfo{RetryAfter: after.Milliseconds()})
+}
+
+// NewDebugError returns an Internal Error with a DebugInfo data of the err, with the stack trace
+// of the caller when stack is true.
+func NewDebugError(err error, stack bool) *Error {
+	info := &DebugInfo{Detail: err.Error()}
+	if stack {
+		info.StackEntries = strings.Split(strings.TrimSpace(string(debug.Stack())), "\n")
+	}
+	return newError(errCodeInternal, info)
+}
+
+// DecodeData will store the error data in the value pointed to by v, like a *BadRequest, it's
+// meant for the clients since the data of a decoded response is a generic JSON value.
+func (e *Error) DecodeData(v interface{}) error {
+	if raw, ok := e.Data.(json.RawMessage); ok {
+		return json.Unmarshal(raw, v)
+	}
+	b, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
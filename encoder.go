@@ -0,0 +1,251 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+)
+
+// encoderConfig keeps the options used to encode the responses.
+type encoderConfig struct {
+	escapeHTML bool
+	prefix     string
+	indent     string
+	newline    bool
+	sortKeys   bool
+	results    map[reflect.Type]ResultEncoderFunc
+}
+
+// ResultEncoderFunc appends the JSON encoding of the result v to dst and returns the extended
+// buffer, v is always of the type the function was registered for. The encoding must be valid
+// compact JSON and escape the strings like json.Encoder.
+type ResultEncoderFunc func(dst []byte, v interface{}) ([]byte, error)
+
+// encode will write the JSON encoding of v to the writer w using the encoder options.
+//
+// The content is fully encoded before the write so w will never receive a partial response, then
+// w is flushed if it's a ResponseWriter or an http.Flusher.
+func (ec encoderConfig) encode(w io.Writer, v interface{}) error {
+	b, ok, err := ec.appendFast(nil, v)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if ec.newline {
+			b = append(b, '\n')
+		}
+	} else {
+		if b, err = ec.marshal(v); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return &TransportError{Op: "write", Err: err}
+	}
+	if err := flush(w); err != nil {
+		return &TransportError{Op: "write", Err: err}
+	}
+	return nil
+}
+
+// marshal returns the encoding of v with json.Encoder.
+func (ec encoderConfig) marshal(v interface{}) ([]byte, error) {
+	if ec.sortKeys {
+		var err error
+		if v, err = ec.sortResults(v); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(ec.escapeHTML)
+	enc.SetIndent(ec.prefix, ec.indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	b := buf.Bytes()
+	// json.Encoder always terminates each value with a newline
+	if !ec.newline {
+		b = bytes.TrimSuffix(b, []byte{'\n'})
+	}
+	return b, nil
+}
+
+// sortResults returns a copy of the responses with the results re-encoded with sorted keys.
+func (ec encoderConfig) sortResults(v interface{}) (interface{}, error) {
+	switch r := v.(type) {
+	case *Response:
+		return ec.sortResult(r)
+	case []*Response:
+		sorted := make([]*Response, len(r))
+		for i := range r {
+			var err error
+			if sorted[i], err = ec.sortResult(r[i]); err != nil {
+				return nil, err
+			}
+		}
+		return sorted, nil
+	}
+	return v, nil
+}
+
+// sortResult returns a copy of the response with the result re-encoded with sorted keys, the
+// result is decoded to generic maps, which json.Encoder always encodes with sorted keys.
+func (ec encoderConfig) sortResult(r *Response) (*Response, error) {
+	if r.Result == nil {
+		return r, nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(ec.escapeHTML)
+	if err := enc.Encode(r.Result); err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	dec := json.NewDecoder(&buf)
+	dec.UseNumber()
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	buf.Reset()
+	if err := enc.Encode(generic); err != nil {
+		return nil, err
+	}
+
+	sorted := *r
+	sorted.Result = json.RawMessage(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}))
+	return &sorted, nil
+}
+
+// appendFast appends the encoding of the responses without reflection, ok is false if any of the
+// responses is not supported and must be encoded with json.Encoder. The output is the same as
+// json.Encoder without the newline.
+//
+// Only the responses without error, with a result of a primitive type, json.RawMessage or a
+// type with a ResultEncoderFunc are supported, and only without indentation.
+func (ec encoderConfig) appendFast(dst []byte, v interface{}) (_ []byte, ok bool, err error) {
+	if ec.prefix != "" || ec.indent != "" {
+		return dst, false, nil
+	}
+	switch r := v.(type) {
+	case *Response:
+		return ec.appendResponse(dst, r)
+	case []*Response:
+		dst = append(dst, '[')
+		for i, res := range r {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			if dst, ok, err = ec.appendResponse(dst, res); !ok || err != nil {
+				return dst, ok, err
+			}
+		}
+		return append(dst, ']'), true, nil
+	}
+	return dst, false, nil
+}
+
+// appendResponse appends the encoding of the response, see appendFast.
+func (ec encoderConfig) appendResponse(dst []byte, r *Response) (_ []byte, ok bool, err error) {
+	if r == nil || r.Error != nil || r.Result == nil {
+		return dst, false, nil
+	}
+	dst = append(dst, `{"jsonrpc":`...)
+	if dst, ok = ec.appendString(dst, r.Version); !ok {
+		return dst, false, nil
+	}
+	dst = append(dst, `,"id":`...)
+	if r.ID == nil {
+		dst = append(dst, "null"...)
+	} else if dst, ok = ec.appendRaw(dst, *r.ID); !ok {
+		return dst, false, nil
+	}
+	dst = append(dst, `,"result":`...)
+	if dst, ok, err = ec.appendResult(dst, r.Result); !ok || err != nil {
+		return dst, ok, err
+	}
+	return append(dst, '}'), true, nil
+}
+
+// appendResult appends the encoding of the result, see appendFast.
+func (ec encoderConfig) appendResult(dst []byte, v interface{}) (_ []byte, ok bool, err error) {
+	switch r := v.(type) {
+	case string:
+		dst, ok = ec.appendString(dst, r)
+		return dst, ok, nil
+	case bool:
+		return strconv.AppendBool(dst, r), true, nil
+	case int:
+		return strconv.AppendInt(dst, int64(r), 10), true, nil
+	case int32:
+		return strconv.AppendInt(dst, int64(r), 10), true, nil
+	case int64:
+		return strconv.AppendInt(dst, r, 10), true, nil
+	case uint:
+		return strconv.AppendUint(dst, uint64(r), 10), true, nil
+	case uint32:
+		return strconv.AppendUint(dst, uint64(r), 10), true, nil
+	case uint64:
+		return strconv.AppendUint(dst, r, 10), true, nil
+	case json.RawMessage:
+		// The keys of the raw results are sorted by sortResults
+		if ec.sortKeys {
+			return dst, false, nil
+		}
+		// A nil json.RawMessage is encoded as null
+		if r == nil {
+			return append(dst, "null"...), true, nil
+		}
+		dst, ok = ec.appendRaw(dst, r)
+		return dst, ok, nil
+	}
+	// The registered encoders don't sort the keys of the results
+	if fn, found := ec.results[reflect.TypeOf(v)]; found && !ec.sortKeys {
+		dst, err = fn(dst, v)
+		return dst, err == nil, err
+	}
+	return dst, false, nil
+}
+
+// appendString appends the quoted string, ok is false if it has a char that json.Encoder would
+// escape, like the quotes or the HTML chars when escapeHTML is set.
+func (ec encoderConfig) appendString(dst []byte, s string) ([]byte, bool) {
+	for i := 0; i < len(s); i++ {
+		if !ec.safeByte(s[i]) {
+			return dst, false
+		}
+	}
+	dst = append(dst, '"')
+	dst = append(dst, s...)
+	return append(dst, '"'), true
+}
+
+// appendRaw appends the raw JSON, ok is false if it's not valid or json.Encoder would change it,
+// like the whitespace that is compacted or the HTML chars when escapeHTML is set.
+func (ec encoderConfig) appendRaw(dst []byte, raw []byte) ([]byte, bool) {
+	for _, c := range raw {
+		if c != '"' && c != '\\' && !ec.safeByte(c) || c == ' ' {
+			return dst, false
+		}
+	}
+	if !json.Valid(raw) {
+		return dst, false
+	}
+	return append(dst, raw...), true
+}
+
+// safeByte returns true if json.Encoder writes the byte of a string as it is.
+func (ec encoderConfig) safeByte(c byte) bool {
+	if c < 0x20 || c >= utf8.RuneSelf || c == '"' || c == '\\' {
+		return false
+	}
+	return !ec.escapeHTML || c != '<' && c != '>' && c != '&'
+}
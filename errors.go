@@ -1,6 +1,60 @@
 package jrpc2go
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNilReader is returned by Handle when the reader is nil.
+var ErrNilReader = errors.New("jsonrpc: reader can't be nil")
+
+// ErrNilWriter is returned by Handle when the writer is nil.
+var ErrNilWriter = errors.New("jsonrpc: writer can't be nil")
+
+// ErrEmptyBatch is matched with errors.Is by the Invalid Request Error returned by Handle when
+// the batch doesn't have any request.
+var ErrEmptyBatch = errors.New("jsonrpc: empty batch")
+
+// TransportError is returned by Handle when reading the requests or writing the responses fails,
+// it can't be replied to the client since the transport itself failed.
+//
+// Op - The operation that failed, read or write.
+type TransportError struct {
+	Op  string
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("jsonrpc: %s: %v", e.Op, e.Err)
+}
+
+// Unwrap returns the error of the transport.
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// BatchWriteError is returned by Handle when writing the responses of a batch fails, it lists
+// the IDs of the responses received by the client and of the lost ones so the transport can retry
+// only the lost requests. It wraps the TransportError of the write.
+//
+// Delivered - The IDs of the responses fully written before the failure.
+//
+// Undelivered - The IDs of the responses not written or partially written.
+type BatchWriteError struct {
+	Delivered   []*json.RawMessage
+	Undelivered []*json.RawMessage
+	Err         error
+}
+
+func (e *BatchWriteError) Error() string {
+	return fmt.Sprintf("jsonrpc: batch write: %d delivered, %d undelivered: %v", len(e.Delivered), len(e.Undelivered), e.Err)
+}
+
+// Unwrap returns the TransportError of the write.
+func (e *BatchWriteError) Unwrap() error {
+	return e.Err
+}
 
 // Error represents a JSON-RPC error, the Response MUST contain the error member if the RPC call encounters an error.
 //
@@ -13,12 +67,18 @@ type Error struct {
 	Code    ErrorCode   `json:"code"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
+	cause   error
 }
 
 func (e *Error) Error() string {
 	return fmt.Sprintf("jsonrpc: { code: %d, message: %s, data: %+v }", e.Code, e.Message, e.Data)
 }
 
+// Unwrap returns the sentinel error matched by the Error, like ErrEmptyBatch, if any.
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
 // ErrorCode represents the API error number.
 type ErrorCode int
 
@@ -43,6 +103,54 @@ const errCodeInvalidRPCVersion ErrorCode = -32001
 // ErrCodeExecutionTimeout means the
 const errCodeExecutionTimeout ErrorCode = -32002
 
+// BatchTimeoutData is the error data of the requests in a batch that were not attempted because
+// the batch deadline expired before their execution started.
+//
+// NotAttempted - The IDs of all the requests in the batch that were not attempted, when the
+// batch is streamed the ones up to the request of the error.
+type BatchTimeoutData struct {
+	NotAttempted []*json.RawMessage `json:"notAttempted"`
+	*RetryInfo
+}
+
+// TimeoutData is the error data of the method execution timeout errors.
+//
+// Method - The name of the method that timed out.
+//
+// ID - The request identifier, it's null for notifications.
+//
+// Timeout - The timeout configured for the method in milliseconds, it's omitted when the
+// execution was stopped by the deadline of the batch or of the caller context instead.
+//
+// Elapsed - The time in milliseconds since the execution started until it timed out.
+type TimeoutData struct {
+	Method  string           `json:"method"`
+	ID      *json.RawMessage `json:"id"`
+	Timeout int64            `json:"timeout,omitempty"`
+	Elapsed int64            `json:"elapsed"`
+	*RetryInfo
+}
+
+// RetryInfo is the error data of the timeout and overload errors when the server is configured
+// to send retry hints, so clients can implement backoff based on the server signals.
+//
+// RetryAfter - The suggested delay in milliseconds before retrying the request.
+//
+// QueueDepth - The number of methods executing on the server when the error occurred.
+type RetryInfo struct {
+	RetryAfter int64 `json:"retryAfter"`
+	QueueDepth int64 `json:"queueDepth"`
+}
+
+// ErrCodeServerOverloaded means the server reached the limit of methods executing at the same time.
+const errCodeServerOverloaded ErrorCode = -32003
+
+// ErrCodeExecutionCanceled means the method execution was canceled before it finished.
+const errCodeExecutionCanceled ErrorCode = -32004
+
+// ErrCodeClientCanceled means the caller canceled the request, like an HTTP client that disconnected.
+const errCodeClientCanceled ErrorCode = -32005
+
 // newError it's for internal use, it's used the messsages and codes from JSON RPC spec.
 func newError(code ErrorCode, data interface{}) *Error {
 	e := &Error{
@@ -64,6 +172,18 @@ func newError(code ErrorCode, data interface{}) *Error {
 		e.Message = "JSON RPC Version must be 2.0"
 	case errCodeExecutionTimeout:
 		e.Message = "Method execution timeout"
+	case errCodeServerOverloaded:
+		e.Message = "Server overloaded"
+	case errCodeExecutionCanceled:
+		e.Message = "Method execution canceled"
+	case errCodeClientCanceled:
+		e.Message = "Request canceled by the client"
+	case errCodeResponseTooLarge:
+		e.Message = "Response too large"
+	case errCodeMethodDisabled:
+		e.Message = "Method disabled"
+	case errCodeQuotaExceeded:
+		e.Message = "Quota exceeded"
 	}
 	return e
 }
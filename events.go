@@ -0,0 +1,110 @@
+package jrpc2go
+
+import (
+	"context"
+	"sync"
+)
+
+// Event is produced by the business code and sent to the clients subscribed to its Topic as a
+// notification where the method is the Topic.
+type Event struct {
+	Topic  string
+	Params interface{}
+}
+
+// EventBus is an external source of events, like a message broker consumer, the channel is closed
+// when there are no more events.
+type EventBus interface {
+	Events() <-chan Event
+}
+
+// ChanEventBus is an EventBus over a channel, the business code sends the events to it.
+type ChanEventBus chan Event
+
+// Events returns the channel.
+func (b ChanEventBus) Events() <-chan Event {
+	return b
+}
+
+// subscription is a Notifier subscribed to a topic.
+type subscription struct {
+	topic    string
+	notifier Notifier
+}
+
+// Broker delivers the events to the Notifiers subscribed to their topics, it decouples the code
+// producing the events from the connections receiving them.
+type Broker struct {
+	mu   sync.RWMutex
+	next uint64
+	subs map[uint64]subscription
+}
+
+// NewBroker returns a Broker without subscriptions.
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[uint64]subscription)}
+}
+
+// Subscribe will deliver the events of the topic to the Notifier, like the one from
+// NotifierFromContext, until the returned function is called or the Notifier returns
+// ErrSessionClosed.
+func (b *Broker) Subscribe(topic string, n Notifier) (unsubscribe func()) {
+	if n == nil {
+		panic("jsonrpc: notifier should not be nil")
+	}
+	b.mu.Lock()
+	id := b.next
+	b.next++
+	b.subs[id] = subscription{topic: topic, notifier: n}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.remove(id) })
+	}
+}
+
+// Publish will notify the event to the subscribers of its topic. The delivery errors are ignored
+// so a slow or gone client doesn't affect the others.
+func (b *Broker) Publish(ctx context.Context, e Event) {
+	b.mu.RLock()
+	var ids []uint64
+	var targets []Notifier
+	for id, s := range b.subs {
+		if s.topic == e.Topic {
+			ids = append(ids, id)
+			targets = append(targets, s.notifier)
+		}
+	}
+	b.mu.RUnlock()
+
+	for i, n := range targets {
+		if err := n.Notify(ctx, e.Topic, e.Params); err == ErrSessionClosed {
+			b.remove(ids[i])
+		}
+	}
+}
+
+// Run will publish the events of the bus until its channel is closed or the ctx is done, it
+// returns the ctx error on the last case.
+func (b *Broker) Run(ctx context.Context, bus EventBus) error {
+	events := bus.Events()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			b.Publish(ctx, e)
+		}
+	}
+}
+
+// remove deletes the subscription with the id.
+func (b *Broker) remove(id uint64) {
+	b.mu.Lock()
+	delete(b.subs, id)
+	b.mu.Unlock()
+}
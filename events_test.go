@@ -0,0 +1,46 @@
+package jrpc2go_test
+
+import (
+	"context"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestBroker_Run(t *testing.T) {
+	b := jrpc.NewBroker()
+	prices := &flakyNotifier{}
+	news := &flakyNotifier{}
+	unsubscribe := b.Subscribe("prices", prices)
+	b.Subscribe("news", news)
+
+	bus := make(jrpc.ChanEventBus, 3)
+	bus <- jrpc.Event{Topic: "prices", Params: 1}
+	bus <- jrpc.Event{Topic: "news", Params: "a"}
+	bus <- jrpc.Event{Topic: "other", Params: nil}
+	close(bus)
+	if err := b.Run(context.Background(), bus); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(prices.got) != 1 || prices.got[0] != "prices1" {
+		t.Errorf("got %v, want [prices1]", prices.got)
+	}
+	if len(news.got) != 1 || news.got[0] != `news"a"` {
+		t.Errorf(`got %v, want [news"a"]`, news.got)
+	}
+
+	unsubscribe()
+	b.Publish(context.Background(), jrpc.Event{Topic: "prices", Params: 2})
+	if len(prices.got) != 1 {
+		t.Errorf("got %v after unsubscribe, want [prices1]", prices.got)
+	}
+}
+
+func TestBroker_Run_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := jrpc.NewBroker().Run(ctx, make(jrpc.ChanEventBus)); err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,51 @@
+package jrpc2go
+
+import "context"
+
+// ErrCodeMethodDisabled means the method is turned off by the FlagProvider.
+const errCodeMethodDisabled ErrorCode = -32007
+
+// FlagProvider tells if a method is enabled, it's consulted before each execution so the methods
+// can be turned off at runtime by an external flag system without a deploy. It must be fast since
+// it's called on every request, like reading the flags kept updated by the flag system SDK.
+type FlagProvider interface {
+	MethodEnabled(ctx context.Context, method string) bool
+}
+
+// FlagProviderFunc is a function that implements the FlagProvider interface.
+type FlagProviderFunc func(ctx context.Context, method string) bool
+
+// MethodEnabled calls f(ctx, method).
+func (f FlagProviderFunc) MethodEnabled(ctx context.Context, method string) bool {
+	return f(ctx, method)
+}
+
+// SetFlagProvider allows to turn off the methods at runtime, the requests of a disabled method
+// are replied with an error with the code and the method as data. When code is 0 the error is
+// Method Disabled (-32007), otherwise it's NewError(code, method).
+//
+// Default is nil, which means all the methods are enabled.
+func (mb *ManagerBuilder) SetFlagProvider(p FlagProvider, code ErrorCode) *ManagerBuilder {
+	mb.flags = p
+	mb.disabledCode = code
+	return mb
+}
+
+// WithFlagProvider overrides the FlagProvider and the error code of the disabled methods.
+func WithFlagProvider(p FlagProvider, code ErrorCode) Option {
+	return func(m *Manager) {
+		m.flags = p
+		m.disabledCode = code
+	}
+}
+
+// disabled returns the error of the method if it's turned off, nil otherwise.
+func (m *Manager) disabled(ctx context.Context, method string) *Error {
+	if m.flags == nil || m.flags.MethodEnabled(ctx, method) {
+		return nil
+	}
+	if m.disabledCode == 0 {
+		return newError(errCodeMethodDisabled, method)
+	}
+	return NewError(m.disabledCode, method)
+}
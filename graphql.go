@@ -0,0 +1,624 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxGraphQLDepth is the maximum nesting of the selection sets, values and types of a GraphQL
+// document, so a deeply nested document can't exhaust the stack of the parser.
+const maxGraphQLDepth = 64
+
+// graphQLRequest is the body of a GraphQL request over HTTP.
+type graphQLRequest struct {
+	Query     string                     `json:"query"`
+	Variables map[string]json.RawMessage `json:"variables"`
+}
+
+// graphQLError is an error of a GraphQL response.
+type graphQLError struct {
+	Message    string        `json:"message"`
+	Path       []string      `json:"path,omitempty"`
+	Extensions *graphQLCause `json:"extensions,omitempty"`
+}
+
+// graphQLCause is the JSON RPC error that caused a GraphQL error.
+type graphQLCause struct {
+	Code ErrorCode   `json:"code"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// graphQLHandler exposes the methods of a Manager as GraphQL fields.
+type graphQLHandler struct {
+	m       *Manager
+	queries map[string]bool
+}
+
+// GraphQLHandler returns an EXPERIMENTAL adapter that exposes the methods as the fields of a
+// GraphQL endpoint, the field arguments are the method params and the result is filtered by the
+// field selection. The methods listed in queries are query fields and all the others are mutation
+// fields.
+//
+// Method names are mapped to GraphQL names replacing the invalid characters with `_`, so the
+// method `files/read` is the field `files_read`.
+//
+// Only a subset of GraphQL is supported: one operation per document with variables, aliases,
+// arguments and nested selections up to 64 levels. Fragments, directives and introspection are
+// not supported. The request body is limited to 10 MiB.
+func GraphQLHandler(m *Manager, queries ...string) http.Handler {
+	h := &graphQLHandler{m: m, queries: make(map[string]bool, len(queries))}
+	for _, q := range queries {
+		h.queries[q] = true
+	}
+	return h
+}
+
+// ServeHTTP will execute the GraphQL operation of the request body.
+func (h *graphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !acceptContentType(r.Header.Get(contentTypeKey), []string{contentTypeValue}) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	lb := &limitedBody{r: http.MaxBytesReader(w, r.Body, defaultMaxBodySize), n: defaultMaxBodySize}
+	defer lb.Close()
+	body, err := ioutil.ReadAll(lb)
+	if err != nil {
+		if lb.exceeded {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var out bytes.Buffer
+	var gr graphQLRequest
+	if err := json.Unmarshal(body, &gr); err != nil {
+		err = h.m.encoder.encode(&out, map[string]interface{}{
+			"errors": []graphQLError{{Message: err.Error()}},
+		})
+	} else {
+		err = h.m.encoder.encode(&out, h.execute(r, &gr))
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(contentTypeKey, contentTypeValue)
+	if _, err := w.Write(out.Bytes()); err != nil {
+		//TODO not sure what to do here
+	}
+}
+
+// execute returns the GraphQL response of the operation.
+func (h *graphQLHandler) execute(r *http.Request, gr *graphQLRequest) map[string]interface{} {
+	op, err := parseGraphQL(gr.Query)
+	if err != nil {
+		return map[string]interface{}{"errors": []graphQLError{{Message: err.Error()}}}
+	}
+
+	names, conflicts := h.fieldNames(op.kind == "query")
+	data := make(map[string]interface{}, len(op.fields))
+	var errs []graphQLError
+
+	for _, f := range op.fields {
+		key := f.alias
+		if key == "" {
+			key = f.name
+		}
+		if f.name == "__typename" {
+			data[key] = strings.ToUpper(op.kind[:1]) + op.kind[1:]
+			continue
+		}
+		if methods, ok := conflicts[f.name]; ok {
+			data[key] = nil
+			errs = append(errs, graphQLError{
+				Message: fmt.Sprintf("field %q is ambiguous, it's the name of the methods %s", f.name, strings.Join(methods, ", ")),
+				Path:    []string{key},
+			})
+			continue
+		}
+
+		method, ok := names[f.name]
+		if !ok {
+			data[key] = nil
+			errs = append(errs, graphQLError{Message: fmt.Sprintf("field %q not found on %s", f.name, op.kind), Path: []string{key}})
+			continue
+		}
+
+		req := &Request{Version: version, Method: method, values: newValues()}
+		if len(f.args) > 0 {
+			params, err := json.Marshal(resolveGraphQLValue(f.args, gr.Variables))
+			if err != nil {
+				data[key] = nil
+				errs = append(errs, graphQLError{Message: err.Error(), Path: []string{key}})
+				continue
+			}
+			raw := json.RawMessage(params)
+			req.Params = &raw
+		}
+
+		resp := h.m.execMethod(withTransportInfo(r.Context(), httpTransportInfo(r, "graphql")), req)
+		if resp.Error != nil {
+			data[key] = nil
+			errs = append(errs, graphQLError{
+				Message:    resp.Error.Message,
+				Path:       []string{key},
+				Extensions: &graphQLCause{Code: resp.Error.Code, Data: resp.Error.Data},
+			})
+			continue
+		}
+
+		v, err := selectGraphQLFields(resp.Result, f.selection)
+		if err != nil {
+			data[key] = nil
+			errs = append(errs, graphQLError{Message: err.Error(), Path: []string{key}})
+			continue
+		}
+		data[key] = v
+	}
+
+	res := map[string]interface{}{"data": data}
+	if len(errs) > 0 {
+		res["errors"] = errs
+	}
+	return res
+}
+
+// fieldNames returns the map from GraphQL field name to method name of the query or mutation
+// fields, the field names of more than one method are returned on conflicts with the sorted names
+// of their methods instead.
+func (h *graphQLHandler) fieldNames(query bool) (names map[string]string, conflicts map[string][]string) {
+	h.m.table.mu.RLock()
+	defer h.m.table.mu.RUnlock()
+	names = make(map[string]string, len(h.m.table.methods))
+	for name := range h.m.table.methods {
+		if h.queries[name] != query {
+			continue
+		}
+		field := graphQLName(name)
+		if methods, ok := conflicts[field]; ok {
+			conflicts[field] = append(methods, name)
+			continue
+		}
+		if other, ok := names[field]; ok {
+			if conflicts == nil {
+				conflicts = make(map[string][]string)
+			}
+			conflicts[field] = []string{other, name}
+			delete(names, field)
+			continue
+		}
+		names[field] = name
+	}
+	for _, methods := range conflicts {
+		sort.Strings(methods)
+	}
+	return names, conflicts
+}
+
+// graphQLName returns the method name with the characters invalid on GraphQL names replaced by `_`.
+func graphQLName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !isGraphQLNameChar(c) || (i == 0 && c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// isGraphQLNameChar reports if c is valid on a GraphQL name.
+func isGraphQLNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// resolveGraphQLValue returns the value with the variables replaced by their values.
+func resolveGraphQLValue(v interface{}, vars map[string]json.RawMessage) interface{} {
+	switch t := v.(type) {
+	case graphQLVariable:
+		if raw, ok := vars[string(t)]; ok {
+			return raw
+		}
+		return nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = resolveGraphQLValue(e, vars)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, e := range t {
+			l[i] = resolveGraphQLValue(e, vars)
+		}
+		return l
+	}
+	return v
+}
+
+// selectGraphQLFields returns the result with only the fields of the selection, results without
+// a selection are returned as they are.
+func selectGraphQLFields(result interface{}, selection []*graphQLField) (interface{}, error) {
+	if len(selection) == 0 || result == nil {
+		return result, nil
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return applyGraphQLSelection(v, selection), nil
+}
+
+// applyGraphQLSelection keeps only the selected fields of the objects in v.
+func applyGraphQLSelection(v interface{}, selection []*graphQLField) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(selection))
+		for _, f := range selection {
+			key := f.alias
+			if key == "" {
+				key = f.name
+			}
+			m[key] = applyGraphQLSelection(t[f.name], f.selection)
+		}
+		return m
+	case []interface{}:
+		for i := range t {
+			t[i] = applyGraphQLSelection(t[i], selection)
+		}
+	}
+	return v
+}
+
+// graphQLOperation is a parsed GraphQL operation.
+type graphQLOperation struct {
+	kind   string
+	fields []*graphQLField
+}
+
+// graphQLField is a parsed GraphQL field selection.
+type graphQLField struct {
+	alias     string
+	name      string
+	args      map[string]interface{}
+	selection []*graphQLField
+}
+
+// graphQLVariable is a reference to a variable on a GraphQL value.
+type graphQLVariable string
+
+// graphQLParser is a recursive descent parser of the supported GraphQL subset.
+type graphQLParser struct {
+	src   string
+	pos   int
+	depth int
+}
+
+// parseGraphQL returns the operation of the GraphQL document.
+func parseGraphQL(src string) (*graphQLOperation, error) {
+	p := &graphQLParser{src: src}
+	op := &graphQLOperation{kind: "query"}
+
+	if p.peek() != '{' {
+		kind := p.name()
+		if kind != "query" && kind != "mutation" {
+			return nil, p.errorf("expected query or mutation, got %q", kind)
+		}
+		op.kind = kind
+		if isGraphQLNameChar(p.peek()) {
+			p.name()
+		}
+		if p.peek() == '(' {
+			if err := p.variableDefinitions(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	fields, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.fields = fields
+
+	if p.peek() != 0 {
+		return nil, p.errorf("only one operation per document is supported")
+	}
+	return op, nil
+}
+
+// errorf returns a syntax error at the current position.
+func (p *graphQLParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("graphql: syntax error at %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// enter will start a nested selection set, value or type, it returns an error if the nesting is
+// over maxGraphQLDepth. It must be paired with leave.
+func (p *graphQLParser) enter() error {
+	if p.depth++; p.depth > maxGraphQLDepth {
+		return p.errorf("document nested deeper than %d levels", maxGraphQLDepth)
+	}
+	return nil
+}
+
+// leave will end a nested selection set, value or type.
+func (p *graphQLParser) leave() {
+	p.depth--
+}
+
+// peek skips the ignored tokens and returns the next char or 0 at the end.
+func (p *graphQLParser) peek() byte {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return c
+		}
+	}
+	return 0
+}
+
+// expect consumes the char c or returns an error.
+func (p *graphQLParser) expect(c byte) error {
+	if p.peek() != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+// name consumes and returns a name, it's empty if there is no name.
+func (p *graphQLParser) name() string {
+	p.peek()
+	start := p.pos
+	for p.pos < len(p.src) && isGraphQLNameChar(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// variableDefinitions consumes the variable definitions, the types are not validated.
+func (p *graphQLParser) variableDefinitions() error {
+	if err := p.expect('('); err != nil {
+		return err
+	}
+	for p.peek() != ')' {
+		if err := p.expect('$'); err != nil {
+			return err
+		}
+		if p.name() == "" {
+			return p.errorf("expected variable name")
+		}
+		if err := p.expect(':'); err != nil {
+			return err
+		}
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+		if p.peek() == '=' {
+			// Default values are not supported, the variables must be sent
+			return p.errorf("variable default values are not supported")
+		}
+	}
+	p.pos++
+	return nil
+}
+
+// typeRef consumes a type reference like `[Int!]!`.
+func (p *graphQLParser) typeRef() error {
+	if err := p.enter(); err != nil {
+		return err
+	}
+	defer p.leave()
+	if p.peek() == '[' {
+		p.pos++
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+		if err := p.expect(']'); err != nil {
+			return err
+		}
+	} else if p.name() == "" {
+		return p.errorf("expected type")
+	}
+	if p.peek() == '!' {
+		p.pos++
+	}
+	return nil
+}
+
+// selectionSet consumes a selection set like `{ a b { c } }`.
+func (p *graphQLParser) selectionSet() ([]*graphQLField, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	var fields []*graphQLField
+	for p.peek() != '}' {
+		switch p.peek() {
+		case 0:
+			return nil, p.errorf("unexpected end of document")
+		case '.':
+			return nil, p.errorf("fragments are not supported")
+		case '@':
+			return nil, p.errorf("directives are not supported")
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	p.pos++
+	if len(fields) == 0 {
+		return nil, p.errorf("selection set should not be empty")
+	}
+	return fields, nil
+}
+
+// field consumes a field like `alias: name(arg: 1) { a }`.
+func (p *graphQLParser) field() (*graphQLField, error) {
+	f := &graphQLField{name: p.name()}
+	if f.name == "" {
+		return nil, p.errorf("expected field name")
+	}
+	if p.peek() == ':' {
+		p.pos++
+		f.alias, f.name = f.name, p.name()
+		if f.name == "" {
+			return nil, p.errorf("expected field name")
+		}
+	}
+	if p.peek() == '(' {
+		p.pos++
+		f.args = make(map[string]interface{})
+		for p.peek() != ')' {
+			name := p.name()
+			if name == "" {
+				return nil, p.errorf("expected argument name")
+			}
+			if err := p.expect(':'); err != nil {
+				return nil, err
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			f.args[name] = v
+		}
+		p.pos++
+	}
+	if p.peek() == '{' {
+		sel, err := p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+		f.selection = sel
+	}
+	return f, nil
+}
+
+// value consumes a value, enum values are returned as strings.
+func (p *graphQLParser) value() (interface{}, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	switch c := p.peek(); {
+	case c == '$':
+		p.pos++
+		name := p.name()
+		if name == "" {
+			return nil, p.errorf("expected variable name")
+		}
+		return graphQLVariable(name), nil
+	case c == '"':
+		return p.stringValue()
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		n := json.Number(p.src[start:p.pos])
+		if _, err := strconv.ParseFloat(string(n), 64); err != nil {
+			return nil, p.errorf("invalid number %q", n)
+		}
+		return n, nil
+	case c == '[':
+		p.pos++
+		l := []interface{}{}
+		for p.peek() != ']' {
+			if p.peek() == 0 {
+				return nil, p.errorf("unexpected end of document")
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, v)
+		}
+		p.pos++
+		return l, nil
+	case c == '{':
+		p.pos++
+		m := map[string]interface{}{}
+		for p.peek() != '}' {
+			name := p.name()
+			if name == "" {
+				return nil, p.errorf("expected object field name")
+			}
+			if err := p.expect(':'); err != nil {
+				return nil, err
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			m[name] = v
+		}
+		p.pos++
+		return m, nil
+	}
+
+	switch name := p.name(); name {
+	case "":
+		return nil, p.errorf("expected value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	default:
+		return name, nil
+	}
+}
+
+// stringValue consumes a quoted string, block strings are not supported.
+func (p *graphQLParser) stringValue() (interface{}, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.src) && p.src[p.pos] != '"' {
+		if p.src[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unterminated string")
+	}
+	p.pos++
+	// GraphQL string escapes are a subset of the JSON ones
+	var s string
+	if err := json.Unmarshal([]byte(p.src[start:p.pos]), &s); err != nil {
+		return nil, p.errorf("invalid string: %v", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,158 @@
+package jrpc2go_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+type userMethod struct{}
+
+func (m *userMethod) Execute(req *jrpc.Request, resp *jrpc.Response) {
+	resp.Result = map[string]interface{}{
+		"name":  "ana",
+		"email": "ana@example.com",
+		"roles": []map[string]string{{"id": "1", "name": "admin"}},
+	}
+}
+
+func TestGraphQLHandler(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Add("users/get", &userMethod{}).
+		Build()
+	h := jrpc.GraphQLHandler(&m, "users/get")
+
+	tests := []struct {
+		name  string
+		body  string
+		wantW string
+	}{
+		{
+			name:  "Query With Selection",
+			body:  `{"query":"{ users_get { name roles { id } } }"}`,
+			wantW: `{"data":{"users_get":{"name":"ana","roles":[{"id":"1"}]}}}`,
+		},
+		{
+			name:  "Mutation With Variables And Alias",
+			body:  `{"query":"mutation Sum($a: Int!) { total: add(v1: $a, v2: 2) }","variables":{"a":40}}`,
+			wantW: `{"data":{"total":42}}`,
+		},
+		{
+			name:  "Method Error",
+			body:  `{"query":"mutation { add(v1: 0, v2: 1) }"}`,
+			wantW: `{"data":{"add":null},"errors":[{"message":"Fake error for test","path":["add"],"extensions":{"code":1}}]}`,
+		},
+		{
+			name:  "Field Not Found",
+			body:  `{"query":"{ add(v1: 1, v2: 2) }"}`,
+			wantW: `{"data":{"add":null},"errors":[{"message":"field \"add\" not found on query","path":["add"]}]}`,
+		},
+		{
+			name:  "Fragments Not Supported",
+			body:  `{"query":"{ users_get { ...F } }"}`,
+			wantW: `{"errors":[{"message":"graphql: syntax error at 14: fragments are not supported"}]}`,
+		},
+		{
+			name:  "Typename",
+			body:  `{"query":"mutation { __typename }"}`,
+			wantW: `{"data":{"__typename":"Mutation"}}`,
+		},
+		{
+			name:  "Selection Too Deep",
+			body:  `{"query":"` + strings.Repeat("{ a ", 65) + `"}`,
+			wantW: `{"errors":[{"message":"graphql: syntax error at 256: document nested deeper than 64 levels"}]}`,
+		},
+		{
+			name:  "Value Too Deep",
+			body:  `{"query":"mutation { add(v1: ` + strings.Repeat("[", 100000) + `) }"}`,
+			wantW: `{"errors":[{"message":"graphql: syntax error at 82: document nested deeper than 64 levels"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := strings.TrimSpace(w.Body.String()); got != tt.wantW {
+				t.Errorf("GraphQLHandler() body = %v, want %v", got, tt.wantW)
+			}
+		})
+	}
+}
+
+func TestGraphQLHandler_NameConflict(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("users/get", &userMethod{}).
+		Add("users.get", &userMethod{}).
+		Add("add", &addMethod{}).
+		Build()
+	h := jrpc.GraphQLHandler(&m, "users/get", "users.get", "add")
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ users_get { name } add(v1: 1, v2: 2) }"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	want := `{"data":{"add":3,"users_get":null},"errors":[{"message":"field \"users_get\" is ambiguous, it's the name of the methods users.get, users/get","path":["users_get"]}]}`
+	if got := strings.TrimSpace(w.Body.String()); got != want {
+		t.Errorf("GraphQLHandler() body = %v, want %v", got, want)
+	}
+}
+
+func TestGraphQLHandler_Request(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Build()
+	h := jrpc.GraphQLHandler(&m, "add")
+	query := `{"query":"{ add(v1: 1, v2: 2) }"}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{
+			name:        "Content Type With Charset",
+			contentType: "application/json; charset=UTF-8",
+			body:        query,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "Content Type With Other Charset",
+			contentType: "application/json; charset=latin1",
+			body:        query,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "Content Type With Suffix",
+			contentType: "application/jsonp",
+			body:        query,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "Body Too Large",
+			contentType: "application/json",
+			body:        `{"query":"{ add }","variables":{"v":"` + strings.Repeat("a", 10<<20) + `"}}`,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("GraphQLHandler() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
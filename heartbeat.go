@@ -0,0 +1,87 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// pingMethodName is the name of the built-in method that checks the server is alive.
+const pingMethodName = "rpc.ping"
+
+// pingMethod replies to rpc.ping with "pong".
+type pingMethod struct{}
+
+// Execute will reply with "pong".
+func (pingMethod) Execute(req *Request, resp *Response) {
+	resp.Result = "pong"
+}
+
+// HeartbeatStats are the counters and the round-trip latency summary of the pings sent by the
+// Server to its connections.
+type HeartbeatStats struct {
+	Pings    int64          `json:"pings"`
+	Timeouts int64          `json:"timeouts"`
+	Latency  LatencySummary `json:"latency"`
+}
+
+// addPing will count a ping answered after the round-trip rtt.
+func (s *statsCollector) addPing(rtt time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.heartbeat.Pings++
+	d := float64(rtt) / float64(time.Millisecond)
+	s.heartbeat.Latency.Total += d
+	s.heartbeat.Latency.Mean = s.heartbeat.Latency.Total / float64(s.heartbeat.Pings)
+	if d > s.heartbeat.Latency.Max {
+		s.heartbeat.Latency.Max = d
+	}
+}
+
+// addPingTimeout will count a ping that was not answered.
+func (s *statsCollector) addPingTimeout() {
+	s.mu.Lock()
+	s.heartbeat.Timeouts++
+	s.mu.Unlock()
+}
+
+// heartbeat keeps the ping sent to a connection and not answered yet.
+type heartbeat struct {
+	mu   sync.Mutex
+	seq  uint64
+	id   string
+	sent time.Time
+}
+
+// next returns the ID of a new ping and false if the previous one was not answered.
+func (h *heartbeat) next(now time.Time) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.id != "" {
+		return "", false
+	}
+	h.seq++
+	h.id = "ping-" + strconv.FormatUint(h.seq, 10)
+	h.sent = now
+	return h.id, true
+}
+
+// answer returns the round-trip of the ping when raw is the response to it.
+func (h *heartbeat) answer(raw json.RawMessage, now time.Time) (time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.id == "" || !bytes.Contains(raw, []byte(h.id)) {
+		return 0, false
+	}
+	var res struct {
+		Method *string `json:"method"`
+		ID     string  `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil || res.Method != nil || res.ID != h.id {
+		return 0, false
+	}
+	h.id = ""
+	return now.Sub(h.sent), true
+}
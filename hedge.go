@@ -0,0 +1,152 @@
+package jrpc2go
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HedgePolicy configures the Hedge middleware.
+//
+// Methods - The names of the idempotent methods hedged, like the ones proxied to an upstream,
+// the other methods are executed once as usual.
+//
+// Percentile - The latency percentile of the method, between 0 and 1, after which the second
+// attempt is started, like 0.95. Default is 0.95.
+//
+// MinDelay - The minimum delay before the second attempt, it's also used until there are
+// enough latency samples of the method.
+type HedgePolicy struct {
+	Methods    []string
+	Percentile float64
+	MinDelay   time.Duration
+}
+
+// hedgeSamples is the number of latency samples kept per method.
+const hedgeSamples = 100
+
+// Hedge returns a Middleware that, for the idempotent methods of the policy, starts a second
+// attempt when the first one takes longer than the latency percentile and replies with the first
+// successful attempt, the other attempt is canceled. When both fail the first error is replied.
+//
+// The methods must be safe to execute twice at the same time.
+func Hedge(policy HedgePolicy) Middleware {
+	if policy.Percentile <= 0 || policy.Percentile > 1 {
+		policy.Percentile = 0.95
+	}
+	h := &hedger{policy: policy, methods: make(map[string]*latencyWindow, len(policy.Methods))}
+	for _, name := range policy.Methods {
+		h.methods[name] = &latencyWindow{}
+	}
+	return func(next Method) Method {
+		return MethodFunc(func(req *Request, resp *Response) {
+			w, ok := h.methods[req.Method]
+			if !ok {
+				next.Execute(req, resp)
+				return
+			}
+			h.execute(next, w, req, resp)
+		})
+	}
+}
+
+// hedger keeps the latencies of the hedged methods.
+type hedger struct {
+	policy  HedgePolicy
+	methods map[string]*latencyWindow
+}
+
+// attempt is the outcome of one execution of a hedged method.
+type attempt struct {
+	resp    Response
+	elapsed time.Duration
+}
+
+// execute will run the first attempt and the second one if the first is slow.
+func (h *hedger) execute(next Method, w *latencyWindow, req *Request, resp *Response) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	done := make(chan attempt, 2)
+	run := func() {
+		start := time.Now()
+		a := attempt{resp: *resp}
+		next.Execute(req.WithContext(ctx), &a.resp)
+		a.elapsed = time.Since(start)
+		done <- a
+	}
+	go run()
+
+	delay := w.percentile(h.policy.Percentile)
+	if delay < h.policy.MinDelay {
+		delay = h.policy.MinDelay
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	running := 1
+	var first *attempt
+	for running > 0 {
+		select {
+		case <-timer.C:
+			if first == nil {
+				running++
+				go run()
+			}
+		case a := <-done:
+			running--
+			if a.resp.Error == nil {
+				w.add(a.elapsed)
+				*resp = a.resp
+				return
+			}
+			if first == nil {
+				first = &a
+			}
+			// The first attempt failed before the delay, there is nothing to hedge
+			if running == 0 {
+				*resp = first.resp
+				return
+			}
+		}
+	}
+}
+
+// latencyWindow keeps the last latencies of a method.
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+// add will keep the latency, replacing the oldest one when the window is full.
+func (w *latencyWindow) add(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.samples) < hedgeSamples {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % hedgeSamples
+}
+
+// percentile returns the latency percentile p or 0 if there are not enough samples.
+func (w *latencyWindow) percentile(p float64) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.samples) < 10 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
@@ -0,0 +1,54 @@
+package jrpc2go_test
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestHedge(t *testing.T) {
+	var calls int32
+	slowFirst := jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			select {
+			case <-req.Context().Done():
+				resp.Error = &jrpc.Error{Code: 1, Message: "canceled"}
+			case <-time.After(time.Second):
+				resp.Result = "slow"
+			}
+			return
+		}
+		resp.Result = "fast"
+	})
+	m := jrpc.NewManagerBuilder().
+		Use(jrpc.Hedge(jrpc.HedgePolicy{Methods: []string{"get"}, MinDelay: 20 * time.Millisecond})).
+		Add("get", slowFirst).
+		Add("put", slowFirst).
+		Build()
+
+	start := time.Now()
+	var out strings.Builder
+	if err := m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"get","id":1}`), &out); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if want := `{"jsonrpc":"2.0","id":1,"result":"fast"}`; strings.TrimSpace(out.String()) != want {
+		t.Errorf("Handle() = %v, want %v", out.String(), want)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Handle() took %v, want the hedged attempt", elapsed)
+	}
+
+	// Not hedged, the first call is fast now
+	out.Reset()
+	if err := m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"put","id":2}`), &out); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("calls = %v, want 3", got)
+	}
+}
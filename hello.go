@@ -0,0 +1,160 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// HelloMethod is the method of the request that negotiates the extensions of a Server
+// connection, it must be the first message sent by the client. The params are the Hello of the
+// client and the result is the Hello of the server, with the features and the compression
+// enabled on the connection.
+//
+// The extensions not requested by the client are disabled, like the heartbeat pings, so a
+// client that doesn't know them isn't surprised. The connections without the exchange keep all
+// the extensions the Server was configured with.
+const HelloMethod = "rpc.hello"
+
+// The features negotiated by HelloMethod.
+const (
+	// FeatureHeartbeat is the pings sent by the Server, see WithHeartbeat.
+	FeatureHeartbeat = "heartbeat"
+	// FeatureShutdownNotice is the notification sent on Shutdown, see WithShutdownNotice.
+	FeatureShutdownNotice = "shutdownNotice"
+	// FeatureCancellation is the rpc.cancel method, see Cancellation. The method is not found on
+	// the connections that don't request it.
+	FeatureCancellation = "cancellation"
+)
+
+// Hello is the params and the result of HelloMethod.
+//
+// Features - The features supported by the client, on the result the ones enabled.
+//
+// Compression - The codecs supported by the client in order of preference, on the result the
+// one selected, if any. The messages after the response are compressed as with CompressionMethod.
+//
+// BatchConcurrency - On the result the requests of a batch executed at the same time.
+//
+// MaxPipelined - On the result the requests of the connection executed at the same time.
+type Hello struct {
+	Features         []string `json:"features"`
+	Compression      []string `json:"compression,omitempty"`
+	BatchConcurrency int      `json:"batchConcurrency,omitempty"`
+	MaxPipelined     int      `json:"maxPipelined,omitempty"`
+}
+
+// negotiate returns true if raw is the request of HelloMethod or CompressionMethod, which is
+// replied and applied to the connection. The codec returned is nil if the messages are not
+// compressed.
+func (s *Server) negotiate(sc *serverConn, raw json.RawMessage) (Codec, bool) {
+	if !bytes.Contains(raw, []byte(HelloMethod)) && !bytes.Contains(raw, []byte(CompressionMethod)) {
+		return nil, false
+	}
+	req, err := decodeRequest(raw)
+	if err != nil || req.Method != HelloMethod && req.Method != CompressionMethod {
+		return nil, false
+	}
+
+	resp := newResponse(req)
+	var codec Codec
+	if req.Method == CompressionMethod {
+		var names []string
+		if e := req.ParseParams(&names); e != nil {
+			resp.SetError(e)
+		} else if codec = s.selectCodec(names); codec != nil {
+			resp.SetResult(codec.Name())
+		}
+	} else {
+		var hello Hello
+		if e := req.ParseParams(&hello); e != nil {
+			resp.SetError(e)
+		} else {
+			reply := s.hello(sc, hello)
+			if codec = s.selectCodec(hello.Compression); codec != nil {
+				reply.Compression = []string{codec.Name()}
+			}
+			resp.SetResult(reply)
+		}
+	}
+	// Without an ID the client can't know the result
+	if req.ID == nil {
+		return nil, true
+	}
+
+	var out bytes.Buffer
+	if err := s.m.encoder.encode(&out, resp); err != nil {
+		return nil, true
+	}
+	// The response is the last message not compressed
+	sc.wmu.Lock()
+	defer sc.wmu.Unlock()
+	if d := s.writeTimeout; d > 0 {
+		_ = sc.conn.SetWriteDeadline(time.Now().Add(d))
+	}
+	if err := writeLine(sc.conn, out.Bytes()); err != nil {
+		return nil, true
+	}
+	sc.codec = codec
+	return codec, true
+}
+
+// selectCodec returns the first codec of names supported by the Server, nil if none is.
+func (s *Server) selectCodec(names []string) Codec {
+	for _, name := range names {
+		if c, ok := s.codecs[name]; ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// hello returns the Hello of the Server for the client, the features the client doesn't
+// support are disabled on the connection.
+func (s *Server) hello(sc *serverConn, client Hello) Hello {
+	supported := map[string]bool{
+		FeatureHeartbeat:      s.heartbeat > 0,
+		FeatureShutdownNotice: s.shutdownMethod != "",
+	}
+	_, supported[FeatureCancellation] = s.m.table.get(cancelMethodName, "")
+
+	reply := Hello{Features: []string{}, BatchConcurrency: s.m.batchConcurrency, MaxPipelined: s.maxPipelined}
+	if reply.BatchConcurrency < 1 {
+		reply.BatchConcurrency = 1
+	}
+	enabled := make(map[string]bool, len(client.Features))
+	for _, f := range client.Features {
+		if supported[f] && !enabled[f] {
+			enabled[f] = true
+			reply.Features = append(reply.Features, f)
+		}
+	}
+
+	if !enabled[FeatureHeartbeat] {
+		sc.ping.stop()
+	}
+	if !enabled[FeatureShutdownNotice] {
+		s.mu.Lock()
+		sc.noNotice = true
+		s.mu.Unlock()
+	}
+	if !enabled[FeatureCancellation] {
+		sc.noCancel = true
+	}
+	return reply
+}
+
+// stopper closes a channel once, it's used to stop the heartbeat of a connection.
+type stopper struct {
+	once sync.Once
+	done chan struct{}
+}
+
+// stop will close the channel if it's not closed, it's safe on a nil stopper.
+func (st *stopper) stop() {
+	if st == nil {
+		return
+	}
+	st.once.Do(func() { close(st.done) })
+}
@@ -1,6 +1,14 @@
 package jrpc2go
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
 	"strings"
 )
@@ -8,28 +16,415 @@ import (
 const contentTypeKey = "Content-Type"
 const contentTypeValue = "application/json"
 
+// defaultSignatureHeader is the HTTP header used for the signatures if none is specified.
+const defaultSignatureHeader = "X-Signature"
+
+// ErrUnsupportedMediaType is returned when the Content-Type of the request is not accepted.
+var ErrUnsupportedMediaType = errors.New("jsonrpc: unsupported media type")
+
+// ErrMethodNotAllowed is returned when the HTTP method of the request is not supported.
+var ErrMethodNotAllowed = errors.New("jsonrpc: http method not allowed")
+
+// ErrRequestTooLarge is returned when the body of the request is over the maximum size.
+var ErrRequestTooLarge = errors.New("jsonrpc: request body too large")
+
+// defaultMaxBodySize is the maximum size of the request body if none is specified.
+const defaultMaxBodySize = 10 << 20
+
+// responseBufferSize is how much of the response is kept before it's written when the whole
+// response isn't needed, so the status can still be chosen after the request is decoded.
+const responseBufferSize = 64 << 10
+
+// allowedMethods are the HTTP methods supported by the handler returned by HTTPHandleFunc.
+const allowedMethods = "POST, OPTIONS, HEAD"
+
+// HTTPError is a transport level failure of the handler returned by HTTPHandleFunc, like a
+// request that can't be read, with the HTTP status that should be replied.
+type HTTPError struct {
+	Status int
+	Err    error
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("jsonrpc: http %d: %v", e.Status, e.Err)
+}
+
+// Unwrap returns the cause of the failure.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPErrorResponder replies to the client on the transport level failures of the handler
+// returned by HTTPHandleFunc, err is always an *HTTPError.
+type HTTPErrorResponder func(w http.ResponseWriter, r *http.Request, err error)
+
+// defaultErrorResponder replies with the status of the error and no body, so the internal
+// errors are not exposed to the clients.
+func defaultErrorResponder(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	var he *HTTPError
+	if errors.As(err, &he) {
+		status = he.Status
+	}
+	w.WriteHeader(status)
+}
+
+// HTTPOption configures the handler returned by HTTPHandleFunc.
+type HTTPOption func(*httpHandler)
+
+// WithSignature will verify the signature of the request body before the execution and sign
+// the response body, the signatures are sent base64 encoded on the header.
+//
+// Requests without a valid signature are rejected with 401 Unauthorized. If the header is
+// empty X-Signature is used.
+func WithSignature(s Signer, header string) HTTPOption {
+	if header == "" {
+		header = defaultSignatureHeader
+	}
+	return func(h *httpHandler) {
+		h.signer = s
+		h.signatureHeader = header
+	}
+}
+
+// defaultContentTypes are the media types of the requests accepted if none are specified, the
+// last two are not standard but they are used by some clients.
+var defaultContentTypes = []string{contentTypeValue, "application/json-rpc", "application/jsonrequest"}
+
+// WithContentTypes replaces the media types accepted on the Content-Type header of the requests,
+// the default is application/json, application/json-rpc and application/jsonrequest.
+//
+// The parameters of the header are ignored except the charset, which must be utf-8 if present.
+func WithContentTypes(types ...string) HTTPOption {
+	return func(h *httpHandler) {
+		h.contentTypes = types
+	}
+}
+
+// WithMaxBodySize limits the size of the request body, the requests over it are rejected with
+// 413 Request Entity Too Large and ErrRequestTooLarge. The default is 10 MiB.
+func WithMaxBodySize(n int64) HTTPOption {
+	return func(h *httpHandler) {
+		h.maxBodySize = n
+	}
+}
+
+// WithErrorResponder replaces how the transport level failures are replied, by default only the
+// HTTP status of the failure is sent. It can be used to log the failures or to reply a body in
+// the format expected by the clients.
+func WithErrorResponder(fn HTTPErrorResponder) HTTPOption {
+	return func(h *httpHandler) {
+		h.onError = fn
+	}
+}
+
+// WithContextFunc sets the function that returns the context of the JSON RPC request from the
+// HTTP request, so values like the user or the tenant can be derived from the cookies or the
+// headers and injected before the methods are executed. It should derive the context from
+// r.Context() to keep the cancellation of the request.
+func WithContextFunc(fn func(r *http.Request) context.Context) HTTPOption {
+	return func(h *httpHandler) {
+		h.contextFunc = fn
+	}
+}
+
+// WithStreamingBatch will write the responses of a batch as they complete, using chunked
+// transfer encoding, so clients with incremental JSON parsers see the results early rather
+// than after the slowest call finishes. It's most useful with SetBatchConcurrency and
+// AllowUnorderedBatch, otherwise a response waits for the ones of the previous requests.
+//
+// Once the first response is written the status is 200 OK, so an invalid request found later
+// in the batch is replied as the last element of the array. It's ignored when the whole body
+// is needed, like with WithSignature since the signature is sent on the header, WithPreFilter
+// or WithMediaCodec.
+func WithStreamingBatch() HTTPOption {
+	return func(h *httpHandler) {
+		h.streamBatch = true
+	}
+}
+
+// httpHandler keeps the configuration of the handler returned by HTTPHandleFunc.
+type httpHandler struct {
+	m               *Manager
+	signer          Signer
+	signatureHeader string
+	contentTypes    []string
+	onError         HTTPErrorResponder
+	contextFunc     func(r *http.Request) context.Context
+	streamBatch     bool
+	preFilter       PreFilter
+	rateLimits      []RateLimit
+	codecs          map[string]MediaCodec
+	maxBodySize     int64
+}
+
 // HTTPHandleFunc it's an helper function to mediate http requests to JSON RPC and back.
-func HTTPHandleFunc(m *Manager) func(w http.ResponseWriter, r *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if !strings.HasPrefix(r.Header.Get(contentTypeKey), contentTypeValue) {
-			w.WriteHeader(http.StatusUnsupportedMediaType)
+//
+// The requests are sent with POST, OPTIONS and HEAD reply with the Allow header and any other
+// HTTP method is rejected with 405 Method Not Allowed.
+func HTTPHandleFunc(m *Manager, opts ...HTTPOption) func(w http.ResponseWriter, r *http.Request) {
+	return newHTTPHandler(m, opts...).serveHTTP
+}
+
+// newHTTPHandler returns the handler with the options applied.
+func newHTTPHandler(m *Manager, opts ...HTTPOption) *httpHandler {
+	h := &httpHandler{
+		m:            m,
+		contentTypes: defaultContentTypes,
+		onError:      defaultErrorResponder,
+		maxBodySize:  defaultMaxBodySize,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// serveHTTP will handle the JSON RPC request of the http request.
+func (h *httpHandler) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodOptions:
+		w.Header().Set("Allow", allowedMethods)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodHead:
+		w.Header().Set("Allow", allowedMethods)
+		w.Header().Set(contentTypeKey, contentTypeValue)
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		w.Header().Set("Allow", allowedMethods)
+		h.onError(w, r, &HTTPError{Status: http.StatusMethodNotAllowed, Err: ErrMethodNotAllowed})
+		return
+	}
+
+	if !h.limitRate(w, r) {
+		return
+	}
+
+	reqType, reqCodec, ok := h.requestCodec(r.Header.Get(contentTypeKey))
+	if !ok {
+		h.onError(w, r, &HTTPError{Status: http.StatusUnsupportedMediaType, Err: ErrUnsupportedMediaType})
+		return
+	}
+	respType, respCodec := h.responseCodec(r.Header.Get("Accept"), reqType, reqCodec)
+	if len(h.codecs) > 0 {
+		w.Header().Add("Vary", "Accept")
+	}
+
+	if r.ContentLength == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	body := &limitedBody{r: http.MaxBytesReader(w, r.Body, h.maxBodySize), n: h.maxBodySize}
+	defer body.Close()
+
+	// The whole request and response are only kept when they are needed, otherwise the requests
+	// are decoded as they are read and the response is written as it's encoded
+	buffered := h.signer != nil || h.preFilter != nil || reqCodec != nil || respCodec != nil
+	var src io.Reader = body
+	if buffered {
+		b, he := h.readBody(r, body, reqCodec)
+		if he != nil {
+			h.onError(w, r, he)
+			return
+		}
+		src = bytes.NewReader(b)
+	}
+
+	ctx := r.Context()
+	if h.contextFunc != nil {
+		ctx = h.contextFunc(r)
+	}
+	ctx = withTransportInfo(ctx, httpTransportInfo(r, "http"))
+	if lang := acceptLanguage(r.Header.Get("Accept-Language")); lang != "" && LocaleFromContext(ctx) == "" {
+		ctx = ContextWithLocale(ctx, lang)
+	}
+	if id := r.Header.Get(CorrelationIDHeader); id != "" {
+		ctx = ContextWithCorrelationID(ctx, id)
+		w.Header().Set(CorrelationIDHeader, id)
+	}
+
+	var out bytes.Buffer
+	sw := &streamWriter{w: w, out: &out, contentType: respType}
+	var start func()
+	if !buffered {
+		sw.limit = responseBufferSize
+		if h.streamBatch {
+			start = sw.start
+		}
+	}
+	status := http.StatusOK
+	err := h.m.handle(ctx, src, sw, start)
+	// The response was already written as it was encoded
+	if sw.streaming {
+		return
+	}
+	if body.exceeded {
+		h.onError(w, r, &HTTPError{Status: http.StatusRequestEntityTooLarge, Err: ErrRequestTooLarge})
+		return
+	}
+	if err != nil {
+		// The client disconnected so there is no one to reply
+		if err == context.Canceled {
+			return
+		}
+		// The request couldn't be read while it was decoded
+		var te *TransportError
+		if errors.As(err, &te) && te.Op == "read" {
+			h.onError(w, r, &HTTPError{Status: http.StatusBadRequest, Err: te.Err})
 			return
 		}
+		// Malformed requests are replied by Handle with the error response
+		var e *Error
+		if !errors.As(err, &e) {
+			h.onError(w, r, &HTTPError{Status: http.StatusInternalServerError, Err: err})
+			return
+		}
+		status = http.StatusBadRequest
+	}
 
-		if r.ContentLength == 0 {
-			w.WriteHeader(http.StatusNoContent)
+	// Notifications don't have a response
+	if out.Len() == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	resp := out.Bytes()
+	if respCodec != nil {
+		if resp, err = respCodec.Encode(bytes.TrimSpace(resp)); err != nil {
+			h.onError(w, r, &HTTPError{Status: http.StatusInternalServerError, Err: err})
+			return
+		}
+	}
+
+	if h.signer != nil {
+		sig, err := h.signer.Sign(resp)
+		if err != nil {
+			h.onError(w, r, &HTTPError{Status: http.StatusInternalServerError, Err: err})
 			return
 		}
+		w.Header().Set(h.signatureHeader, base64.StdEncoding.EncodeToString(sig))
+	}
+
+	w.Header().Add(contentTypeKey, respType)
+	w.WriteHeader(status)
+	if _, err := w.Write(resp); err != nil {
+		//TODO not sure what to do here
+	}
+	_ = flush(w)
+}
+
+// acceptContentType returns true if the media type of the Content-Type header is one of the
+// types and the charset, if present, is utf-8.
+func acceptContentType(header string, types []string) bool {
+	mediaType, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return false
+	}
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+		return false
+	}
+	for _, t := range types {
+		if strings.EqualFold(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// readBody returns the whole body of the request decoded by the codec, after the prefilter and
+// the signature check.
+func (h *httpHandler) readBody(r *http.Request, body *limitedBody, codec MediaCodec) ([]byte, *HTTPError) {
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		if body.exceeded {
+			return nil, &HTTPError{Status: http.StatusRequestEntityTooLarge, Err: ErrRequestTooLarge}
+		}
+		return nil, &HTTPError{Status: http.StatusBadRequest, Err: err}
+	}
 
-		w.Header().Add(contentTypeKey, contentTypeValue)
+	if he := h.filterHTTP(r, b); he != nil {
+		return nil, he
+	}
 
-		err := m.Handle(r.Context(), r.Body, w)
-		defer r.Body.Close()
+	if h.signer != nil {
+		sig, err := base64.StdEncoding.DecodeString(r.Header.Get(h.signatureHeader))
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			if _, err := w.Write([]byte(err.Error())); err != nil {
-				//TODO not sure what to do here
-			}
+			err = ErrInvalidSignature
+		} else {
+			err = h.signer.Verify(b, sig)
 		}
+		if err != nil {
+			return nil, &HTTPError{Status: http.StatusUnauthorized, Err: err}
+		}
+	}
+
+	return decodeBody(codec, b)
+}
+
+// limitedBody is the request body limited by http.MaxBytesReader, it records when the limit is
+// exceeded since the error of the reader has no type.
+type limitedBody struct {
+	r        io.ReadCloser
+	n        int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.n -= int64(n)
+	if err != nil && err != io.EOF && b.n <= 0 {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.r.Close()
+}
+
+// streamWriter buffers the response until start is called or the buffer is over the limit, if
+// any, then it writes directly to the http.ResponseWriter and flushes after each element of the
+// batch.
+type streamWriter struct {
+	w           http.ResponseWriter
+	out         *bytes.Buffer
+	contentType string
+	limit       int
+	streaming   bool
+}
+
+// start will send the headers of the streamed response.
+func (s *streamWriter) start() {
+	s.w.Header().Add(contentTypeKey, s.contentType)
+	s.w.WriteHeader(http.StatusOK)
+	s.streaming = true
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.streaming {
+		return s.w.Write(p)
+	}
+	s.out.Write(p)
+	if s.limit <= 0 || s.out.Len() <= s.limit {
+		return len(p), nil
+	}
+	// The response is too large to keep, the rest is written as it's encoded
+	s.start()
+	if _, err := s.w.Write(s.out.Bytes()); err != nil {
+		return 0, err
+	}
+	s.out.Reset()
+	return len(p), nil
+}
+
+// Flush will send the written data to the client if it's streaming.
+func (s *streamWriter) Flush() error {
+	if !s.streaming {
+		return nil
 	}
+	return flush(s.w)
 }
@@ -0,0 +1,694 @@
+package jrpc2go_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestHTTPHandleFunc_WithSignature(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Build()
+	signer := jrpc.NewHMACSigner([]byte("secret"))
+	h := jrpc.HTTPHandleFunc(&m, jrpc.WithSignature(signer, ""))
+
+	body := `{"jsonrpc":"2.0","method":"add","id":1,"params":{"v1":1,"v2":2}}`
+	sig, _ := signer.Sign([]byte(body))
+
+	tests := []struct {
+		name       string
+		signature  string
+		wantStatus int
+	}{
+		{name: "Valid Signature", signature: base64.StdEncoding.EncodeToString(sig), wantStatus: http.StatusOK},
+		{name: "Invalid Signature", signature: base64.StdEncoding.EncodeToString([]byte("bad")), wantStatus: http.StatusUnauthorized},
+		{name: "No Signature", signature: "", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("X-Signature", tt.signature)
+			w := httptest.NewRecorder()
+			h(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HTTPHandleFunc() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			rsig, err := base64.StdEncoding.DecodeString(w.Header().Get("X-Signature"))
+			if err != nil {
+				t.Fatalf("response signature error = %v", err)
+			}
+			if err := signer.Verify(w.Body.Bytes(), rsig); err != nil {
+				t.Errorf("response signature Verify() error = %v", err)
+			}
+		})
+	}
+}
+
+type correlationMethod struct{}
+
+func (m *correlationMethod) Execute(req *jrpc.Request, resp *jrpc.Response) {
+	resp.Error = &jrpc.Error{Code: 1, Message: jrpc.CorrelationIDFromContext(req.Context())}
+}
+
+func TestHTTPHandleFunc_CorrelationID(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		EchoCorrelationID().
+		Add("fail", &correlationMethod{}).
+		Build()
+	h := jrpc.HTTPHandleFunc(&m)
+
+	body := `{"jsonrpc":"2.0","method":"fail","id":1}`
+	r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Correlation-ID", "abc")
+	w := httptest.NewRecorder()
+	h(w, r)
+
+	want := `{"jsonrpc":"2.0","id":1,"error":{"code":1,"message":"abc","data":{"correlationId":"abc"}}}` + "\n"
+	if w.Body.String() != want {
+		t.Errorf("HTTPHandleFunc() = %v, want %v", w.Body.String(), want)
+	}
+	if got := w.Header().Get("X-Correlation-ID"); got != "abc" {
+		t.Errorf("HTTPHandleFunc() correlation header = %v, want abc", got)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	h(w, r)
+
+	// The ID generated on the first use is the same for the method and the error data
+	got := regexp.MustCompile(`"message":"([0-9a-f]{32})","data":{"correlationId":"([0-9a-f]{32})"}`).FindStringSubmatch(w.Body.String())
+	if got == nil || got[1] != got[2] {
+		t.Errorf("HTTPHandleFunc() generated correlation = %v", w.Body.String())
+	}
+
+	// Without the header the ID is adopted from the meta member of the params
+	body = `{"jsonrpc":"2.0","method":"fail","id":1,"params":{"_meta":{"correlationId":"m1"}}}`
+	r = httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	h(w, r)
+
+	want = `{"jsonrpc":"2.0","id":1,"error":{"code":1,"message":"m1","data":{"correlationId":"m1"}}}` + "\n"
+	if w.Body.String() != want {
+		t.Errorf("HTTPHandleFunc() = %v, want %v", w.Body.String(), want)
+	}
+}
+
+func TestHTTPHandleFunc_Notifications(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("echo", &echoMethod{}).
+		Build()
+	h := jrpc.HTTPHandleFunc(&m)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Notification",
+			body:       `{"jsonrpc":"2.0","method":"echo","params":"a"}`,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "Notification Batch",
+			body:       `[{"jsonrpc":"2.0","method":"echo","params":"a"},{"jsonrpc":"2.0","method":"echo","params":"b"}]`,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "Mixed Batch",
+			body:       `[{"jsonrpc":"2.0","method":"echo","params":"a"},{"jsonrpc":"2.0","method":"echo","params":"b","id":1}]`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"jsonrpc":"2.0","id":1,"result":"b"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			h(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("HTTPHandleFunc() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("HTTPHandleFunc() body = %v, want %v", w.Body.String(), tt.wantBody)
+			}
+			if tt.wantStatus == http.StatusNoContent && w.Header().Get("Content-Type") != "" {
+				t.Errorf("HTTPHandleFunc() Content-Type = %v, want none", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestHTTPHandleFunc_ContentTypes(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("echo", &echoMethod{}).
+		Build()
+	body := `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`
+
+	tests := []struct {
+		name        string
+		opts        []jrpc.HTTPOption
+		contentType string
+		wantStatus  int
+	}{
+		{name: "JSON", contentType: "application/json", wantStatus: http.StatusOK},
+		{name: "JSON UTF-8", contentType: "application/json; charset=UTF-8", wantStatus: http.StatusOK},
+		{name: "JSON Latin-1", contentType: "application/json; charset=iso-8859-1", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "JSON-RPC", contentType: "application/json-rpc", wantStatus: http.StatusOK},
+		{name: "JSON Request", contentType: "application/jsonrequest", wantStatus: http.StatusOK},
+		{name: "JSON Prefix", contentType: "application/jsonp", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "Missing", contentType: "", wantStatus: http.StatusUnsupportedMediaType},
+		{
+			name:        "Custom Accepted",
+			opts:        []jrpc.HTTPOption{jrpc.WithContentTypes("application/vnd.rpc+json")},
+			contentType: "application/vnd.rpc+json",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "Custom Replaces Default",
+			opts:        []jrpc.HTTPOption{jrpc.WithContentTypes("application/vnd.rpc+json")},
+			contentType: "application/json",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			jrpc.HTTPHandleFunc(&m, tt.opts...)(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("HTTPHandleFunc() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHTTPHandleFunc_WithErrorResponder(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("echo", &echoMethod{}).
+		Build()
+
+	var got error
+	h := jrpc.HTTPHandleFunc(&m, jrpc.WithErrorResponder(func(w http.ResponseWriter, r *http.Request, err error) {
+		got = err
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	h(w, r)
+
+	if w.Code != http.StatusTeapot {
+		t.Errorf("HTTPHandleFunc() status = %d, want %d", w.Code, http.StatusTeapot)
+	}
+	var he *jrpc.HTTPError
+	if !errors.As(got, &he) || he.Status != http.StatusUnsupportedMediaType || !errors.Is(got, jrpc.ErrUnsupportedMediaType) {
+		t.Errorf("HTTPHandleFunc() responder error = %v", got)
+	}
+
+	// The default responder doesn't expose the internal errors
+	r = httptest.NewRequest(http.MethodPost, "/rpc", failReader{})
+	r.Header.Set("Content-Type", "application/json")
+	r.ContentLength = -1
+	w = httptest.NewRecorder()
+	jrpc.HTTPHandleFunc(&m)(w, r)
+
+	if w.Code != http.StatusBadRequest || w.Body.Len() != 0 {
+		t.Errorf("HTTPHandleFunc() = %d %q, want %d and no body", w.Code, w.Body.String(), http.StatusBadRequest)
+	}
+}
+
+func TestHTTPHandleFunc_MalformedRequest(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("echo", &echoMethod{}).
+		Build()
+
+	tests := []struct {
+		name     string
+		body     string
+		wantBody string
+	}{
+		{
+			name:     "Empty Batch",
+			body:     `[]`,
+			wantBody: `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request","data":"no methods specified"}}` + "\n",
+		},
+		{
+			name:     "Truncated",
+			body:     `{"jsonrpc":"2.0","method":`,
+			wantBody: `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error","data":"truncated request"}}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			jrpc.HTTPHandleFunc(&m)(w, r)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("HTTPHandleFunc() status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("HTTPHandleFunc() body = %v, want %v", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+type tenantKey struct{}
+
+func TestHTTPHandleFunc_WithContextFunc(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("tenant", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			resp.Result = req.Context().Value(tenantKey{})
+		})).
+		Build()
+	h := jrpc.HTTPHandleFunc(&m, jrpc.WithContextFunc(func(r *http.Request) context.Context {
+		return context.WithValue(r.Context(), tenantKey{}, r.Header.Get("X-Tenant"))
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"tenant","id":1}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Tenant", "acme")
+	w := httptest.NewRecorder()
+	h(w, r)
+
+	if want := `{"jsonrpc":"2.0","id":1,"result":"acme"}` + "\n"; w.Body.String() != want {
+		t.Errorf("HTTPHandleFunc() = %v, want %v", w.Body.String(), want)
+	}
+}
+
+func TestHTTPHandleFunc_Methods(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("echo", &echoMethod{}).
+		Build()
+	h := jrpc.HTTPHandleFunc(&m)
+
+	tests := []struct {
+		method     string
+		wantStatus int
+	}{
+		{method: http.MethodOptions, wantStatus: http.StatusNoContent},
+		{method: http.MethodHead, wantStatus: http.StatusOK},
+		{method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed},
+		{method: http.MethodPut, wantStatus: http.StatusMethodNotAllowed},
+		{method: http.MethodDelete, wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/rpc", nil)
+			w := httptest.NewRecorder()
+			h(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("HTTPHandleFunc() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Allow"); got != "POST, OPTIONS, HEAD" {
+				t.Errorf("HTTPHandleFunc() Allow = %v, want POST, OPTIONS, HEAD", got)
+			}
+			if w.Body.Len() != 0 {
+				t.Errorf("HTTPHandleFunc() body = %v, want none", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHTTPHandleFunc_WithStreamingBatch(t *testing.T) {
+	release := make(chan struct{})
+	m := jrpc.NewManagerBuilder().
+		Add("slow", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			<-release
+			resp.Result = "slow"
+		})).
+		Add("fast", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) { resp.Result = "fast" })).
+		SetBatchConcurrency(2).
+		AllowUnorderedBatch().
+		Build()
+	srv := httptest.NewServer(http.HandlerFunc(jrpc.HTTPHandleFunc(&m, jrpc.WithStreamingBatch())))
+	defer srv.Close()
+
+	body := `[{"jsonrpc":"2.0","method":"slow","id":1},{"jsonrpc":"2.0","method":"fast","id":2}]`
+	res, err := http.Post(srv.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	defer res.Body.Close()
+
+	// The fast result must arrive while the slow call is still running
+	first := `[{"jsonrpc":"2.0","id":2,"result":"fast"}`
+	buf := make([]byte, len(first))
+	if _, err := io.ReadFull(res.Body, buf); err != nil {
+		t.Fatalf("ReadFull() error = %v", err)
+	}
+	if string(buf) != first {
+		t.Errorf("first element = %s, want %s", buf, first)
+	}
+
+	close(release)
+	rest, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if want := `,{"jsonrpc":"2.0","id":1,"result":"slow"}]` + "\n"; string(rest) != want {
+		t.Errorf("rest = %s, want %s", rest, want)
+	}
+}
+
+func TestHTTPHandleFunc_WithStreamingBatch_BatchTimeout(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("slow", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			<-req.Context().Done()
+		})).
+		Add("fast", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) { resp.Result = "fast" })).
+		SetBatchTimeout(20 * time.Millisecond).
+		SetBatchConcurrency(4).
+		Build()
+	srv := httptest.NewServer(http.HandlerFunc(jrpc.HTTPHandleFunc(&m, jrpc.WithStreamingBatch())))
+	defer srv.Close()
+
+	// The requests after the first one are received once the batch deadline expired
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = io.WriteString(pw, `[{"jsonrpc":"2.0","method":"slow","id":1},`)
+		time.Sleep(50 * time.Millisecond)
+		_, _ = io.WriteString(pw, `{"jsonrpc":"2.0","method":"fast","id":2},{"jsonrpc":"2.0","method":"fast","id":3},{"jsonrpc":"2.0","method":"fast","id":4}]`)
+		pw.Close()
+	}()
+	res, err := http.Post(srv.URL, "application/json", pr)
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	defer res.Body.Close()
+
+	var got []struct {
+		ID    int
+		Error struct {
+			Code jrpc.ErrorCode
+			Data struct {
+				NotAttempted []int
+			}
+		}
+	}
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	// Each streamed error lists the requests not attempted up to its own
+	want := map[int][]int{2: {2}, 3: {2, 3}, 4: {2, 3, 4}}
+	if len(got) != 4 {
+		t.Fatalf("responses = %+v, want 4", got)
+	}
+	for _, r := range got[1:] {
+		if r.Error.Code != -32002 || !reflect.DeepEqual(r.Error.Data.NotAttempted, want[r.ID]) {
+			t.Errorf("response %d = %+v, want not attempted %v", r.ID, r.Error, want[r.ID])
+		}
+	}
+}
+
+// failingResponseWriter fails the writes after the first n.
+type failingResponseWriter struct {
+	*httptest.ResponseRecorder
+	n int
+}
+
+func (w *failingResponseWriter) Write(p []byte) (int, error) {
+	if w.n--; w.n < 0 {
+		return 0, io.ErrClosedPipe
+	}
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestHTTPHandleFunc_WithStreamingBatch_WriteError(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("echo", &echoMethod{}).
+		Build()
+	h := jrpc.HTTPHandleFunc(&m, jrpc.WithStreamingBatch())
+
+	body := `[{"jsonrpc":"2.0","method":"echo","params":"a","id":1},{"jsonrpc":"2.0","method":"echo","params":"b","id":2},{"jsonrpc":"2.0","method":"echo","params":"c","id":3}]`
+	r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := &failingResponseWriter{ResponseRecorder: httptest.NewRecorder(), n: 1}
+	h(w, r)
+
+	// Nothing is written after the failed element, not even the end of the array
+	if want := `[{"jsonrpc":"2.0","id":1,"result":"a"}`; w.Body.String() != want {
+		t.Errorf("HTTPHandleFunc() body = %s, want %s", w.Body.String(), want)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("HTTPHandleFunc() status = %v, want %v", w.Code, http.StatusOK)
+	}
+}
+
+func TestHTTPHandleFunc_TransportInfo(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("transport", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			info, _ := jrpc.TransportInfoFromContext(req.Context())
+			resp.Result = info.Kind + " " + info.Endpoint
+		})).
+		Build()
+	h := jrpc.HTTPHandleFunc(&m)
+
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"transport","id":1}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h(w, r)
+
+	if want := `{"jsonrpc":"2.0","id":1,"result":"http http://example.com/rpc"}` + "\n"; w.Body.String() != want {
+		t.Errorf("HTTPHandleFunc() = %v, want %v", w.Body.String(), want)
+	}
+}
+
+func TestHTTPHandleFunc_WithPreFilter(t *testing.T) {
+	m := jrpc.NewManagerBuilder().Add("echo", &echoMethod{}).Build()
+	h := jrpc.HTTPHandleFunc(&m, jrpc.WithPreFilter(func(r *jrpc.RawRequest) error {
+		if r.Header.Get("X-Blocked") != "" {
+			return errors.New("blocked")
+		}
+		if len(r.Body) > 64 {
+			return &jrpc.HTTPError{Status: http.StatusRequestEntityTooLarge}
+		}
+		return nil
+	}))
+
+	tests := []struct {
+		name       string
+		body       string
+		blocked    bool
+		wantStatus int
+	}{
+		{name: "Accepted", body: `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`, wantStatus: http.StatusOK},
+		{name: "Rejected", body: `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`, blocked: true, wantStatus: http.StatusForbidden},
+		{name: "Custom Status", body: `{"jsonrpc":"2.0","method":"echo","params":"` + strings.Repeat("a", 64) + `","id":1}`, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			if tt.blocked {
+				r.Header.Set("X-Blocked", "1")
+			}
+			w := httptest.NewRecorder()
+			h(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("HTTPHandleFunc() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHTTPHandleFunc_WithMaxBodySize(t *testing.T) {
+	m := jrpc.NewManagerBuilder().Add("echo", &echoMethod{}).Build()
+	keep := jrpc.WithPreFilter(func(r *jrpc.RawRequest) error { return nil })
+	small := `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`
+	large := `{"jsonrpc":"2.0","method":"echo","params":"` + strings.Repeat("a", 128) + `","id":1}`
+	huge := strings.Repeat("a", 100<<10)
+
+	tests := []struct {
+		name       string
+		body       string
+		opts       []jrpc.HTTPOption
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "Within Limit", body: small, opts: []jrpc.HTTPOption{jrpc.WithMaxBodySize(64)}, wantStatus: http.StatusOK,
+			wantBody: `{"jsonrpc":"2.0","id":1,"result":"a"}`},
+		{name: "Over Limit", body: large, opts: []jrpc.HTTPOption{jrpc.WithMaxBodySize(64)}, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "Over Limit Buffered", body: large, opts: []jrpc.HTTPOption{jrpc.WithMaxBodySize(64), keep}, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "Response Over Buffer", body: `{"jsonrpc":"2.0","method":"echo","params":"` + huge + `","id":1}`, wantStatus: http.StatusOK,
+			wantBody: `{"jsonrpc":"2.0","id":1,"result":"` + huge + `"}`},
+		{name: "Parse Error", body: `{"jsonrpc":"2.0",`, wantStatus: http.StatusBadRequest,
+			wantBody: `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error","data":"truncated request"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got error
+			opts := append(tt.opts, jrpc.WithErrorResponder(func(w http.ResponseWriter, r *http.Request, err error) {
+				got = err
+				var he *jrpc.HTTPError
+				if errors.As(err, &he) {
+					w.WriteHeader(he.Status)
+				}
+			}))
+			r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			jrpc.HTTPHandleFunc(&m, opts...)(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("HTTPHandleFunc() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusRequestEntityTooLarge && !errors.Is(got, jrpc.ErrRequestTooLarge) {
+				t.Errorf("HTTPHandleFunc() responder error = %v, want %v", got, jrpc.ErrRequestTooLarge)
+			}
+			if body := strings.TrimSpace(w.Body.String()); tt.wantBody != "" && body != tt.wantBody {
+				t.Errorf("HTTPHandleFunc() body = %.80v, want %.80v", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestWithRateLimit_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rl   jrpc.RateLimit
+	}{
+		{name: "Zero Limit", rl: jrpc.RateLimit{Window: time.Minute}},
+		{name: "Negative Limit", rl: jrpc.RateLimit{Limit: -1, Window: time.Minute}},
+		{name: "Zero Window", rl: jrpc.RateLimit{Limit: 1}},
+		{name: "Negative Window", rl: jrpc.RateLimit{Limit: 1, Window: -time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("WithRateLimit() didn't panic")
+				}
+			}()
+			_ = jrpc.WithRateLimit(tt.rl)
+		})
+	}
+}
+
+func TestHTTPHandleFunc_WithRateLimit(t *testing.T) {
+	clock := newFakeClock()
+	m := jrpc.NewManagerBuilder().Add("echo", &echoMethod{}).SetClock(clock).Build()
+	h := jrpc.HTTPHandleFunc(&m,
+		jrpc.WithRateLimit(jrpc.RateLimit{Limit: 2, Window: time.Minute}),
+		jrpc.WithRateLimit(jrpc.RateLimit{Limit: 1, Window: time.Minute, KeyFunc: jrpc.BearerTokenKey}),
+	)
+	call := func(ip, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.RemoteAddr = ip + ":1234"
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := call("10.0.0.1", ""); w.Code != http.StatusOK {
+			t.Fatalf("call %d status = %v, want %v", i, w.Code, http.StatusOK)
+		}
+	}
+	w := call("10.0.0.1", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("status = %v, Retry-After = %v, want 429 and 60", w.Code, w.Header().Get("Retry-After"))
+	}
+	if want := `{"jsonrpc":"2.0","id":null,"error":{"code":-32003,"message":"Server overloaded","data":{"retryAfter":60000,"queueDepth":0}}}` + "\n"; w.Body.String() != want {
+		t.Errorf("body = %v, want %v", w.Body.String(), want)
+	}
+
+	if w := call("10.0.0.2", "abc"); w.Code != http.StatusOK {
+		t.Errorf("other IP status = %v, want %v", w.Code, http.StatusOK)
+	}
+	if w := call("10.0.0.3", "abc"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("same token status = %v, want %v", w.Code, http.StatusTooManyRequests)
+	}
+
+	// Half of the previous window is still counted
+	clock.Advance(90 * time.Second)
+	if w := call("10.0.0.1", ""); w.Code != http.StatusOK {
+		t.Errorf("next window status = %v, want %v", w.Code, http.StatusOK)
+	}
+}
+
+// hexCodec is a MediaCodec of JSON encoded as hex, it stands for a binary format like msgpack.
+type hexCodec struct{}
+
+func (hexCodec) Decode(body []byte) (json.RawMessage, error) {
+	return hex.DecodeString(string(body))
+}
+
+func (hexCodec) Encode(msg json.RawMessage) ([]byte, error) {
+	return []byte(hex.EncodeToString(msg)), nil
+}
+
+func TestHTTPHandleFunc_WithMediaCodec(t *testing.T) {
+	m := jrpc.NewManagerBuilder().Add("echo", &echoMethod{}).Build()
+	h := jrpc.HTTPHandleFunc(&m, jrpc.WithMediaCodec("application/x-hex", hexCodec{}))
+
+	req := `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`
+	resp := `{"jsonrpc":"2.0","id":1,"result":"a"}`
+	tests := []struct {
+		name        string
+		contentType string
+		accept      string
+		body        string
+		wantType    string
+		want        string
+	}{
+		{name: "JSON", contentType: "application/json", body: req, wantType: "application/json", want: resp + "\n"},
+		{name: "Codec", contentType: "application/x-hex", body: hex.EncodeToString([]byte(req)), wantType: "application/x-hex", want: hex.EncodeToString([]byte(resp))},
+		{name: "Accept Codec", contentType: "application/json", accept: "application/x-hex", body: req, wantType: "application/x-hex", want: hex.EncodeToString([]byte(resp))},
+		{name: "Accept JSON", contentType: "application/x-hex", accept: "application/x-hex;q=0.5, application/json", body: hex.EncodeToString([]byte(req)), wantType: "application/json", want: resp + "\n"},
+		{name: "Accept Any", contentType: "application/x-hex", accept: "*/*", body: hex.EncodeToString([]byte(req)), wantType: "application/x-hex", want: hex.EncodeToString([]byte(resp))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			h(w, r)
+			if got := w.Header().Get("Content-Type"); got != tt.wantType || w.Body.String() != tt.want {
+				t.Errorf("HTTPHandleFunc() = %v %v, want %v %v", got, w.Body.String(), tt.wantType, tt.want)
+			}
+			if w.Header().Get("Vary") != "Accept" {
+				t.Errorf("Vary = %v, want Accept", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// inFlightMethodName is the name of the admin method that lists the requests being executed.
+const inFlightMethodName = "rpc.inflight"
+
+// InFlightRequest describes a request that is currently being executed by the Manager.
+//
+// ID - The request identifier, it's null for notifications.
+//
+// Method - The name of the method being executed.
+//
+// Elapsed - The time in milliseconds since the execution started.
+type InFlightRequest struct {
+	ID      *json.RawMessage `json:"id"`
+	Method  string           `json:"method"`
+	Elapsed int64            `json:"elapsed"`
+}
+
+// inFlightEntry keeps the state of one method execution.
+type inFlightEntry struct {
+	seq      uint64
+	req      *Request
+	owner    Notifier
+	started  time.Time
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+// inFlightTracker keeps the methods being executed so they can be listed and canceled.
+type inFlightTracker struct {
+	clock   Clock
+	mu      sync.Mutex
+	seq     uint64
+	entries map[uint64]*inFlightEntry
+}
+
+// newInFlightTracker returns an empty tracker ready to use.
+func newInFlightTracker(clock Clock) *inFlightTracker {
+	return &inFlightTracker{
+		clock:   clock,
+		entries: make(map[uint64]*inFlightEntry),
+	}
+}
+
+// add will register a new execution and return the entry that must be removed once it finishes.
+func (t *inFlightTracker) add(req *Request, cancel context.CancelFunc) *inFlightEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seq++
+	e := &inFlightEntry{
+		seq:     t.seq,
+		req:     req,
+		owner:   NotifierFromContext(req.Context()),
+		started: t.clock.Now(),
+		cancel:  cancel,
+	}
+	t.entries[e.seq] = e
+	return e
+}
+
+// remove will unregister an execution.
+func (t *inFlightTracker) remove(e *inFlightEntry) {
+	t.mu.Lock()
+	delete(t.entries, e.seq)
+	t.mu.Unlock()
+}
+
+// isCanceled reports if the execution was canceled with cancel.
+func (t *inFlightTracker) isCanceled(e *inFlightEntry) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return e.canceled
+}
+
+// list returns the executions ordered by start time.
+func (t *inFlightTracker) list() []InFlightRequest {
+	return t.listOwned(nil, true)
+}
+
+// listOwned returns the executions of the owner ordered by start time, or of all the owners if
+// all is true. The requests without an owner are only listed with all.
+func (t *inFlightTracker) listOwned(owner Notifier, all bool) []InFlightRequest {
+	t.mu.Lock()
+	entries := make([]*inFlightEntry, 0, len(t.entries))
+	for _, e := range t.entries {
+		if all || owns(owner, e) {
+			entries = append(entries, e)
+		}
+	}
+	t.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+
+	now := t.clock.Now()
+	l := make([]InFlightRequest, 0, len(entries))
+	for _, e := range entries {
+		l = append(l, InFlightRequest{
+			ID:      e.req.ID,
+			Method:  e.req.Method,
+			Elapsed: now.Sub(e.started).Milliseconds(),
+		})
+	}
+	return l
+}
+
+// cancel will cancel all the executions of requests with the id and returns how many were canceled.
+func (t *inFlightTracker) cancel(id string) int {
+	return t.cancelOwned(id, nil, true)
+}
+
+// cancelOwned will cancel the executions of requests of the owner with the id, or of all the
+// owners if all is true, and returns how many were canceled.
+func (t *inFlightTracker) cancelOwned(id string, owner Notifier, all bool) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for _, e := range t.entries {
+		if !all && !owns(owner, e) {
+			continue
+		}
+		if e.req.ID != nil && idString(*e.req.ID) == id && !e.canceled {
+			e.canceled = true
+			e.cancel()
+			n++
+		}
+	}
+	return n
+}
+
+// owns returns true if the execution was received from the connection or session of the owner.
+func owns(owner Notifier, e *inFlightEntry) bool {
+	return owner != nil && e.owner == owner
+}
+
+// idString returns the text of a request ID, string IDs are returned without the quotes.
+func idString(id json.RawMessage) string {
+	var s string
+	if bytes.HasPrefix(id, []byte{'"'}) && json.Unmarshal(id, &s) == nil {
+		return s
+	}
+	return string(id)
+}
+
+// inFlightMethod is the method that lists the requests being executed.
+type inFlightMethod struct {
+	tracker *inFlightTracker
+	all     bool
+}
+
+// Execute will reply with the list of requests of the caller being executed, or of all the
+// callers with AdminScope.
+func (m *inFlightMethod) Execute(req *Request, resp *Response) {
+	resp.Result = m.tracker.listOwned(NotifierFromContext(req.Context()), m.all)
+}
+
+// InFlight returns the requests that are currently being executed, ordered by start time.
+//
+// Requests that already replied with a timeout are still listed until their method returns.
+func (m *Manager) InFlight() []InFlightRequest {
+	return m.inFlightTracker.list()
+}
+
+// CancelRequest will cancel the context of the requests being executed with the specified ID and
+// reply to them with a cancellation error. String IDs are matched without the quotes.
+//
+// It returns false if there is no request being executed with that ID.
+func (m *Manager) CancelRequest(id string) bool {
+	return m.inFlightTracker.cancel(id) > 0
+}
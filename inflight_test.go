@@ -0,0 +1,151 @@
+package jrpc2go_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+type waitMethod struct {
+	started chan struct{}
+}
+
+func (m *waitMethod) Execute(req *jrpc.Request, resp *jrpc.Response) {
+	m.started <- struct{}{}
+	<-req.Context().Done()
+}
+
+func TestManager_InFlight(t *testing.T) {
+	wait := &waitMethod{started: make(chan struct{}, 1)}
+	m := jrpc.NewManagerBuilder().
+		EnableInFlight().
+		Enable(jrpc.AdminScope()).
+		Add("wait", wait).
+		Build()
+
+	done := make(chan string)
+	go func() {
+		w := &bytes.Buffer{}
+		_ = m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"wait","id":"req-1"}`), w)
+		done <- strings.TrimSpace(w.String())
+	}()
+	<-wait.started
+
+	w := &bytes.Buffer{}
+	if err := m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"rpc.inflight","id":2}`), w); err != nil {
+		t.Fatalf("Manager.Handle() error = %v", err)
+	}
+
+	var resp struct {
+		Result []jrpc.InFlightRequest `json:"result"`
+	}
+	if err := json.Unmarshal(w.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(resp.Result) != 2 || resp.Result[0].Method != "wait" || string(*resp.Result[0].ID) != `"req-1"` {
+		t.Errorf("rpc.inflight result = %s", w.String())
+	}
+
+	if m.CancelRequest("unknown") {
+		t.Errorf("Manager.CancelRequest() = true, want false for unknown ID")
+	}
+	if !m.CancelRequest("req-1") {
+		t.Errorf("Manager.CancelRequest() = false, want true")
+	}
+
+	want := `{"jsonrpc":"2.0","id":"req-1","error":{"code":-32004,"message":"Method execution canceled","data":"canceled by the server"}}`
+	select {
+	case got := <-done:
+		if got != want {
+			t.Errorf("Manager.Handle() result = %v, want %v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Manager.CancelRequest() didn't cancel the request")
+	}
+}
+
+func TestManager_InFlight_Scoped(t *testing.T) {
+	wait := &waitMethod{started: make(chan struct{}, 1)}
+	m := jrpc.NewManagerBuilder().
+		Enable(jrpc.Introspection(), jrpc.Cancellation()).
+		Add("wait", wait).
+		Build()
+	srv, owner := startServer(t, &m)
+	defer srv.Shutdown(context.Background())
+	defer owner.Close()
+	other, err := net.Dial("tcp", owner.RemoteAddr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() error = %v", err)
+	}
+	defer other.Close()
+
+	call := func(c net.Conn, r *bufio.Reader, req string) string {
+		t.Helper()
+		_ = c.SetDeadline(time.Now().Add(time.Second))
+		if _, err := c.Write([]byte(req + "\n")); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		got, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("ReadString() error = %v", err)
+		}
+		return strings.TrimSpace(got)
+	}
+	ownerR, otherR := bufio.NewReader(owner), bufio.NewReader(other)
+
+	if _, err := owner.Write([]byte(`{"jsonrpc":"2.0","method":"wait","id":1}` + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	<-wait.started
+
+	tests := []struct {
+		name string
+		conn net.Conn
+		r    *bufio.Reader
+		req  string
+		want string
+	}{
+		{name: "Other Cancels None", conn: other, r: otherR, req: `{"jsonrpc":"2.0","method":"rpc.cancel","params":{"id":1},"id":3}`,
+			want: `{"jsonrpc":"2.0","id":3,"result":false}`},
+	}
+	for _, tt := range tests {
+		if got := call(tt.conn, tt.r, tt.req); got != tt.want {
+			t.Errorf("%v: response = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// The other connection only sees its own rpc.inflight request
+	if got := call(other, otherR, `{"jsonrpc":"2.0","method":"rpc.inflight","id":2}`); strings.Contains(got, `"wait"`) || !strings.Contains(got, `"rpc.inflight"`) {
+		t.Errorf("rpc.inflight of the other connection = %v, want only its own request", got)
+	}
+
+	// The owner cancels its request, the response of the wait can come first
+	_ = owner.SetDeadline(time.Now().Add(time.Second))
+	if _, err := owner.Write([]byte(`{"jsonrpc":"2.0","method":"rpc.cancel","params":{"id":1},"id":4}` + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	var got []string
+	for i := 0; i < 2; i++ {
+		line, err := ownerR.ReadString('\n')
+		if err != nil {
+			t.Fatalf("ReadString() error = %v", err)
+		}
+		got = append(got, strings.TrimSpace(line))
+	}
+	sort.Strings(got)
+	want := []string{
+		`{"jsonrpc":"2.0","id":1,"error":{"code":-32004,"message":"Method execution canceled","data":"canceled by the server"}}`,
+		`{"jsonrpc":"2.0","id":4,"result":true}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("owner responses = %v, want %v", got, want)
+	}
+}
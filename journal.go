@@ -0,0 +1,200 @@
+package jrpc2go
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JournalEntry is a request message recorded on the Journal and not completed yet.
+type JournalEntry struct {
+	ID      string        `json:"id"`
+	Time    time.Time     `json:"time"`
+	Message *QueueMessage `json:"message"`
+}
+
+// Journal is a write-ahead log of the request messages, they are recorded before the execution
+// and marked as complete after the response is sent, so the ones interrupted by a crash can be
+// executed again with QueueTransport.Recover.
+type Journal interface {
+	// Begin records the message before its execution and returns the ID of the entry.
+	Begin(msg *QueueMessage) (string, error)
+	// Complete marks the entry as processed.
+	Complete(id string) error
+	// Incomplete returns the entries not completed, in the order they were recorded.
+	Incomplete() ([]JournalEntry, error)
+}
+
+// MemoryJournal is a Journal kept in memory, it doesn't survive a crash of the process so it's
+// only useful for tests or to recover from a restart of the transport.
+type MemoryJournal struct {
+	mu      sync.Mutex
+	entries journalEntries
+}
+
+// NewMemoryJournal returns an empty MemoryJournal.
+func NewMemoryJournal() *MemoryJournal {
+	return &MemoryJournal{}
+}
+
+// Begin records the message and returns the ID of the entry.
+func (j *MemoryJournal) Begin(msg *QueueMessage) (string, error) {
+	e := JournalEntry{ID: newCorrelationID(), Time: time.Now(), Message: msg}
+	j.mu.Lock()
+	j.entries.add(e)
+	j.mu.Unlock()
+	return e.ID, nil
+}
+
+// Complete removes the entry.
+func (j *MemoryJournal) Complete(id string) error {
+	j.mu.Lock()
+	j.entries.remove(id)
+	j.mu.Unlock()
+	return nil
+}
+
+// Incomplete returns the entries not completed, in the order they were recorded.
+func (j *MemoryJournal) Incomplete() ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.entries.list(), nil
+}
+
+// FileJournal is a Journal on an append-only file synced on each record, the file is truncated
+// when all the entries are complete so it doesn't grow forever.
+type FileJournal struct {
+	mu      sync.Mutex
+	f       *os.File
+	entries journalEntries
+}
+
+// journalRecord is a line of the journal file.
+type journalRecord struct {
+	Op    string        `json:"op"`
+	Entry *JournalEntry `json:"entry,omitempty"`
+	ID    string        `json:"id,omitempty"`
+}
+
+// NewFileJournal opens or creates the journal file at path and loads the incomplete entries.
+func NewFileJournal(path string) (*FileJournal, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	j := &FileJournal{f: f}
+
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for s.Scan() {
+		var rec journalRecord
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			// A partial line of a crash while writing, the entry was never executed
+			continue
+		}
+		switch {
+		case rec.Op == "begin" && rec.Entry != nil:
+			j.entries.add(*rec.Entry)
+		case rec.Op == "complete":
+			j.entries.remove(rec.ID)
+		}
+	}
+	if err := s.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// Begin appends the message to the file and returns the ID of the entry.
+func (j *FileJournal) Begin(msg *QueueMessage) (string, error) {
+	e := JournalEntry{ID: newCorrelationID(), Time: time.Now(), Message: msg}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.write(journalRecord{Op: "begin", Entry: &e}); err != nil {
+		return "", err
+	}
+	j.entries.add(e)
+	return e.ID, nil
+}
+
+// Complete marks the entry as processed, the file is truncated if there are no entries left.
+func (j *FileJournal) Complete(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries.remove(id)
+	if len(j.entries.m) == 0 {
+		if err := j.f.Truncate(0); err != nil {
+			return err
+		}
+		return j.f.Sync()
+	}
+	return j.write(journalRecord{Op: "complete", ID: id})
+}
+
+// Incomplete returns the entries not completed, in the order they were recorded.
+func (j *FileJournal) Incomplete() ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.entries.list(), nil
+}
+
+// Close closes the file.
+func (j *FileJournal) Close() error {
+	return j.f.Close()
+}
+
+// write appends the record to the file and syncs it, j.mu must be held.
+func (j *FileJournal) write(rec journalRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := j.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+// journalEntries keeps the incomplete entries with the order they were recorded.
+type journalEntries struct {
+	seq uint64
+	m   map[string]journalSlot
+}
+
+// journalSlot is an entry and its position.
+type journalSlot struct {
+	seq   uint64
+	entry JournalEntry
+}
+
+// add will keep the entry after the previous ones.
+func (je *journalEntries) add(e JournalEntry) {
+	if je.m == nil {
+		je.m = make(map[string]journalSlot)
+	}
+	je.seq++
+	je.m[e.ID] = journalSlot{seq: je.seq, entry: e}
+}
+
+// remove will discard the entry with the id.
+func (je *journalEntries) remove(id string) {
+	delete(je.m, id)
+}
+
+// list returns the entries in the order they were added.
+func (je *journalEntries) list() []JournalEntry {
+	slots := make([]journalSlot, 0, len(je.m))
+	for _, s := range je.m {
+		slots = append(slots, s)
+	}
+	sort.Slice(slots, func(a, b int) bool { return slots[a].seq < slots[b].seq })
+	entries := make([]JournalEntry, len(slots))
+	for i, s := range slots {
+		entries[i] = s.entry
+	}
+	return entries
+}
@@ -0,0 +1,134 @@
+package jrpc2go_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestFileJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "requests.journal")
+
+	j, err := jrpc.NewFileJournal(path)
+	if err != nil {
+		t.Fatalf("NewFileJournal() error = %v", err)
+	}
+	var ids []string
+	for _, v := range []string{"a", "b", "c"} {
+		id, err := j.Begin(&jrpc.QueueMessage{Value: []byte(v)})
+		if err != nil {
+			t.Fatalf("FileJournal.Begin() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := j.Complete(ids[1]); err != nil {
+		t.Fatalf("FileJournal.Complete() error = %v", err)
+	}
+	j.Close()
+
+	// Reopened after a crash
+	j, err = jrpc.NewFileJournal(path)
+	if err != nil {
+		t.Fatalf("NewFileJournal() error = %v", err)
+	}
+	defer j.Close()
+	entries, _ := j.Incomplete()
+	if len(entries) != 2 || string(entries[0].Message.Value) != "a" || string(entries[1].Message.Value) != "c" {
+		t.Fatalf("FileJournal.Incomplete() = %+v, want a and c", entries)
+	}
+
+	for _, e := range entries {
+		if err := j.Complete(e.ID); err != nil {
+			t.Fatalf("FileJournal.Complete() error = %v", err)
+		}
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Errorf("journal file = %v, %v, want it truncated", fi, err)
+	}
+}
+
+func TestQueueTransport_Recover(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Build()
+
+	j := jrpc.NewMemoryJournal()
+	if _, err := j.Begin(&jrpc.QueueMessage{
+		Value:   []byte(`{"jsonrpc":"2.0","method":"add","id":1,"params":{"v1":1,"v2":2}}`),
+		Headers: map[string]string{jrpc.CorrelationHeader: "c1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	q := newMemQueue()
+	q.sent = make(chan *jrpc.QueueMessage, 1)
+	qt := jrpc.NewQueueTransport(&m, q, q, jrpc.WithJournal(j), jrpc.WithReplyTopic("replies"))
+	if err := qt.Recover(context.Background()); err != nil {
+		t.Fatalf("QueueTransport.Recover() error = %v", err)
+	}
+
+	r := <-q.sent
+	if r.Headers[jrpc.CorrelationHeader] != "c1" || strings.TrimSpace(string(r.Value)) != `{"jsonrpc":"2.0","id":1,"result":3}` {
+		t.Errorf("recovered response = %+v", r)
+	}
+	if entries, _ := j.Incomplete(); len(entries) != 0 {
+		t.Errorf("Journal.Incomplete() = %v, want none", entries)
+	}
+	if len(q.acked) != 0 {
+		t.Errorf("acknowledged messages = %v, want none", q.acked)
+	}
+}
+
+func TestQueueTransport_WithJournal_Canceled(t *testing.T) {
+	started := make(chan struct{}, 2)
+	m := jrpc.NewManagerBuilder().
+		Add("slow", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			started <- struct{}{}
+			<-req.Context().Done()
+		})).
+		Build()
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Request", body: `{"jsonrpc":"2.0","method":"slow","id":1}`},
+		{name: "Notification", body: `{"jsonrpc":"2.0","method":"slow"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := jrpc.NewMemoryJournal()
+			q := newMemQueue(&jrpc.QueueMessage{
+				Value:   []byte(tt.body),
+				Headers: map[string]string{jrpc.CorrelationHeader: "c1"},
+			})
+			qt := jrpc.NewQueueTransport(&m, q, q, jrpc.WithJournal(j))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() { done <- qt.Serve(ctx) }()
+			<-started
+			cancel()
+			<-done
+
+			// The call was interrupted so it's executed again by Recover
+			entries, err := j.Incomplete()
+			if err != nil || len(entries) != 1 || string(entries[0].Message.Value) != tt.body {
+				t.Errorf("Journal.Incomplete() = %v, %v, want the interrupted message", entries, err)
+			}
+			if len(q.acked) != 0 {
+				t.Errorf("acknowledged messages = %v, want none", q.acked)
+			}
+		})
+	}
+}
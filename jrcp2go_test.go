@@ -1,7 +1,9 @@
 package jrpc2go_test
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"testing"
 
 	jrpc "github.com/fabiodcorreia/jrpc2go"
@@ -83,3 +85,201 @@ func TestRequest_ParseParams(t *testing.T) {
 		})
 	}
 }
+
+type shapeParams struct {
+	Area float64
+}
+
+func (p *shapeParams) UnmarshalParams(ctx context.Context, raw json.RawMessage) error {
+	var kind struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(raw, &kind); err != nil {
+		return err
+	}
+	switch kind.Kind {
+	case "square":
+		var s struct {
+			Side float64 `json:"side"`
+		}
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		p.Area = s.Side * s.Side
+	case "rect":
+		var r struct {
+			Width  float64 `json:"width"`
+			Height float64 `json:"height"`
+		}
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return err
+		}
+		p.Area = r.Width * r.Height
+	case "forbidden":
+		return &jrpc.Error{Code: 1, Message: "forbidden"}
+	default:
+		return fmt.Errorf("unknown kind %q", kind.Kind)
+	}
+	return nil
+}
+
+func TestRequest_ParseParams_Unmarshaler(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      string
+		want        float64
+		wantErrCode jrpc.ErrorCode
+		wantErrData interface{}
+	}{
+		{name: "Square", params: `{"kind":"square","side":3}`, want: 9},
+		{name: "Rect", params: `{"kind":"rect","width":2,"height":5}`, want: 10},
+		{name: "Unknown Kind", params: `{"kind":"circle"}`, wantErrCode: -32602, wantErrData: `unknown kind "circle"`},
+		{name: "Custom Error", params: `{"kind":"forbidden"}`, wantErrCode: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := json.RawMessage(tt.params)
+			r := &jrpc.Request{Params: &raw}
+
+			var p shapeParams
+			err := r.ParseParams(&p)
+			if tt.wantErrCode != 0 {
+				if err == nil || err.Code != tt.wantErrCode || (tt.wantErrData != nil && err.Data != tt.wantErrData) {
+					t.Errorf("Request.ParseParams() error = %v, want code %v data %v", err, tt.wantErrCode, tt.wantErrData)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Request.ParseParams() error = %v", err)
+			}
+			if p.Area != tt.want {
+				t.Errorf("Request.ParseParams() area = %v, want %v", p.Area, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequest_ParseParamsCtx(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      string
+		opts        []jrpc.ParseOption
+		want        interface{}
+		wantErrData interface{}
+	}{
+		{name: "Unknown Fields Allowed", params: `{"V1":1,"V3":3}`, want: float64(1)},
+		{name: "Unknown Fields Rejected", params: `{"V1":1,"V3":3}`, opts: []jrpc.ParseOption{jrpc.DisallowUnknownFields()}, wantErrData: `json: unknown field "V3"`},
+		{name: "Use Number", params: `{"V1":9007199254740993}`, opts: []jrpc.ParseOption{jrpc.UseNumber()}, want: json.Number("9007199254740993")},
+		{name: "Within Max Size", params: `{"V1":1}`, opts: []jrpc.ParseOption{jrpc.MaxParamsSize(8)}, want: float64(1)},
+		{name: "Over Max Size", params: `{"V1":10}`, opts: []jrpc.ParseOption{jrpc.MaxParamsSize(8)}, wantErrData: "params larger than 8 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := json.RawMessage(tt.params)
+			r := &jrpc.Request{Params: &raw}
+
+			var p struct {
+				V1 interface{}
+			}
+			err := r.ParseParamsCtx(context.Background(), &p, tt.opts...)
+			if tt.wantErrData != nil {
+				if err == nil || err.Code != -32602 || err.Data != tt.wantErrData {
+					t.Errorf("Request.ParseParamsCtx() error = %v, want data %v", err, tt.wantErrData)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Request.ParseParamsCtx() error = %v", err)
+			}
+			if p.V1 != tt.want {
+				t.Errorf("Request.ParseParamsCtx() V1 = %#v, want %#v", p.V1, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequest_IDValue(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		want       interface{}
+		wantErr    error
+		wantString string
+	}{
+		{name: "Notification", want: nil},
+		{name: "String", id: `"a\"b"`, want: `a"b`, wantString: `a"b`},
+		{name: "Number", id: `9007199254740993`, want: json.Number("9007199254740993"), wantString: "9007199254740993"},
+		{name: "Object", id: `{"a":1}`, wantErr: jrpc.ErrInvalidID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &jrpc.Request{}
+			if tt.id != "" {
+				raw := json.RawMessage(tt.id)
+				r.ID = &raw
+			}
+			// The copies with another context have the same ID
+			r = r.WithContext(context.Background())
+
+			if got := r.IsNotification(); got != (tt.id == "") {
+				t.Errorf("Request.IsNotification() = %v, want %v", got, tt.id == "")
+			}
+			got, err := r.IDValue()
+			if err != tt.wantErr || got != tt.want {
+				t.Errorf("Request.IDValue() = %#v, %v, want %#v, %v", got, err, tt.want, tt.wantErr)
+			}
+			if tt.wantString != "" {
+				if s := r.MustIDString(); s != tt.wantString {
+					t.Errorf("Request.MustIDString() = %v, want %v", s, tt.wantString)
+				}
+			}
+		})
+	}
+}
+
+func TestResponse_SetResult_SetError(t *testing.T) {
+	id := json.RawMessage(`1`)
+	tests := []struct {
+		name string
+		set  func(r *jrpc.Response)
+		want string
+	}{
+		{
+			name: "Error After Result",
+			set: func(r *jrpc.Response) {
+				r.SetResult("ok")
+				r.SetError(&jrpc.Error{Code: 1, Message: "failed"})
+			},
+			want: `{"jsonrpc":"2.0","id":1,"error":{"code":1,"message":"failed"}}`,
+		},
+		{
+			name: "Result After Error",
+			set: func(r *jrpc.Response) {
+				r.SetError(&jrpc.Error{Code: 1, Message: "failed"})
+				r.SetResult("ok")
+			},
+			want: `{"jsonrpc":"2.0","id":1,"result":"ok"}`,
+		},
+		{
+			name: "Both Fields Set",
+			set: func(r *jrpc.Response) {
+				r.Result = "ok"
+				r.Error = &jrpc.Error{Code: 1, Message: "failed"}
+			},
+			want: `{"jsonrpc":"2.0","id":1,"error":{"code":1,"message":"failed"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &jrpc.Response{Version: "2.0", ID: &id}
+			tt.set(r)
+			b, err := json.Marshal(r)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("json.Marshal() = %s, want %s", b, tt.want)
+			}
+		})
+	}
+}
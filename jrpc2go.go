@@ -2,10 +2,14 @@ package jrpc2go
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"sync"
 )
 
 // JSON RPC Specification: https://www.jsonrpc.org/specification#notification
@@ -13,6 +17,9 @@ import (
 // version represents the JSON RPC version supported.
 const version = "2.0"
 
+// PluginSymbol is the name of the constructor that plugins loaded with LoadPlugin must export.
+const PluginSymbol = "NewMethods"
+
 // jsonArrayChar is the char used on JSON to identifiy the start of an array.
 const jsonArrayCharCode = 91
 
@@ -33,21 +40,111 @@ type Request struct {
 	ID      *json.RawMessage `json:"id,omitempty"`
 	Params  *json.RawMessage `json:"params,omitempty"`
 	ctx     context.Context
+	values  *values
+}
+
+// Unmarshaler is implemented by the params that decode themselves from the raw params, like
+// polymorphic params that select the type from a "kind" field.
+//
+// An *Error returned is replied as it is, any other error is replied as ErrInvalidParams.
+type Unmarshaler interface {
+	UnmarshalParams(ctx context.Context, raw json.RawMessage) error
 }
 
 // ParseParams will get the params from the request and and stores the result in the value pointed to by v.
 //
+// If v implements Unmarshaler it receives the raw params and the request context.
+//
 // Request.Params is optional but if we are calling the function they need to be there otherwise returns
 // ErrInvalidParams.
 func (r *Request) ParseParams(v interface{}) *Error {
+	return r.parseParams(nil, v)
+}
+
+// ParseOption configures how the params are decoded by Request.ParseParamsCtx.
+type ParseOption func(*parseOptions)
+
+// parseOptions keeps the decoding options of one ParseParamsCtx call.
+type parseOptions struct {
+	disallowUnknown bool
+	useNumber       bool
+	maxSize         int
+}
+
+// DisallowUnknownFields rejects the params with object keys that don't match any field of the
+// destination struct.
+func DisallowUnknownFields() ParseOption {
+	return func(o *parseOptions) {
+		o.disallowUnknown = true
+	}
+}
+
+// UseNumber decodes the numbers into an interface{} as a json.Number instead of a float64, so
+// the big integers don't lose precision.
+func UseNumber() ParseOption {
+	return func(o *parseOptions) {
+		o.useNumber = true
+	}
+}
+
+// MaxParamsSize rejects the params larger than n bytes without decoding them.
+func MaxParamsSize(n int) ParseOption {
+	return func(o *parseOptions) {
+		o.maxSize = n
+	}
+}
+
+// ParseParamsCtx is like ParseParams with the decoding options of the call, so a method can opt
+// into stricter decoding. The ctx is the one given to an Unmarshaler, which ignores the options
+// other than MaxParamsSize.
+func (r *Request) ParseParamsCtx(ctx context.Context, v interface{}, opts ...ParseOption) *Error {
+	return r.parseParams(ctx, v, opts...)
+}
+
+// parseParams will decode the params into v, the request context is used when ctx is nil.
+func (r *Request) parseParams(ctx context.Context, v interface{}, opts ...ParseOption) *Error {
 	if v == nil {
 		return newError(errCodeInvalidParams, "v can't be nil to parse request parameters")
 	}
 	if r.Params == nil {
 		return newError(errCodeInvalidParams, "request doesn't have params")
 	}
-	if err := json.Unmarshal(*r.Params, &v); err != nil {
-		return newError(errCodeInvalidParams, err)
+	var o parseOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.maxSize > 0 && len(*r.Params) > o.maxSize {
+		return newError(errCodeInvalidParams, fmt.Sprintf("params larger than %d bytes", o.maxSize))
+	}
+	if u, ok := v.(Unmarshaler); ok {
+		if ctx == nil {
+			ctx = r.Context()
+		}
+		if err := u.UnmarshalParams(ctx, *r.Params); err != nil {
+			var e *Error
+			if errors.As(err, &e) {
+				return e
+			}
+			return newError(errCodeInvalidParams, err.Error())
+		}
+		return nil
+	}
+	if !o.disallowUnknown && !o.useNumber {
+		if err := json.Unmarshal(*r.Params, &v); err != nil {
+			return newError(errCodeInvalidParams, err)
+		}
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(*r.Params))
+	if o.disallowUnknown {
+		dec.DisallowUnknownFields()
+	}
+	if o.useNumber {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(&v); err != nil {
+		// The errors of DisallowUnknownFields are plain errors, the message is kept as data
+		return newError(errCodeInvalidParams, err.Error())
 	}
 	return nil
 }
@@ -74,6 +171,51 @@ func (r *Request) WithContext(ctx context.Context) *Request {
 	return r2
 }
 
+// ErrInvalidID is returned when the ID of a request is not a String, a Number or Null.
+var ErrInvalidID = errors.New("jsonrpc: invalid request id")
+
+// IsNotification returns true if the request doesn't have an ID, so the server must not reply.
+// A null ID is decoded as no ID.
+func (r *Request) IsNotification() bool {
+	return r.ID == nil
+}
+
+// IDValue returns the decoded ID of the request, a string, a json.Number or nil for the
+// notifications. It returns ErrInvalidID if the ID is of other JSON type.
+func (r *Request) IDValue() (interface{}, error) {
+	if r.ID == nil {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(*r.ID))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, ErrInvalidID
+	}
+	switch v.(type) {
+	case nil, string, json.Number:
+		return v, nil
+	}
+	return nil, ErrInvalidID
+}
+
+// MustIDString returns the ID of the request as text, the strings without the quotes and the
+// numbers as they were sent. It panics if the request is a notification or the ID is not valid,
+// so it should only be used by methods that are never called as notifications.
+func (r *Request) MustIDString() string {
+	v, err := r.IDValue()
+	if err != nil {
+		panic(err)
+	}
+	switch id := v.(type) {
+	case string:
+		return id
+	case json.Number:
+		return id.String()
+	}
+	panic("jsonrpc: request is a notification")
+}
+
 // Response represents a JSON-RPC response from the server and containers the following.
 //
 // Version - A String specifying the version of the JSON-RPC protocol. MUST be exactly "2.0".
@@ -88,6 +230,37 @@ type Response struct {
 	Error   *Error           `json:"error,omitempty"`
 }
 
+// SetResult sets the result of the response and clears the error, so the response is a success.
+func (r *Response) SetResult(v interface{}) {
+	r.Result = v
+	r.Error = nil
+}
+
+// SetError sets the error of the response and clears the result, so the response is a failure.
+func (r *Response) SetError(e *Error) {
+	r.Error = e
+	r.Result = nil
+}
+
+// response has the fields of Response without its MarshalJSON.
+type response Response
+
+// MarshalJSON encodes the response with only the error when both the result and the error are
+// set, since a response can't have both.
+func (r Response) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		r.Result = nil
+	}
+	// The HTML chars are escaped, or not, by the encoder of the response
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(response(r)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
 // newResponse create a Response value from a Request value.
 func newResponse(r *Request) *Response {
 	return &Response{
@@ -104,38 +277,161 @@ type Method interface {
 	Execute(req *Request, resp *Response)
 }
 
-// parseMethodRequest will receive data from a Reader and convert into a Request value.
+// requestDecoder decodes the requests from a Reader one at a time, so the elements of a batch
+// can be executed as they are decoded instead of keeping the whole batch in memory.
+type requestDecoder struct {
+	br    *bufio.Reader
+	dec   *json.Decoder
+	batch bool
+	done  bool
+}
+
+// readerPool keeps the buffered readers of the request decoders. The requests don't reference
+// the buffer of the reader, the elements are copied by the json.Decoder, so it's reused as soon
+// as the decoder is closed even if a method is still running with its request.
+var readerPool = sync.Pool{New: func() interface{} { return bufio.NewReader(nil) }}
+
+// newRequestDecoder will receive data from a Reader and prepare the decoding of the requests,
+// the decoder must be closed once the requests are decoded.
+//
+// It will return an error for the following cases:
 //
-// It will return a slice of Requests (even if the reader only have one method request)
-// or an Error for the following cases:
+// - ErrCodeParseError Error if the Reader is empty.
 //
-// - ErrCodeParseError if fail to read from the Reader or to fetch the frist char of the content.
+// - ErrCodeInvalidRequest Error if the batch array can't be opened.
 //
-// - ErrCodeInvalidRequest if the JSON RPC request is not valid.
-func parseMethodRequest(r io.Reader) ([]*Request, *Error) {
-	br := bufio.NewReader(r)
+// - TransportError if fail to read from the Reader.
+func newRequestDecoder(r io.Reader) (*requestDecoder, error) {
+	br := readerPool.Get().(*bufio.Reader)
+	br.Reset(r)
+	d := &requestDecoder{br: br}
 
-	f, _, err := br.ReadRune()
+	f, err := firstRune(br)
+	if err == io.EOF {
+		d.close()
+		return nil, newError(errCodeParseError, emptyRequest)
+	}
 	if err != nil {
-		return nil, newError(errCodeParseError, fmt.Sprintf("fail to read the request text: %v", err))
+		d.close()
+		return nil, &TransportError{Op: "read", Err: err}
 	}
 
 	if err := br.UnreadRune(); err != nil {
-		return nil, newError(errCodeParseError, fmt.Sprintf("fail to read the request text: %v", err))
+		d.close()
+		return nil, &TransportError{Op: "read", Err: err}
 	}
 
-	var rs []*Request
-	if f != '[' {
-		var req *Request
-		if err := json.NewDecoder(br).Decode(&req); err != nil {
-			return nil, newError(errCodeInvalidRequest, err)
+	d.dec, d.batch = json.NewDecoder(br), f == jsonArrayCharCode
+	if d.batch {
+		if _, err := d.dec.Token(); err != nil {
+			d.close()
+			return nil, decodeError(err)
 		}
-		return append(rs, req), nil
 	}
+	return d, nil
+}
+
+// close will return the buffered reader to the pool, the decoder can't be used after it.
+func (d *requestDecoder) close() {
+	// The reader is released so the pool doesn't keep it alive
+	d.br.Reset(nil)
+	readerPool.Put(d.br)
+	d.br, d.dec = nil, nil
+}
 
-	if err := json.NewDecoder(br).Decode(&rs); err != nil {
-		return nil, newError(errCodeInvalidRequest, err)
+// firstRune returns the first rune of the content that is not whitespace or the byte order mark,
+// the whitespace and the byte order mark are discarded from br.
+func firstRune(br *bufio.Reader) (rune, error) {
+	for {
+		f, _, err := br.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		switch f {
+		case ' ', '\t', '\n', '\r', '\uFEFF':
+			continue
+		}
+		return f, nil
 	}
+}
 
-	return rs, nil
+// next returns the next request or io.EOF when there are no more requests, it returns an
+// ErrCodeParseError Error if the content is empty or truncated, an ErrCodeInvalidRequest
+// Error if the JSON RPC request is not valid and a TransportError if the read fails.
+//
+// An invalid element of a batch, like null or a number, doesn't stop the batch since the next
+// element can still be decoded, done is only set by the malformed JSON and the read errors.
+func (d *requestDecoder) next() (*Request, error) {
+	if d.done {
+		return nil, io.EOF
+	}
+
+	if !d.batch {
+		d.done = true
+		var raw json.RawMessage
+		if err := d.dec.Decode(&raw); err == io.EOF {
+			return nil, newError(errCodeParseError, emptyRequest)
+		} else if err != nil {
+			return nil, decodeError(err)
+		}
+		req, err := decodeRequest(raw)
+		if err != nil {
+			return nil, decodeError(err)
+		}
+		return req, nil
+	}
+
+	if !d.dec.More() {
+		d.done = true
+		if _, err := d.dec.Token(); err != nil {
+			return nil, decodeError(d.endOfBatch(err))
+		}
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		d.done = true
+		return nil, decodeError(d.endOfBatch(err))
+	}
+	req, err := decodeRequest(raw)
+	if err != nil {
+		return nil, decodeError(err)
+	}
+	return req, nil
 }
+
+// endOfBatch returns io.ErrUnexpectedEOF if the batch ended without the closing bracket or err
+// otherwise, since the decoder reports it as a syntax error.
+func (d *requestDecoder) endOfBatch(err error) error {
+	rest, _ := ioutil.ReadAll(d.dec.Buffered())
+	if _, perr := d.br.Peek(1); perr == io.EOF && len(bytes.TrimSpace(rest)) == 0 {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// emptyRequest is the data of the parse error returned when there is no request to decode.
+const emptyRequest = "empty request"
+
+// decodeError returns an ErrCodeParseError Error if the request ended before the JSON was
+// complete, an ErrCodeInvalidRequest Error if the JSON is not a valid request or a
+// TransportError if the read failed.
+func decodeError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return newError(errCodeParseError, "truncated request")
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var se *json.SyntaxError
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &se) || errors.As(err, &te) {
+		return newError(errCodeInvalidRequest, err.Error())
+	}
+	return &TransportError{Op: "read", Err: err}
+}
+
+// ProfilerLabel is the pprof label set to the method name when ManagerBuilder.EnableProfilerLabels is used.
+const ProfilerLabel = "jrpc.method"
@@ -0,0 +1,183 @@
+package jrpc2go
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrInvalidJWE is returned when a JWE can't be parsed or decrypted.
+var ErrInvalidJWE = errors.New("jsonrpc: invalid JWE")
+
+// KeyResolver returns the symmetric key used to encrypt and decrypt the JWE payloads, the key
+// length selects the content encryption: 16 bytes for A128GCM, 24 for A192GCM and 32 for A256GCM.
+type KeyResolver interface {
+	ResolveKey(ctx context.Context, kid string) ([]byte, error)
+}
+
+// KeyResolverFunc type is an adapter to allow the use of ordinary functions as a KeyResolver.
+type KeyResolverFunc func(ctx context.Context, kid string) ([]byte, error)
+
+// ResolveKey calls f(ctx, kid).
+func (f KeyResolverFunc) ResolveKey(ctx context.Context, kid string) ([]byte, error) {
+	return f(ctx, kid)
+}
+
+// jweHeader is the JWE protected header.
+type jweHeader struct {
+	Alg string `json:"alg"`
+	Enc string `json:"enc"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// jweEnc returns the content encryption algorithm name for the key.
+func jweEnc(key []byte) (string, error) {
+	switch len(key) {
+	case 16, 24, 32:
+		return fmt.Sprintf("A%dGCM", len(key)*8), nil
+	}
+	return "", fmt.Errorf("jsonrpc: JWE key must have 16, 24 or 32 bytes, got %d", len(key))
+}
+
+// EncryptJWE returns the plaintext encrypted as a JWE compact serialization using direct
+// encryption (alg "dir") with AES GCM, the kid is added to the header if not empty.
+func EncryptJWE(key []byte, kid string, plaintext []byte) (string, error) {
+	enc, err := jweEnc(key)
+	if err != nil {
+		return "", err
+	}
+	hb, err := json.Marshal(jweHeader{Alg: "dir", Enc: enc, Kid: kid})
+	if err != nil {
+		return "", err
+	}
+	header := base64.RawURLEncoding.EncodeToString(hb)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(header))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return strings.Join([]string{
+		header,
+		"",
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+// DecryptJWE returns the plaintext of a JWE compact serialization created with direct encryption
+// and AES GCM, the key is resolved by the kid of the header. It also returns the kid.
+func DecryptJWE(ctx context.Context, token string, kr KeyResolver) ([]byte, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 || parts[1] != "" {
+		return nil, "", ErrInvalidJWE
+	}
+
+	var h jweHeader
+	hb, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(hb, &h) != nil || h.Alg != "dir" {
+		return nil, "", ErrInvalidJWE
+	}
+
+	key, err := kr.ResolveKey(ctx, h.Kid)
+	if err != nil {
+		return nil, "", err
+	}
+	if enc, err := jweEnc(key); err != nil || enc != h.Enc {
+		return nil, "", ErrInvalidJWE
+	}
+
+	var raw [3][]byte
+	for i := range raw {
+		if raw[i], err = base64.RawURLEncoding.DecodeString(parts[i+2]); err != nil {
+			return nil, "", ErrInvalidJWE
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(raw[0]) != gcm.NonceSize() {
+		return nil, "", ErrInvalidJWE
+	}
+	sealed := append(append([]byte(nil), raw[1]...), raw[2]...)
+	plaintext, err := gcm.Open(nil, raw[0], sealed, []byte(parts[0]))
+	if err != nil {
+		return nil, "", ErrInvalidJWE
+	}
+	return plaintext, h.Kid, nil
+}
+
+// Encrypted returns a middleware where the params and the results are JWE encrypted, so they stay
+// confidential through logging proxies. The params must be a JSON string with a JWE compact
+// serialization, created with EncryptJWE, that is decrypted before the execution and the result
+// is encrypted with the same key.
+//
+// Params that can't be decrypted are rejected with an invalid params error, the errors are not
+// encrypted since they are part of the protocol.
+func Encrypted(kr KeyResolver) Middleware {
+	if kr == nil {
+		panic("jsonrpc: key resolver should not be nil")
+	}
+	return func(next Method) Method {
+		return MethodFunc(func(req *Request, resp *Response) {
+			var kid string
+			if req.Params != nil {
+				var token string
+				if err := json.Unmarshal(*req.Params, &token); err != nil {
+					resp.Error = newError(errCodeInvalidParams, "params must be a JWE compact serialization")
+					return
+				}
+				plaintext, k, err := DecryptJWE(req.Context(), token, kr)
+				if err != nil {
+					resp.Error = newError(errCodeInvalidParams, err.Error())
+					return
+				}
+				kid = k
+				params := json.RawMessage(plaintext)
+				req = req.WithContext(req.Context())
+				req.Params = &params
+			}
+
+			next.Execute(req, resp)
+			if resp.Error != nil || resp.Result == nil {
+				return
+			}
+
+			b, err := json.Marshal(resp.Result)
+			if err == nil {
+				var key []byte
+				if key, err = kr.ResolveKey(req.Context(), kid); err == nil {
+					resp.Result, err = EncryptJWE(key, kid, b)
+				}
+			}
+			if err != nil {
+				resp.SetError(newError(errCodeInternal, "fail to encrypt the result"))
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestEncrypted(t *testing.T) {
+	keys := map[string][]byte{
+		"k1": []byte("0123456789abcdef0123456789abcdef"),
+		"k2": []byte("0123456789abcdef"),
+	}
+	kr := jrpc.KeyResolverFunc(func(ctx context.Context, kid string) ([]byte, error) {
+		if k, ok := keys[kid]; ok {
+			return k, nil
+		}
+		return nil, errors.New("unknown key")
+	})
+
+	m := jrpc.NewManagerBuilder().
+		Use(jrpc.Encrypted(kr)).
+		Add("add", &addMethod{}).
+		Build()
+
+	tests := []struct {
+		name     string
+		kid      string
+		params   string
+		want     string
+		wantCode jrpc.ErrorCode
+	}{
+		{name: "AES 256", kid: "k1", want: "3"},
+		{name: "AES 128", kid: "k2", want: "3"},
+		{name: "Not Encrypted", params: `{"v1":1,"v2":2}`, wantCode: -32602},
+		{name: "Invalid JWE", params: `"a.b.c"`, wantCode: -32602},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.params
+			if params == "" {
+				token, err := jrpc.EncryptJWE(keys[tt.kid], tt.kid, []byte(`{"v1":1,"v2":2}`))
+				if err != nil {
+					t.Fatalf("EncryptJWE() error = %v", err)
+				}
+				params = `"` + token + `"`
+			}
+
+			w := &bytes.Buffer{}
+			r := `{"jsonrpc":"2.0","method":"add","id":1,"params":` + params + `}`
+			if err := m.Handle(context.Background(), strings.NewReader(r), w); err != nil {
+				t.Fatalf("Manager.Handle() error = %v", err)
+			}
+
+			var resp struct {
+				Result string      `json:"result"`
+				Error  *jrpc.Error `json:"error"`
+			}
+			if err := json.Unmarshal(w.Bytes(), &resp); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if tt.wantCode != 0 {
+				if resp.Error == nil || resp.Error.Code != tt.wantCode {
+					t.Errorf("Manager.Handle() result = %s, want error %d", w.String(), tt.wantCode)
+				}
+				return
+			}
+
+			got, kid, err := jrpc.DecryptJWE(context.Background(), resp.Result, kr)
+			if err != nil {
+				t.Fatalf("DecryptJWE() error = %v", err)
+			}
+			if string(got) != tt.want || kid != tt.kid {
+				t.Errorf("DecryptJWE() = %s, %s, want %s, %s", got, kid, tt.want, tt.kid)
+			}
+		})
+	}
+}
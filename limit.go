@@ -0,0 +1,41 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ErrCodeResponseTooLarge means the encoded result is over the maximum response size.
+const errCodeResponseTooLarge ErrorCode = -32006
+
+// ResponseTooLargeData is the error data of the results over the maximum response size.
+//
+// Size - The size in bytes of the encoded result.
+//
+// Limit - The maximum size in bytes configured on the Manager.
+type ResponseTooLargeData struct {
+	Size  int `json:"size"`
+	Limit int `json:"limit"`
+}
+
+// limitResponse will replace the result with an error if its encoding is over the maximum
+// response size, the result is kept encoded so it's not encoded twice.
+func (m *Manager) limitResponse(res *Response) {
+	if m.maxResponseSize <= 0 || res.Error != nil || res.Result == nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(m.encoder.escapeHTML)
+	if err := enc.Encode(res.Result); err != nil {
+		// The encoding error is reported when the response is written
+		return
+	}
+	b := bytes.TrimSuffix(buf.Bytes(), []byte{'\n'})
+	if len(b) <= m.maxResponseSize {
+		res.Result = json.RawMessage(b)
+		return
+	}
+	res.SetError(newError(errCodeResponseTooLarge, &ResponseTooLargeData{Size: len(b), Limit: m.maxResponseSize}))
+}
@@ -0,0 +1,83 @@
+package jrpc2go
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MessageCatalog keeps the localized error messages by locale and error code, like
+// {"pt": {-32601: "Método não encontrado"}}, the codes and the data of the errors don't change.
+type MessageCatalog map[string]map[ErrorCode]string
+
+// message returns the message of the code on the locale, falling back to the base language of
+// the locale, e.g. pt-BR falls back to pt.
+func (c MessageCatalog) message(locale string, code ErrorCode) (string, bool) {
+	for locale != "" {
+		if msg, ok := c[locale][code]; ok {
+			return msg, true
+		}
+		i := strings.LastIndexAny(locale, "-_")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return "", false
+}
+
+// localize will replace the error of res with a copy that has the message of the locale of
+// the context, if the catalog has it.
+func (m *Manager) localize(ctx context.Context, res *Response) {
+	if m.catalog == nil || res.Error == nil {
+		return
+	}
+	msg, ok := m.catalog.message(LocaleFromContext(ctx), res.Error.Code)
+	if !ok {
+		return
+	}
+	res.Error = &Error{Code: res.Error.Code, Message: msg, Data: res.Error.Data}
+}
+
+// ContextWithLocale returns a copy of ctx with the locale used to select the error messages from
+// the MessageCatalog, the HTTP handler sets it from the Accept-Language header.
+func ContextWithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey, locale)
+}
+
+// LocaleFromContext returns the locale of the request or empty if there is none.
+func LocaleFromContext(ctx context.Context) string {
+	l, _ := ctx.Value(localeKey).(string)
+	return l
+}
+
+// acceptLanguage returns the language with the highest quality of an Accept-Language header,
+// e.g. "fr-CH, fr;q=0.9, en;q=0.8" returns fr-CH. It returns empty if there is none.
+func acceptLanguage(header string) string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		t := tag{lang: strings.TrimSpace(fields[0]), q: 1}
+		for _, p := range fields[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					t.q = q
+				}
+			}
+		}
+		if t.lang != "" && t.lang != "*" && t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	if len(tags) == 0 {
+		return ""
+	}
+	return tags[0].lang
+}
@@ -0,0 +1,46 @@
+package jrpc2go_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestManagerBuilder_SetMessageCatalog(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		SetMessageCatalog(jrpc.MessageCatalog{
+			"pt":    {-32601: "Método não encontrado"},
+			"pt-PT": {-32601: "Método inexistente"},
+		}).
+		Build()
+	h := jrpc.HTTPHandleFunc(&m)
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		wantMessage    string
+	}{
+		{name: "No Language", acceptLanguage: "", wantMessage: "Method not found"},
+		{name: "Unknown Language", acceptLanguage: "fr", wantMessage: "Method not found"},
+		{name: "Base Language", acceptLanguage: "pt-BR", wantMessage: "Método não encontrado"},
+		{name: "Exact Locale", acceptLanguage: "pt-PT", wantMessage: "Método inexistente"},
+		{name: "Highest Quality", acceptLanguage: "en;q=0.5, pt-PT;q=0.9, *;q=0.1", wantMessage: "Método inexistente"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"none","id":1}`))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Accept-Language", tt.acceptLanguage)
+			w := httptest.NewRecorder()
+			h(w, r)
+
+			want := `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"` + tt.wantMessage + `","data":"none"}}` + "\n"
+			if w.Body.String() != want {
+				t.Errorf("HTTPHandleFunc() = %v, want %v", w.Body.String(), want)
+			}
+		})
+	}
+}
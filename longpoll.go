@@ -0,0 +1,162 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+)
+
+// SessionHeader is the HTTP header with the session ID of the long polling transport.
+const SessionHeader = "X-Session-ID"
+
+// LongPollOption configures the LongPoll transport.
+type LongPollOption func(*LongPoll)
+
+// WithPollTimeout sets how long a poll waits for notifications before replying with
+// 204 No Content. Default is 30 seconds.
+func WithPollTimeout(d time.Duration) LongPollOption {
+	return func(lp *LongPoll) {
+		lp.pollTimeout = d
+	}
+}
+
+// WithSessionTTL sets how long a session is kept without calls or polls. Default is 2 minutes.
+func WithSessionTTL(d time.Duration) LongPollOption {
+	return func(lp *LongPoll) {
+		lp.sessionTTL = d
+	}
+}
+
+// WithMaxPending sets the maximum number of notifications pending delivery per session,
+// the notifications over the limit are rejected with ErrSessionFull. Default is 100.
+func WithMaxPending(n int) LongPollOption {
+	return func(lp *LongPoll) {
+		lp.maxPending = n
+	}
+}
+
+// WithMaxSessions sets the maximum number of live sessions, the calls that would create a session
+// over the limit are rejected with 503 Service Unavailable. Default is 10000, 0 means no limit.
+func WithMaxSessions(n int) LongPollOption {
+	return func(lp *LongPoll) {
+		lp.maxSessions = n
+	}
+}
+
+// WithHTTPOptions sets the options of the handler used for the calls.
+func WithHTTPOptions(opts ...HTTPOption) LongPollOption {
+	return func(lp *LongPoll) {
+		lp.httpOpts = opts
+	}
+}
+
+// LongPoll is an HTTP transport where the clients post the calls and hold a GET request
+// to receive the server notifications, it's a fallback for networks where persistent
+// connections like WebSockets are blocked.
+//
+// A session is created on the first call without the X-Session-ID header and its ID is sent back
+// on that header, the following calls and polls must send it. A GET with the session ID replies
+// with a JSON array of the pending notifications as soon as there is one, or with 204 No Content
+// when the poll timeout expires. Unknown or expired sessions are rejected with 404 Not Found and
+// the calls are rejected with 503 Service Unavailable while the limit of sessions is reached.
+//
+// The methods send notifications to the session with the Notifier from NotifierFromContext.
+type LongPoll struct {
+	pollTimeout time.Duration
+	sessionTTL  time.Duration
+	maxPending  int
+	maxSessions int
+	httpOpts    []HTTPOption
+
+	m        *Manager
+	calls    *httpHandler
+	sessions *sessionRegistry
+}
+
+// NewLongPoll returns the long polling transport for the Manager.
+func NewLongPoll(m *Manager, opts ...LongPollOption) *LongPoll {
+	lp := &LongPoll{
+		pollTimeout: 30 * time.Second,
+		sessionTTL:  2 * time.Minute,
+		maxPending:  100,
+		maxSessions: 10000,
+		m:           m,
+	}
+	for _, opt := range opts {
+		opt(lp)
+	}
+	lp.calls = newHTTPHandler(m, lp.httpOpts...)
+	lp.sessions = newSessionRegistry(lp.sessionTTL, lp.maxPending, lp.maxSessions)
+	return lp
+}
+
+// Session returns the live session with the id.
+func (lp *LongPoll) Session(id string) (*Session, bool) {
+	return lp.sessions.get(id)
+}
+
+// Close will close all the sessions, the pending notifications are discarded.
+func (lp *LongPoll) Close() {
+	lp.sessions.closeAll()
+}
+
+// ServeHTTP will handle the calls and the polls of the sessions.
+func (lp *LongPoll) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		lp.serveCall(w, r)
+	case http.MethodGet:
+		lp.servePoll(w, r)
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// serveCall will handle the JSON RPC request with the session on the context.
+func (lp *LongPoll) serveCall(w http.ResponseWriter, r *http.Request) {
+	var s *Session
+	var ok bool
+	if id := r.Header.Get(SessionHeader); id != "" {
+		if s, ok = lp.sessions.get(id); !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	} else if s, ok = lp.sessions.create(); !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set(SessionHeader, s.ID())
+	ctx := withTransportInfo(contextWithNotifier(r.Context(), s), httpTransportInfo(r, "longpoll"))
+	lp.calls.serveHTTP(w, r.WithContext(ctx))
+}
+
+// servePoll will wait for the notifications of the session.
+func (lp *LongPoll) servePoll(w http.ResponseWriter, r *http.Request) {
+	s, ok := lp.sessions.get(r.Header.Get(SessionHeader))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), lp.pollTimeout)
+	defer cancel()
+
+	n := s.wait(ctx)
+	if len(n) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var out bytes.Buffer
+	if err := lp.m.encoder.encode(&out, n); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(contentTypeKey, contentTypeValue)
+	if _, err := w.Write(out.Bytes()); err != nil {
+		//TODO not sure what to do here
+	}
+}
@@ -0,0 +1,122 @@
+package jrpc2go_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+type notifyMethod struct{}
+
+func (m *notifyMethod) Execute(req *jrpc.Request, resp *jrpc.Response) {
+	n := jrpc.NotifierFromContext(req.Context())
+	if n == nil {
+		resp.Error = &jrpc.Error{Code: 1, Message: "notifications not supported"}
+		return
+	}
+	if err := n.Notify(context.Background(), "progress", 100); err != nil {
+		resp.Error = &jrpc.Error{Code: 2, Message: err.Error()}
+		return
+	}
+	resp.Result = "started"
+}
+
+func TestLongPoll(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("start", &notifyMethod{}).
+		Build()
+	lp := jrpc.NewLongPoll(&m, jrpc.WithPollTimeout(100*time.Millisecond))
+	defer lp.Close()
+
+	do := func(method, session, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/poll", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		if session != "" {
+			r.Header.Set(jrpc.SessionHeader, session)
+		}
+		w := httptest.NewRecorder()
+		lp.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(http.MethodPost, "", `{"jsonrpc":"2.0","method":"start","id":1}`)
+	session := w.Header().Get(jrpc.SessionHeader)
+	if w.Code != http.StatusOK || session == "" {
+		t.Fatalf("LongPoll call status = %d, session = %q", w.Code, session)
+	}
+	if want := `{"jsonrpc":"2.0","id":1,"result":"started"}`; strings.TrimSpace(w.Body.String()) != want {
+		t.Errorf("LongPoll call body = %v, want %v", w.Body.String(), want)
+	}
+
+	w = do(http.MethodGet, session, "")
+	if want := `[{"jsonrpc":"2.0","method":"progress","params":100}]`; w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != want {
+		t.Errorf("LongPoll poll status = %d, body = %v, want %v", w.Code, w.Body.String(), want)
+	}
+
+	if w = do(http.MethodGet, session, ""); w.Code != http.StatusNoContent {
+		t.Errorf("LongPoll empty poll status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if w = do(http.MethodGet, "unknown", ""); w.Code != http.StatusNotFound {
+		t.Errorf("LongPoll unknown session status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w = do(http.MethodPost, "unknown", `{"jsonrpc":"2.0","method":"start","id":1}`); w.Code != http.StatusNotFound {
+		t.Errorf("LongPoll unknown session call status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestLongPoll_TransportInfo(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("transport", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			info, _ := jrpc.TransportInfoFromContext(req.Context())
+			resp.Result = info.Kind + " " + info.Endpoint
+		})).
+		Build()
+	lp := jrpc.NewLongPoll(&m)
+	defer lp.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/poll", strings.NewReader(`{"jsonrpc":"2.0","method":"transport","id":1}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	lp.ServeHTTP(w, r)
+
+	if want := `{"jsonrpc":"2.0","id":1,"result":"longpoll http://example.com/poll"}`; strings.TrimSpace(w.Body.String()) != want {
+		t.Errorf("LongPoll call body = %v, want %v", w.Body.String(), want)
+	}
+}
+
+func TestLongPoll_WithMaxSessions(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("start", &notifyMethod{}).
+		Build()
+	lp := jrpc.NewLongPoll(&m, jrpc.WithMaxSessions(1))
+	defer lp.Close()
+
+	do := func(session string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/poll", strings.NewReader(`{"jsonrpc":"2.0","method":"start","id":1}`))
+		r.Header.Set("Content-Type", "application/json")
+		if session != "" {
+			r.Header.Set(jrpc.SessionHeader, session)
+		}
+		w := httptest.NewRecorder()
+		lp.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("")
+	session := w.Header().Get(jrpc.SessionHeader)
+	if w.Code != http.StatusOK || session == "" {
+		t.Fatalf("LongPoll call status = %d, session = %q", w.Code, session)
+	}
+	if w = do(""); w.Code != http.StatusServiceUnavailable || w.Header().Get(jrpc.SessionHeader) != "" {
+		t.Errorf("LongPoll call over the limit status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	// The live session is still served
+	if w = do(session); w.Code != http.StatusOK {
+		t.Errorf("LongPoll call with the session status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
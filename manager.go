@@ -3,22 +3,103 @@ package jrpc2go
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math/rand"
+	"path"
+	"reflect"
+	"regexp"
+	"runtime/pprof"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// settings keeps the configuration shared by the ManagerBuilder and the Manager that can
+// be overridden on derived managers.
+type settings struct {
+	timeout      time.Duration
+	batchTimeout time.Duration
+	maxInFlight  int64
+	retryAfter   time.Duration
+	middleware   []Middleware
+	redactor     Redactor
+	encoder      encoderConfig
+	catalog      MessageCatalog
+	clock        Clock
+	labels       bool
+
+	batchConcurrency int
+	unorderedBatch   bool
+	slowThreshold    time.Duration
+	slowFunc         func(RequestInfo)
+	accessLog        *accessLogger
+
+	echoCorrelationID bool
+	strictVersion     bool
+	versionCheck      VersionCheck
+	maxResponseSize   int
+	chunkSize         int
+	flags             FlagProvider
+	disabledCode      ErrorCode
+	panics            PanicReporter
+	costFuncs         []func(ctx context.Context, r CostReport)
+}
+
 // ManagerBuilder will support the Builder pattern for the Manager struct.
 type ManagerBuilder struct {
-	timeout time.Duration
-	methods map[string]Method
+	settings
+	inFlight    bool
+	ping        bool
+	captureSize int
+	stats       bool
+	expvar      string
+	methods     map[string]Method
+	patterns    []patternMethod
+	versions    map[string][]versionMethod
+	timeouts    map[string]time.Duration
+	infos       map[string]MethodInfo
+	deprecated  map[string]string
+	defaults    map[string]map[string]interface{}
+
+	// Set by the features enabled with Enable
+	introspection bool
+	health        bool
+	cancellation  bool
+	adminScope    bool
+
+	notificationWorkers int
+	notificationBudget  time.Duration
+	notificationFunc    func(RequestInfo)
+	memoryLimit         int64
+	eventFuncs          []func(ManagerEvent)
+	dedupWindow         time.Duration
+	dedupIdentity       func(ctx context.Context) string
+	dedupMethods        []string
+	quotaIdentity       func(ctx context.Context) string
+	quotaStore          QuotaStore
+	quotas              []Quota
 }
 
 // NewManagerBuilder will return a new builder for the Manager.
 func NewManagerBuilder() *ManagerBuilder {
 	return &ManagerBuilder{
-		timeout: 10 * time.Second,
-		methods: make(map[string]Method),
+		settings: settings{
+			timeout: 10 * time.Second,
+			clock:   systemClock{},
+			encoder: encoderConfig{
+				escapeHTML: true,
+				newline:    true,
+			},
+		},
+		methods:    make(map[string]Method),
+		versions:   make(map[string][]versionMethod),
+		timeouts:   make(map[string]time.Duration),
+		infos:      make(map[string]MethodInfo),
+		deprecated: make(map[string]string),
+		defaults:   make(map[string]map[string]interface{}),
 	}
 }
 
@@ -30,6 +111,256 @@ func (mb *ManagerBuilder) SetTimeout(timeout time.Duration) *ManagerBuilder {
 	return mb
 }
 
+// SetMethodTimeout allows to specify a custom timeout for the execution of one method, it takes
+// precedence over the timeout set with SetTimeout.
+func (mb *ManagerBuilder) SetMethodTimeout(name string, timeout time.Duration) *ManagerBuilder {
+	mb.timeouts[name] = timeout
+	return mb
+}
+
+// SetBatchTimeout allows to specify a deadline for the execution of a whole batch request.
+//
+// Once the deadline expires the responses already computed are returned and the remaining requests
+// reply with a timeout error that lists the requests that were not attempted.
+//
+// Default is 0, which means no batch deadline.
+func (mb *ManagerBuilder) SetBatchTimeout(timeout time.Duration) *ManagerBuilder {
+	mb.batchTimeout = timeout
+	return mb
+}
+
+// SetStrictVersion specifies whether the requests without the jsonrpc member set to "2.0" are
+// replied with the standard -32600 Invalid Request error, as the specification requires.
+//
+// Default is false for compatibility, the requests are replied with the -32001 error
+// "JSON RPC Version must be 2.0".
+func (mb *ManagerBuilder) SetStrictVersion(on bool) *ManagerBuilder {
+	mb.strictVersion = on
+	return mb
+}
+
+// SetBatchConcurrency allows to execute up to n requests of a batch at the same time.
+//
+// The responses keep the order of the requests unless AllowUnorderedBatch is used.
+//
+// Default is 1, which means the requests of a batch are executed one after the other.
+func (mb *ManagerBuilder) SetBatchConcurrency(n int) *ManagerBuilder {
+	mb.batchConcurrency = n
+	return mb
+}
+
+// AllowUnorderedBatch will reply the responses of a batch in the order their execution finished
+// instead of the order of the requests, for clients that correlate the responses by ID. It only
+// has effect with SetBatchConcurrency greater than 1.
+func (mb *ManagerBuilder) AllowUnorderedBatch() *ManagerBuilder {
+	mb.unorderedBatch = true
+	return mb
+}
+
+// SetClock allows to replace the source of time used to measure the executions and to expire
+// the timeouts, so tests can simulate the timeouts deterministically.
+//
+// Default is the system clock.
+func (mb *ManagerBuilder) SetClock(c Clock) *ManagerBuilder {
+	if c == nil {
+		c = systemClock{}
+	}
+	mb.clock = c
+	return mb
+}
+
+// SetMaxInFlight allows to limit the number of methods executing at the same time, once the limit
+// is reached the new requests are rejected with an overload error.
+//
+// Default is 0, which means no limit.
+func (mb *ManagerBuilder) SetMaxInFlight(n int) *ManagerBuilder {
+	mb.maxInFlight = int64(n)
+	return mb
+}
+
+// SetRetryAfter allows to specify the retry delay suggested to the clients on the timeout
+// and overload errors, the error data will also contain the number of methods executing.
+//
+// Default is 0, which means the errors don't carry any retry hint.
+func (mb *ManagerBuilder) SetRetryAfter(d time.Duration) *ManagerBuilder {
+	mb.retryAfter = d
+	return mb
+}
+
+// SetSlowRequestThreshold allows to specify the execution time from which a request is reported
+// as slow to fn, with the method, the params size and the elapsed time. When fn is nil the slow
+// requests are written to the standard logger.
+//
+// Default is 0, which means the slow requests are not reported.
+func (mb *ManagerBuilder) SetSlowRequestThreshold(d time.Duration, fn func(RequestInfo)) *ManagerBuilder {
+	mb.slowThreshold = d
+	mb.slowFunc = fn
+	return mb
+}
+
+// EchoCorrelationID will include the correlation ID of the call on the error responses, the
+// error data is replaced by a CorrelationData carrying the ID and the original data.
+//
+// The correlation ID is adopted from the context, see ContextWithCorrelationID, or from the
+// correlationId of the `_meta` member of the params, like `"params":{"_meta":{"correlationId":
+// "abc"}}`, or generated for each call and it's available to the methods with
+// CorrelationIDFromContext.
+func (mb *ManagerBuilder) EchoCorrelationID() *ManagerBuilder {
+	mb.echoCorrelationID = true
+	return mb
+}
+
+// EnableInFlight will register the method rpc.inflight that replies with the list of requests
+// being executed, with the method name, the request ID and the elapsed time. Only the requests of
+// the connection or session of the caller are listed, unless AdminScope is enabled.
+//
+// The same information and the cancellation of requests are always available from the
+// Manager.InFlight and Manager.CancelRequest functions.
+func (mb *ManagerBuilder) EnableInFlight() *ManagerBuilder {
+	mb.inFlight = true
+	return mb
+}
+
+// EnablePing will register the built-in method rpc.ping that replies with "pong", so the clients
+// can check the server is alive and measure the round-trip latency.
+func (mb *ManagerBuilder) EnablePing() *ManagerBuilder {
+	mb.ping = true
+	return mb
+}
+
+// EnableProfilerLabels will tag the goroutines executing the methods with the pprof label
+// jrpc.method set to the method name, so the CPU and block profiles can be broken down by method.
+func (mb *ManagerBuilder) EnableProfilerLabels() *ManagerBuilder {
+	mb.labels = true
+	return mb
+}
+
+// EnableStats will collect the requests and errors counters and the latency summary per method,
+// available from Manager.Stats.
+func (mb *ManagerBuilder) EnableStats() *ManagerBuilder {
+	mb.stats = true
+	return mb
+}
+
+// PublishExpvar will enable the stats and publish them on expvar under the names prefix+"requests",
+// prefix+"errors" and prefix+"methods", e.g. the prefix "rpc." publishes "rpc.requests".
+//
+// Build will panic if the names are already published, like expvar.Publish.
+func (mb *ManagerBuilder) PublishExpvar(prefix string) *ManagerBuilder {
+	mb.expvar = prefix
+	return mb
+}
+
+// SetCaptureSize allows to keep the n most recent requests and responses, redacted by the
+// Redactor, to be inspected on the DebugHandler.
+//
+// Default is 0, which means the requests are not captured.
+func (mb *ManagerBuilder) SetCaptureSize(n int) *ManagerBuilder {
+	mb.captureSize = n
+	return mb
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters should be escaped inside
+// JSON quoted strings of the responses, like `&` becoming `\u0026`.
+//
+// Default is true, the same as json.Encoder.
+func (mb *ManagerBuilder) SetEscapeHTML(on bool) *ManagerBuilder {
+	mb.encoder.escapeHTML = on
+	return mb
+}
+
+// SetIndent instructs the response encoder to format each response with the given prefix
+// and indent, it's mostly useful for debugging transports.
+//
+// Default is no indentation.
+func (mb *ManagerBuilder) SetIndent(prefix, indent string) *ManagerBuilder {
+	mb.encoder.prefix = prefix
+	mb.encoder.indent = indent
+	return mb
+}
+
+// SetTrailingNewline specifies whether each response should be terminated with a newline.
+//
+// Default is true, the same as json.Encoder.
+func (mb *ManagerBuilder) SetTrailingNewline(on bool) *ManagerBuilder {
+	mb.encoder.newline = on
+	return mb
+}
+
+// SetMaxResponseSize sets the maximum size in bytes of the encoded result of a method, a bigger
+// result is replaced by a Response too large error with the size and the limit as data, so a
+// method can't exhaust the memory or the frame limit of the transport.
+//
+// Default is 0, no limit.
+func (mb *ManagerBuilder) SetMaxResponseSize(n int) *ManagerBuilder {
+	mb.maxResponseSize = n
+	return mb
+}
+
+// EnableChunking will let the methods stream large results to the client as rpc.chunk
+// notifications of up to size bytes, written to the writer from ChunkWriter, the last chunk is
+// marked as done and it's followed by the response. The clients join the chunks with a
+// ChunkAssembler.
+//
+// It's only available on the transports that support server-initiated notifications.
+func (mb *ManagerBuilder) EnableChunking(size int) *ManagerBuilder {
+	if size <= 0 {
+		panic("jsonrpc: chunk size should be positive")
+	}
+	mb.chunkSize = size
+	return mb
+}
+
+// SetResultEncoder registers the function that encodes the results of the same type as sample,
+// so the responses of hot methods with small struct results are encoded without reflection.
+// The results of type string, bool, the integers and json.RawMessage are always encoded
+// without reflection.
+//
+// It's not used with SetIndent or, for the registered types, with SetSortKeys.
+func (mb *ManagerBuilder) SetResultEncoder(sample interface{}, fn ResultEncoderFunc) *ManagerBuilder {
+	if mb.encoder.results == nil {
+		mb.encoder.results = make(map[reflect.Type]ResultEncoderFunc)
+	}
+	mb.encoder.results[reflect.TypeOf(sample)] = fn
+	return mb
+}
+
+// SetSortKeys specifies whether the object keys of the results should be sorted, including
+// the fields of structs and raw JSON, so the responses are byte-stable across runs, which
+// golden-file tests and response caches need.
+//
+// Default is false, only the keys of maps are sorted, the same as json.Encoder.
+func (mb *ManagerBuilder) SetSortKeys(on bool) *ManagerBuilder {
+	mb.encoder.sortKeys = on
+	return mb
+}
+
+// SetMessageCatalog allows to localize the messages of the errors replied, the locale is selected
+// with ContextWithLocale or from the Accept-Language header on HTTP. The errors without a message
+// on the catalog for the locale keep the original message.
+//
+// Default is nil, which means the messages are not localized.
+func (mb *ManagerBuilder) SetMessageCatalog(c MessageCatalog) *ManagerBuilder {
+	mb.catalog = c
+	return mb
+}
+
+// SetRedactor allows to specify the Redactor applied to the params and results before they are
+// logged, audited or captured by the Manager.
+//
+// Default is nil, which means the values are not redacted.
+func (mb *ManagerBuilder) SetRedactor(r Redactor) *ManagerBuilder {
+	mb.redactor = r
+	return mb
+}
+
+// Use will append middleware to wrap the execution of every method, the first middleware
+// added is the outermost one.
+func (mb *ManagerBuilder) Use(mw ...Middleware) *ManagerBuilder {
+	mb.middleware = append(mb.middleware, mw...)
+	return mb
+}
+
 // Add will append a new method to the manager to be executed. the name should be unique
 // if the name name is used more then one time it will overwrite the handler of that method.
 //
@@ -44,114 +375,692 @@ func (mb *ManagerBuilder) Add(name string, h Method) *ManagerBuilder {
 	return mb
 }
 
+// AddVersion will append a new implementation of a method with the version, so multiple
+// implementations of one method can coexist during the rollout of a new one.
+//
+// Requests select a version with the method name suffix `name@version` or with a context created
+// with ContextWithVersion. Requests without a version are routed randomly to the versions with
+// weight greater than 0, proportionally to the weight. Methods added with Add take precedence
+// over the versions routed by weight.
+//
+// If the name or the version are empty, the weight is negative or the h is nil this function will panic.
+func (mb *ManagerBuilder) AddVersion(name, version string, weight int, h Method) *ManagerBuilder {
+	if name == "" || version == "" || weight < 0 || h == nil {
+		panic("jsonrpc: method name, version and function should not be empty")
+	}
+	vs := mb.versions[name]
+	for i := range vs {
+		if vs[i].version == version {
+			vs[i] = versionMethod{version: version, weight: weight, method: h}
+			return mb
+		}
+	}
+	mb.versions[name] = append(vs, versionMethod{version: version, weight: weight, method: h})
+	return mb
+}
+
+// AddMethods will append all the methods using the map keys as the method names, like the
+// methods loaded from a plugin with LoadPlugin.
+//
+// It follows the same rules as Add and panics if a name is empty or a method is nil.
+func (mb *ManagerBuilder) AddMethods(methods map[string]Method) *ManagerBuilder {
+	for name, h := range methods {
+		mb.Add(name, h)
+	}
+	return mb
+}
+
+// AddPattern will append a new method executed for every request with a method name that matches
+// the pattern, using the syntax of path.Match where `*` matches any sequence of characters except `/`.
+//
+// Methods added with Add take precedence over patterns and patterns are matched in the order they
+// were added. The method can get the matched name from Request.Method.
+//
+// If the pattern is malformed or the h is nil this function will panic.
+func (mb *ManagerBuilder) AddPattern(pattern string, h Method) *ManagerBuilder {
+	if _, err := path.Match(pattern, ""); err != nil || pattern == "" || h == nil {
+		panic("jsonrpc: method pattern should be valid and function should not be empty")
+	}
+	mb.patterns = append(mb.patterns, patternMethod{
+		name:   pattern,
+		match:  func(name string) bool { ok, _ := path.Match(pattern, name); return ok },
+		method: h,
+	})
+	return mb
+}
+
+// AddPrefix will append a new method executed for every request with a method name that starts
+// with the prefix, it follows the same precedence rules as AddPattern.
+//
+// If the prefix is empty or the h is nil this function will panic.
+func (mb *ManagerBuilder) AddPrefix(prefix string, h Method) *ManagerBuilder {
+	if prefix == "" || h == nil {
+		panic("jsonrpc: method prefix and function should not be empty")
+	}
+	mb.patterns = append(mb.patterns, patternMethod{
+		name:   prefix,
+		match:  func(name string) bool { return strings.HasPrefix(name, prefix) },
+		method: h,
+	})
+	return mb
+}
+
+// AddRegexp will append a new method executed for every request with a method name that matches
+// the regular expression, it follows the same precedence rules as AddPattern.
+//
+// If the re or the h is nil this function will panic.
+func (mb *ManagerBuilder) AddRegexp(re *regexp.Regexp, h Method) *ManagerBuilder {
+	if re == nil || h == nil {
+		panic("jsonrpc: method regexp and function should not be empty")
+	}
+	mb.patterns = append(mb.patterns, patternMethod{
+		name:   re.String(),
+		match:  re.MatchString,
+		method: h,
+	})
+	return mb
+}
+
 // Build will use the configuration collected during the build return a manager
 // with these configurations.
 func (mb *ManagerBuilder) Build() Manager {
-	return Manager{
-		methods: mb.methods,
-		timeout: mb.timeout,
+	tracker := newInFlightTracker(mb.clock)
+	if mb.inFlight {
+		mb.methods[inFlightMethodName] = &inFlightMethod{tracker: tracker, all: mb.adminScope}
+	}
+	if mb.ping {
+		mb.methods[pingMethodName] = pingMethod{}
+	}
+	var capture *captureRing
+	if mb.captureSize > 0 {
+		capture = newCaptureRing(mb.captureSize)
+	}
+	var stats *statsCollector
+	if mb.stats || mb.expvar != "" {
+		stats = newStatsCollector()
+	}
+	if mb.expvar != "" {
+		stats.publish(mb.expvar)
+	}
+	table := &methodTable{
+		methods:    mb.methods,
+		patterns:   mb.patterns,
+		versions:   mb.versions,
+		timeouts:   mb.timeouts,
+		infos:      mb.infos,
+		deprecated: mb.deprecated,
+		defaults:   mb.defaults,
+	}
+	mb.registerFeatures(table, tracker)
+	m := Manager{
+		settings:        mb.settings,
+		capture:         capture,
+		stats:           stats,
+		table:           table,
+		inFlightTracker: tracker,
+		notifications:   newNotificationPool(mb.notificationWorkers, mb.notificationBudget, mb.notificationFunc),
+		memory:          newMemoryBudget(mb.memoryLimit),
+		events:          newEventHub(mb.eventFuncs),
+		dedup:           newDedupCache(mb.dedupWindow, mb.dedupIdentity, mb.dedupMethods),
+		quota:           newQuotaEngine(mb.quotaIdentity, mb.quotaStore, mb.quotas),
 	}
+	m.emitRegistered()
+	return m
 }
 
 // Manager represent the JSON RPC method register manager.
 type Manager struct {
-	mu      sync.RWMutex
-	methods map[string]Method
-	timeout time.Duration
+	// inFlight is accessed atomically and it's the first field to keep it 64-bit aligned
+	inFlight int64
+	settings
+	table           *methodTable
+	inFlightTracker *inFlightTracker
+	capture         *captureRing
+	stats           *statsCollector
+	notifications   *notificationPool
+	memory          *memoryBudget
+	events          *eventHub
+	dedup           *dedupCache
+	quota           *quotaEngine
+}
+
+// methodTable keeps the registered methods, it's shared by the Manager and its derived managers.
+type methodTable struct {
+	mu         sync.RWMutex
+	methods    map[string]Method
+	patterns   []patternMethod
+	versions   map[string][]versionMethod
+	timeouts   map[string]time.Duration
+	infos      map[string]MethodInfo
+	deprecated map[string]string
+	defaults   map[string]map[string]interface{}
+}
+
+// patternMethod is a method registered for all the names accepted by match.
+type patternMethod struct {
+	// name is the pattern, the prefix or the regexp the method was registered with
+	name   string
+	match  func(name string) bool
+	method Method
+}
+
+// versionMethod is one implementation of a method registered with AddVersion.
+type versionMethod struct {
+	version string
+	weight  int
+	method  Method
+}
+
+// get returns the method registered with the name, the version selected by the name suffix
+// `name@version` or by the version argument, or the first pattern that matches the name.
+func (t *methodTable) get(name, version string) (Method, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	// The version from the context is ignored for methods without versions
+	if method, ok := t.methods[name]; ok && (version == "" || t.versions[name] == nil) {
+		return method, true
+	}
+
+	base := name
+	if i := strings.LastIndexByte(name, '@'); i > 0 {
+		base, version = name[:i], name[i+1:]
+	}
+	if vs, ok := t.versions[base]; ok {
+		if method, ok := selectVersion(vs, version); ok {
+			return method, true
+		}
+	}
+
+	for _, p := range t.patterns {
+		if p.match(name) {
+			return p.method, true
+		}
+	}
+	return nil, false
+}
+
+// registered returns the name the method of the request name was registered with, the name
+// without the `@version` suffix for the versioned methods or the pattern that matches it, so the
+// requests of the same method are grouped. It returns name if no method is found.
+func (t *methodTable) registered(name string) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if _, ok := t.methods[name]; ok {
+		return name
+	}
+	if _, ok := t.versions[name]; ok {
+		return name
+	}
+	if i := strings.LastIndexByte(name, '@'); i > 0 {
+		if _, ok := t.versions[name[:i]]; ok {
+			return name[:i]
+		}
+	}
+	for _, p := range t.patterns {
+		if p.match(name) {
+			return p.name
+		}
+	}
+	return name
+}
+
+// selectVersion returns the method with the version, or one of the methods chosen randomly
+// by weight if the version is empty.
+func selectVersion(vs []versionMethod, version string) (Method, bool) {
+	if version != "" {
+		for _, v := range vs {
+			if v.version == version {
+				return v.method, true
+			}
+		}
+		return nil, false
+	}
+
+	total := 0
+	for _, v := range vs {
+		total += v.weight
+	}
+	if total == 0 {
+		return nil, false
+	}
+	n := rand.Intn(total) // #nosec G404 -- routing doesn't need a secure random
+	for _, v := range vs {
+		if n < v.weight {
+			return v.method, true
+		}
+		n -= v.weight
+	}
+	return nil, false
+}
+
+// replace swaps the method registered with the name and returns false if there is none.
+func (t *methodTable) replace(name string, h Method) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.methods[name]; !ok {
+		return false
+	}
+	t.methods[name] = h
+	return true
+}
+
+// timeout returns the timeout of the method with the name or def if the method doesn't have one.
+func (t *methodTable) timeout(name string, def time.Duration) time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if d, ok := t.timeouts[name]; ok {
+		return d
+	}
+	return def
+}
+
+// Replace will atomically swap the method registered with the name for h, the requests being
+// executed finish on the previous method and the new requests are executed on h.
+//
+// It returns an error if h is nil or there is no method registered with Add for the name.
+func (m *Manager) Replace(name string, h Method) error {
+	if h == nil {
+		return fmt.Errorf("jsonrpc: method %q can't be replaced by a nil method", name)
+	}
+	if !m.table.replace(name, h) {
+		return fmt.Errorf("jsonrpc: method %q is not registered", name)
+	}
+	m.emit(ManagerEvent{Kind: MethodRegistered, Method: name})
+	return nil
 }
 
 // Handle will receive a request content and write the result of the excecution to the writer.
 //
-// It can return an error if the JSON encoding or the writing fails.
+// The elements of a batch are executed as they are decoded from r, so the batch is never kept
+// in memory. An invalid element, like null, is replied with an Invalid Request error response.
+// A malformed element stops the batch and its error is returned, the responses of the elements
+// before it are replied with the error response as the last element.
+//
+// The responses of a batch are in the order of the requests, even when they are executed
+// concurrently, unless ManagerBuilder.AllowUnorderedBatch is used.
+//
+// It returns ErrNilReader or ErrNilWriter for programming errors, an *Error for malformed input,
+// which is also written to w as an error response with a null ID, a TransportError if reading or
+// writing fails, or an error if the JSON encoding fails. When writing the responses of a batch
+// fails the TransportError is wrapped by a BatchWriteError with the delivered and lost IDs.
+//
+// When the ctx is canceled, like when the HTTP client disconnected, the methods being executed
+// stop with a client canceled error, distinct from the timeout, and nothing is written since
+// there is no one to read it, context.Canceled is returned instead.
 func (m *Manager) Handle(ctx context.Context, r io.Reader, w io.Writer) error {
+	return m.handle(ctx, r, w, nil)
+}
+
+// handle will execute the requests, when start is not nil the responses of a batch are written
+// to w as they complete and start is called before the first one.
+func (m *Manager) handle(ctx context.Context, r io.Reader, w io.Writer, start func()) error {
 	if r == nil {
-		return newError(errCodeInternal, "r io.Reader can't be nil")
+		return ErrNilReader
 	}
 
 	if w == nil {
-		return newError(errCodeInternal, "w io.Writer can't be nil")
+		return ErrNilWriter
 	}
 
-	rq, err := parseMethodRequest(r)
+	dec, err := newRequestDecoder(r)
 	if err != nil {
-		return err
+		return m.replyError(w, err)
 	}
+	defer dec.close()
 
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	if len(rq) == 0 {
-		return newError(errCodeInvalidRequest, "no methods specified")
+	if m.batchTimeout > 0 && dec.batch {
+		var cancel context.CancelFunc
+		ctx, cancel = m.withTimeout(ctx, m.batchTimeout)
+		defer cancel()
+	}
+
+	b := m.newBatch()
+	var stream *batchStream
+	if start != nil && dec.batch {
+		stream = &batchStream{m: m, w: w, start: start}
+		b.emit = stream.write
 	}
+	// Shared by all the requests not attempted so each error lists all of them
+	notAttempted := &BatchTimeoutData{NotAttempted: []*json.RawMessage{}}
 
-	resp := make([]*Response, 0, len(rq))
+	// The requests are executed as they are decoded, an invalid element is replied with an error
+	// response and a malformed one stops the batch
+	count := 0
+	for {
+		req, err := dec.next()
+		if err == io.EOF {
+			break
+		}
+		var e *Error
+		if err != nil && dec.batch && !dec.done && errors.As(err, &e) {
+			count++
+			iResp := &Response{Version: version, Error: e}
+			b.add(&Request{}, func() *Response { return iResp })
+			continue
+		}
+		if err != nil {
+			resp := b.wait()
+			if stream != nil && stream.started {
+				return stream.abort(err)
+			}
+			return m.replyBatchError(w, resp, err)
+		}
+		count++
 
-	for i := range rq {
-		tResp := m.execMethod(ctx, rq[i])
-		// If no ID means it's a notification and the server shouldn't reply
-		// if we have an error it should return anyway
-		if rq[i].ID != nil || tResp.Error != nil {
-			resp = append(resp, tResp)
+		if ctx.Err() != nil {
+			notAttempted.RetryInfo = m.retryInfo()
+			if req.ID != nil {
+				notAttempted.NotAttempted = append(notAttempted.NotAttempted, req.ID)
+			}
+			data := notAttempted
+			if stream != nil {
+				// The streamed response is encoded while the next requests are appended, it gets
+				// a copy with the requests not attempted so far
+				data = &BatchTimeoutData{
+					NotAttempted: make([]*json.RawMessage, len(notAttempted.NotAttempted)),
+					RetryInfo:    notAttempted.RetryInfo,
+				}
+				copy(data.NotAttempted, notAttempted.NotAttempted)
+			}
+			tResp := newResponse(req)
+			tResp.Error = newError(errCodeExecutionTimeout, data)
+			m.localize(ctx, tResp)
+			b.add(req, func() *Response { return tResp })
+			continue
+		}
+		// The request is charged until it's finished, including while it waits to be executed
+		charge, ok := m.memory.charge(req)
+		if !ok {
+			oResp := m.overloaded(ctx, req)
+			b.add(req, func() *Response { return oResp })
+			continue
+		}
+		rctx := withMemoryCharge(ctx, charge)
+		if req.ID == nil && m.notifications != nil {
+			m.dispatch(rctx, req)
+			charge.release()
+			continue
 		}
+		b.add(req, func() *Response {
+			defer charge.release()
+			return m.execMethod(rctx, req)
+		})
 	}
+	resp := b.wait()
 
-	// If more then one response return a json array
-	if len(resp) > 1 {
-		return json.NewEncoder(w).Encode(resp)
-		//if err := json.NewEncoder(w).Encode(resp); err != nil {
-		//	return err
-		//}
+	// The caller is gone, there is no one to read the responses
+	if ctx.Err() == context.Canceled {
+		return ctx.Err()
 	}
-	// If only one response return a json object
-	if len(resp) == 1 {
-		return json.NewEncoder(w).Encode(resp[0])
+
+	if count == 0 {
+		e := newError(errCodeInvalidRequest, "no methods specified")
+		e.cause = ErrEmptyBatch
+		return m.replyError(w, e)
 	}
+
+	if stream != nil && stream.started {
+		return stream.close()
+	}
+
 	// If no response don't send anything
-	return nil
+	if len(resp) == 0 {
+		return nil
+	}
+	// If more then one response return a json array, if only one return a json object
+	var v interface{} = resp
+	if len(resp) == 1 {
+		v = resp[0]
+	}
+	if !dec.batch {
+		return m.encoder.encode(w, v)
+	}
+	return m.writeBatch(w, resp, v)
+}
+
+// writeBatch will write v, the encoding of the responses of a batch, a partial array can't be
+// parsed so none of the responses is delivered if the write fails, even when the batch has only
+// one response.
+func (m *Manager) writeBatch(w io.Writer, resp []*Response, v interface{}) error {
+	err := m.encoder.encode(w, v)
+	var te *TransportError
+	if errors.As(err, &te) {
+		bwe := &BatchWriteError{Delivered: []*json.RawMessage{}, Err: err}
+		for _, r := range resp {
+			bwe.Undelivered = appendID(bwe.Undelivered, r)
+		}
+		return bwe
+	}
+	return err
+}
+
+// replyBatchError will write the responses of the requests executed before the malformed element
+// of a batch with its error response as the last element and return err, only the error response
+// is written, like replyError, if there are no responses or the error is not an *Error.
+func (m *Manager) replyBatchError(w io.Writer, resp []*Response, err error) error {
+	var e *Error
+	if len(resp) == 0 || !errors.As(err, &e) {
+		return m.replyError(w, err)
+	}
+	resp = append(resp, &Response{Version: version, Error: e})
+	if werr := m.writeBatch(w, resp, resp); werr != nil {
+		return werr
+	}
+	return err
+}
+
+// replyError will write the error response with a null ID if err is an *Error, like a parse error,
+// and return err so the caller can log it.
+func (m *Manager) replyError(w io.Writer, err error) error {
+	var e *Error
+	if !errors.As(err, &e) {
+		return err
+	}
+	if werr := m.encoder.encode(w, &Response{Version: version, Error: e}); werr != nil {
+		return werr
+	}
+	return err
 }
 
 // execMethod will receive a request, execute the method and return the response.
 func (m *Manager) execMethod(ctx context.Context, req *Request) *Response {
+	ctx = correlate(ctx, req)
+	return m.dedupe(ctx, req, func() *Response {
+		return m.run(ctx, req, m.table.timeout(req.Method, m.timeout))
+	})
+}
+
+// run will execute the request with the timeout and apply the response policies, the context
+// must be already correlated.
+func (m *Manager) run(ctx context.Context, req *Request, timeout time.Duration) *Response {
+	ctx, meter := m.withCostMeter(ctx)
+	start := m.clock.Now()
+	res := m.execute(ctx, req, timeout)
+	m.limitResponse(res)
+	var costs map[string]int64
+	if meter != nil {
+		costs = meter.snapshot()
+		m.reportCost(ctx, req, costs)
+		m.chargeQuota(ctx, req, costs)
+	}
+	elapsed := m.clock.Now().Sub(start)
+	m.observe(ctx, req, res, elapsed, costs)
+	m.localize(ctx, res)
+	m.echoCorrelation(ctx, res)
+	m.logAccess(ctx, req, res, start, elapsed)
+	return res
+}
+
+// observe will record the execution of the request on the enabled observability features.
+func (m *Manager) observe(ctx context.Context, req *Request, res *Response, elapsed time.Duration, costs map[string]int64) {
+	if m.capture != nil {
+		m.capture.add(m.captureRequest(req, res, CorrelationIDFromContext(ctx), elapsed))
+	}
+	if m.stats != nil {
+		m.stats.add(m.table.registered(req.Method), res, elapsed, costs)
+	}
+	m.reportSlow(ctx, req, res, elapsed)
+}
+
+// execute will validate the request and execute the method with the timeout.
+func (m *Manager) execute(ctx context.Context, req *Request, timeout time.Duration) *Response {
 	res := newResponse(req)
-	if req.Version != version {
+	if !m.versionCheck.accept(req) {
+		if m.strictVersion {
+			res.Version = version
+			res.Error = newError(errCodeInvalidRequest, `jsonrpc must be "2.0"`)
+			return res
+		}
 		res.Error = newError(errCodeInvalidRPCVersion, res.Version)
 		return res
 	}
+	res.Version = version
 
 	if req.Method == "" {
 		res.Error = newError(errCodeMethodNotFound, "Method not specified or empty")
 		return res
 	}
 
-	m.mu.RLock()
-	method, ok := m.methods[req.Method]
-	m.mu.RUnlock()
-
+	method, ok := m.table.get(req.Method, versionFromContext(ctx))
 	if !ok {
 		res.Error = newError(errCodeMethodNotFound, req.Method)
 		return res
 	}
+	if e := m.disabled(ctx, req.Method); e != nil {
+		res.Error = e
+		return res
+	}
+	if e := m.admitQuota(ctx, req); e != nil {
+		res.Error = e
+		return res
+	}
+	req = m.table.withDefaults(req)
+	if msg, ok := m.table.deprecation(req.Method); ok {
+		m.warnDeprecated(ctx, req, msg)
+	}
+	method = chain(method, m.middleware)
+
+	if n := atomic.AddInt64(&m.inFlight, 1); m.maxInFlight > 0 && n > m.maxInFlight {
+		atomic.AddInt64(&m.inFlight, -1)
+		res.Error = newError(errCodeServerOverloaded, m.retryData())
+		m.emitRequest(ctx, OverloadRejected, req)
+		return res
+	}
+	// The request decoded by Handle was already charged, the others are charged now
+	charge := memoryChargeFromContext(ctx)
+	if charge != nil {
+		charge.hold()
+	} else if charge, ok = m.memory.charge(req); !ok {
+		atomic.AddInt64(&m.inFlight, -1)
+		res.Error = newError(errCodeServerOverloaded, m.retryData())
+		m.emitRequest(ctx, OverloadRejected, req)
+		return res
+	}
 
 	finish := make(chan bool, 1)
 
-	ctxT, cancel := context.WithTimeout(ctx, m.timeout)
+	ctxT, cancel := m.withTimeout(ctx, timeout)
 	defer cancel()
+	if charge != nil {
+		// The requests made by the method with its context are charged on their own
+		ctxT = context.WithValue(ctxT, memoryKey, (*memoryCharge)(nil))
+	}
+	var cw *chunkWriter
+	if n := NotifierFromContext(ctx); m.chunkSize > 0 && n != nil && req.ID != nil {
+		cw = newChunkWriter(ctx, n, req.ID, m.chunkSize)
+		ctxT = context.WithValue(ctxT, chunkKey, cw)
+	}
 	req = req.WithContext(ctxT)
+	// The requests not created by the transports get the value store on their copy
+	if req.values == nil {
+		req.values = newValues()
+	}
+	entry := m.inFlightTracker.add(req, cancel)
+
+	// The method writes its own response, it's only copied when the method finishes in time so
+	// a method still running after the timeout can't change the response being encoded
+	out := &Response{Version: res.Version, ID: res.ID}
 
+	started := m.clock.Now()
 	//! The goroutine will stay there until it finish even after the timeout
 	go func() {
-		method.Execute(req, res)
+		defer atomic.AddInt64(&m.inFlight, -1)
+		defer charge.release()
+		defer m.inFlightTracker.remove(entry)
+		if m.labels {
+			pprof.Do(ctxT, pprof.Labels(ProfilerLabel, req.Method), func(ctx context.Context) {
+				m.callMethod(method, req.WithContext(ctx), out)
+			})
+		} else {
+			m.callMethod(method, req, out)
+		}
+		if cw != nil {
+			cw.close()
+		}
 		close(finish)
 	}()
 
 	select {
 	case <-ctxT.Done():
-		res.Error = newError(errCodeExecutionTimeout, nil)
+		if m.inFlightTracker.isCanceled(entry) {
+			res.Error = newError(errCodeExecutionCanceled, "canceled by the server")
+			break
+		}
+		if ctx.Err() == context.Canceled {
+			res.Error = newError(errCodeClientCanceled, nil)
+			break
+		}
+		data := &TimeoutData{
+			Method:    req.Method,
+			ID:        req.ID,
+			Elapsed:   m.clock.Now().Sub(started).Milliseconds(),
+			RetryInfo: m.retryInfo(),
+		}
+		if ctx.Err() == nil {
+			data.Timeout = timeout.Milliseconds()
+		}
+		res.Error = newError(errCodeExecutionTimeout, data)
+		m.emitRequest(ctx, TimeoutOccurred, req)
 	case <-finish:
+		*res = *out
 		if res.Error != nil {
-			res.Result = nil
+			res.SetError(res.Error)
 		}
 	}
 	return res
 }
+
+// overloaded returns the server overloaded response of a request rejected before it's executed.
+func (m *Manager) overloaded(ctx context.Context, req *Request) *Response {
+	res := newResponse(req)
+	res.Version = version
+	res.Error = newError(errCodeServerOverloaded, m.retryData())
+	m.localize(ctx, res)
+	m.emitRequest(ctx, OverloadRejected, req)
+	return res
+}
+
+// retryInfo returns the retry hint for the timeout and overload errors, it returns nil if the
+// manager is not configured to send retry hints.
+func (m *Manager) retryInfo() *RetryInfo {
+	if m.retryAfter <= 0 {
+		return nil
+	}
+	return &RetryInfo{
+		RetryAfter: m.retryAfter.Milliseconds(),
+		QueueDepth: atomic.LoadInt64(&m.inFlight),
+	}
+}
+
+// retryData returns the retry hint as error data, it's nil instead of a nil *RetryInfo so the
+// data member is omitted from the error.
+func (m *Manager) retryData() interface{} {
+	if ri := m.retryInfo(); ri != nil {
+		return ri
+	}
+	return nil
+}
@@ -3,8 +3,20 @@ package jrpc2go_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"reflect"
+	"regexp"
+	"runtime/pprof"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -100,10 +112,11 @@ func TestManager(t *testing.T) {
 		w   io.Writer
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantW   string
-		wantErr bool
+		name        string
+		args        args
+		wantW       string
+		wantElapsed int64
+		wantErr     bool
 	}{
 		{
 			name: "No Reader",
@@ -199,7 +212,8 @@ func TestManager(t *testing.T) {
 				ctx: context.Background(),
 				w:   &bytes.Buffer{},
 			},
-			wantW: `{"jsonrpc":"2.0","id":"1","error":{"code":-32002,"message":"Method execution timeout"}}`,
+			wantW:       `{"jsonrpc":"2.0","id":"1","error":{"code":-32002,"message":"Method execution timeout","data":{"method":"sum","id":"1","timeout":1000,"elapsed":1000}}}`,
+			wantElapsed: 1000,
 		},
 		{
 			name: "Response Error with Result",
@@ -226,9 +240,1457 @@ func TestManager(t *testing.T) {
 			}
 
 			wt := tt.args.w.(*bytes.Buffer)
-			if gotW := wt.String(); strings.Compare(strings.TrimSpace(gotW), tt.wantW) != 0 {
+			if gotW := roundElapsed(wt.String(), tt.wantElapsed); strings.Compare(strings.TrimSpace(gotW), tt.wantW) != 0 {
 				t.Errorf("Manager.Handle() result = '%v', want %v", gotW, tt.wantW)
 			}
 		})
 	}
 }
+
+var elapsedPattern = regexp.MustCompile(`"elapsed":(\d+)`)
+
+// roundElapsed returns the response with the elapsed times of the timeouts that are at least
+// want milliseconds, and less than 100 more, replaced by want so the response can be compared.
+func roundElapsed(resp string, want int64) string {
+	return elapsedPattern.ReplaceAllStringFunc(resp, func(s string) string {
+		n, _ := strconv.ParseInt(elapsedPattern.FindStringSubmatch(s)[1], 10, 64)
+		if n < want || n >= want+100 {
+			return s
+		}
+		return `"elapsed":` + strconv.FormatInt(want, 10)
+	})
+}
+
+type echoMethod struct{}
+
+func (m *echoMethod) Execute(req *jrpc.Request, resp *jrpc.Response) {
+	var p string
+	if err := req.ParseParams(&p); err != nil {
+		resp.Error = err
+		return
+	}
+	resp.Result = p
+}
+
+func TestManagerBuilder_Encoder(t *testing.T) {
+	req := `{"jsonrpc":"2.0","method":"echo","id":1,"params":"a&b"}`
+
+	tests := []struct {
+		name  string
+		mb    *jrpc.ManagerBuilder
+		wantW string
+	}{
+		{
+			name:  "Default Encoder",
+			mb:    jrpc.NewManagerBuilder(),
+			wantW: "{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":\"a\\u0026b\"}\n",
+		},
+		{
+			name:  "No HTML Escape",
+			mb:    jrpc.NewManagerBuilder().SetEscapeHTML(false),
+			wantW: "{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":\"a&b\"}\n",
+		},
+		{
+			name:  "No Trailing Newline",
+			mb:    jrpc.NewManagerBuilder().SetTrailingNewline(false),
+			wantW: "{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":\"a\\u0026b\"}",
+		},
+		{
+			name:  "Indent",
+			mb:    jrpc.NewManagerBuilder().SetIndent("", " ").SetEscapeHTML(false),
+			wantW: "{\n \"jsonrpc\": \"2.0\",\n \"id\": 1,\n \"result\": \"a&b\"\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.mb.Add("echo", &echoMethod{}).Build()
+			w := &bytes.Buffer{}
+			if err := m.Handle(context.Background(), strings.NewReader(req), w); err != nil {
+				t.Fatalf("Manager.Handle() error = %v", err)
+			}
+			if gotW := w.String(); gotW != tt.wantW {
+				t.Errorf("Manager.Handle() result = %q, want %q", gotW, tt.wantW)
+			}
+		})
+	}
+}
+
+func TestManagerBuilder_SetBatchTimeout(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		SetBatchTimeout(500*time.Millisecond).
+		Add("add", &addMethod{}).
+		Build()
+
+	tests := []struct {
+		name  string
+		r     string
+		wantW string
+	}{
+		{
+			name:  "Batch Within Deadline",
+			r:     `[{"jsonrpc":"2.0","method":"add","id":1,"params":{"v1":1,"v2":2}},{"jsonrpc":"2.0","method":"add","id":2,"params":{"v1":2,"v2":2}}]`,
+			wantW: `[{"jsonrpc":"2.0","id":1,"result":3},{"jsonrpc":"2.0","id":2,"result":4}]`,
+		},
+		{
+			name: "Batch Deadline Expired",
+			r:    `[{"jsonrpc":"2.0","method":"add","id":1,"params":{"v1":1,"v2":2}},{"jsonrpc":"2.0","method":"add","id":2,"params":{"v1":10,"v2":10}},{"jsonrpc":"2.0","method":"add","id":3,"params":{"v1":1,"v2":1}},{"jsonrpc":"2.0","method":"add","params":{"v1":1,"v2":1}}]`,
+			wantW: `[{"jsonrpc":"2.0","id":1,"result":3},` +
+				`{"jsonrpc":"2.0","id":2,"error":{"code":-32002,"message":"Method execution timeout","data":{"method":"add","id":2,"elapsed":500}}},` +
+				`{"jsonrpc":"2.0","id":3,"error":{"code":-32002,"message":"Method execution timeout","data":{"notAttempted":[3]}}},` +
+				`{"jsonrpc":"2.0","id":null,"error":{"code":-32002,"message":"Method execution timeout","data":{"notAttempted":[3]}}}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			if err := m.Handle(context.Background(), strings.NewReader(tt.r), w); err != nil {
+				t.Fatalf("Manager.Handle() error = %v", err)
+			}
+			if gotW := roundElapsed(strings.TrimSpace(w.String()), 500); gotW != tt.wantW {
+				t.Errorf("Manager.Handle() result = %v, want %v", gotW, tt.wantW)
+			}
+		})
+	}
+}
+
+type blockMethod struct {
+	release chan struct{}
+}
+
+func (m *blockMethod) Execute(req *jrpc.Request, resp *jrpc.Response) {
+	<-m.release
+	resp.Result = true
+}
+
+func TestManagerBuilder_SetRetryAfter(t *testing.T) {
+	block := &blockMethod{release: make(chan struct{})}
+	defer close(block.release)
+
+	m := jrpc.NewManagerBuilder().
+		SetTimeout(100*time.Millisecond).
+		SetMaxInFlight(1).
+		SetRetryAfter(2*time.Second).
+		Add("block", block).
+		Build()
+
+	tests := []struct {
+		name  string
+		r     string
+		wantW string
+	}{
+		{
+			name:  "Timeout with Retry Hint",
+			r:     `{"jsonrpc":"2.0","method":"block","id":1}`,
+			wantW: `{"jsonrpc":"2.0","id":1,"error":{"code":-32002,"message":"Method execution timeout","data":{"method":"block","id":1,"timeout":100,"elapsed":100,"retryAfter":2000,"queueDepth":1}}}`,
+		},
+		{
+			name:  "Overload with Retry Hint",
+			r:     `{"jsonrpc":"2.0","method":"block","id":2}`,
+			wantW: `{"jsonrpc":"2.0","id":2,"error":{"code":-32003,"message":"Server overloaded","data":{"retryAfter":2000,"queueDepth":1}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			if err := m.Handle(context.Background(), strings.NewReader(tt.r), w); err != nil {
+				t.Fatalf("Manager.Handle() error = %v", err)
+			}
+			if gotW := roundElapsed(strings.TrimSpace(w.String()), 100); gotW != tt.wantW {
+				t.Errorf("Manager.Handle() result = %v, want %v", gotW, tt.wantW)
+			}
+		})
+	}
+}
+
+type nameMethod struct {
+	tag string
+}
+
+func (m *nameMethod) Execute(req *jrpc.Request, resp *jrpc.Response) {
+	resp.Result = m.tag + ":" + req.Method
+}
+
+func TestManagerBuilder_AddPattern(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("files/read", &nameMethod{tag: "exact"}).
+		AddPattern("files/*", &nameMethod{tag: "pattern"}).
+		AddPrefix("$/", &nameMethod{tag: "prefix"}).
+		AddRegexp(regexp.MustCompile(`^v[0-9]+\.`), &nameMethod{tag: "regexp"}).
+		Build()
+
+	tests := []struct {
+		name   string
+		method string
+		wantW  string
+	}{
+		{name: "Exact Before Pattern", method: "files/read", wantW: `"exact:files/read"`},
+		{name: "Pattern", method: "files/write", wantW: `"pattern:files/write"`},
+		{name: "Pattern Not Nested", method: "files/a/b", wantW: ``},
+		{name: "Prefix", method: "$/cancelRequest", wantW: `"prefix:$/cancelRequest"`},
+		{name: "Regexp", method: "v2.add", wantW: `"regexp:v2.add"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			r := `{"jsonrpc":"2.0","method":"` + tt.method + `","id":1}`
+			if err := m.Handle(context.Background(), strings.NewReader(r), w); err != nil {
+				t.Fatalf("Manager.Handle() error = %v", err)
+			}
+			want := `{"jsonrpc":"2.0","id":1,"result":` + tt.wantW + `}`
+			if tt.wantW == "" {
+				want = `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found","data":"` + tt.method + `"}}`
+			}
+			if gotW := strings.TrimSpace(w.String()); gotW != want {
+				t.Errorf("Manager.Handle() result = %v, want %v", gotW, want)
+			}
+		})
+	}
+}
+
+func TestManagerBuilder_AddVersion(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("echo", &nameMethod{tag: "echo"}).
+		AddVersion("add", "v1", 1, &nameMethod{tag: "v1"}).
+		AddVersion("add", "v2", 0, &nameMethod{tag: "v2"}).
+		Build()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		method  string
+		wantW   string
+		wantErr bool
+	}{
+		{name: "Weighted Version", ctx: context.Background(), method: "add", wantW: `"v1:add"`},
+		{name: "Version Suffix", ctx: context.Background(), method: "add@v2", wantW: `"v2:add@v2"`},
+		{name: "Context Version", ctx: jrpc.ContextWithVersion(context.Background(), "v2"), method: "add", wantW: `"v2:add"`},
+		{name: "Context Version Without Versions", ctx: jrpc.ContextWithVersion(context.Background(), "v2"), method: "echo", wantW: `"echo:echo"`},
+		{name: "Unknown Version", ctx: context.Background(), method: "add@v3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			r := `{"jsonrpc":"2.0","method":"` + tt.method + `","id":1}`
+			if err := m.Handle(tt.ctx, strings.NewReader(r), w); err != nil {
+				t.Fatalf("Manager.Handle() error = %v", err)
+			}
+			want := `{"jsonrpc":"2.0","id":1,"result":` + tt.wantW + `}`
+			if tt.wantErr {
+				want = `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found","data":"` + tt.method + `"}}`
+			}
+			if gotW := strings.TrimSpace(w.String()); gotW != want {
+				t.Errorf("Manager.Handle() result = %v, want %v", gotW, want)
+			}
+		})
+	}
+}
+
+func TestManager_Replace(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("hello", &nameMethod{tag: "old"}).
+		Build()
+	d := m.With(jrpc.WithTimeout(time.Second))
+
+	if err := m.Replace("bye", &nameMethod{tag: "new"}); err == nil {
+		t.Errorf("Manager.Replace() error = nil, want error for method not registered")
+	}
+	if err := m.Replace("hello", nil); err == nil {
+		t.Errorf("Manager.Replace() error = nil, want error for nil method")
+	}
+	if err := m.Replace("hello", &nameMethod{tag: "new"}); err != nil {
+		t.Fatalf("Manager.Replace() error = %v", err)
+	}
+
+	want := `{"jsonrpc":"2.0","id":1,"result":"new:hello"}`
+	for _, mm := range []*jrpc.Manager{&m, d} {
+		w := &bytes.Buffer{}
+		if err := mm.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"hello","id":1}`), w); err != nil {
+			t.Fatalf("Manager.Handle() error = %v", err)
+		}
+		if gotW := strings.TrimSpace(w.String()); gotW != want {
+			t.Errorf("Manager.Handle() result = %v, want %v", gotW, want)
+		}
+	}
+}
+
+type labelMethod struct{}
+
+func (m *labelMethod) Execute(req *jrpc.Request, resp *jrpc.Response) {
+	resp.Result, _ = pprof.Label(req.Context(), jrpc.ProfilerLabel)
+}
+
+func TestManagerBuilder_EnableProfilerLabels(t *testing.T) {
+	manager := jrpc.NewManagerBuilder().
+		EnableProfilerLabels().
+		Add("label", &labelMethod{}).
+		Build()
+
+	var out strings.Builder
+	in := strings.NewReader(`{"jsonrpc":"2.0","method":"label","id":1}`)
+	if err := manager.Handle(context.Background(), in, &out); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if want := `{"jsonrpc":"2.0","id":1,"result":"label"}` + "\n"; out.String() != want {
+		t.Errorf("Handle() = %v, want %v", out.String(), want)
+	}
+}
+
+func TestManager_Handle_StreamingBatch(t *testing.T) {
+	executed := make(chan string, 2)
+	record := jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+		var p string
+		_ = req.ParseParams(&p)
+		executed <- p
+		resp.Result = p
+	})
+	manager := jrpc.NewManagerBuilder().Add("record", record).Build()
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	var out bytes.Buffer
+	go func() {
+		done <- manager.Handle(context.Background(), pr, &out)
+	}()
+
+	if _, err := io.WriteString(pw, `[{"jsonrpc":"2.0","method":"record","params":"first","id":1},`); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	select {
+	case p := <-executed:
+		if p != "first" {
+			t.Fatalf("executed = %v, want first", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("first request not executed before the batch was complete")
+	}
+
+	if _, err := io.WriteString(pw, `{"jsonrpc":"2.0","method":"record","params":"second","id":2}]`); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	pw.Close()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	want := `[{"jsonrpc":"2.0","id":1,"result":"first"},{"jsonrpc":"2.0","id":2,"result":"second"}]` + "\n"
+	if out.String() != want {
+		t.Errorf("Handle() = %v, want %v", out.String(), want)
+	}
+}
+
+func TestManager_Handle_EmptyInput(t *testing.T) {
+	manager := jrpc.NewManagerBuilder().Add("echo", &echoMethod{}).Build()
+
+	tests := []struct {
+		name     string
+		r        string
+		wantCode jrpc.ErrorCode
+		wantData string
+	}{
+		{name: "Empty", r: "", wantCode: -32700, wantData: "empty request"},
+		{name: "Whitespace Only", r: " \n\t ", wantCode: -32700, wantData: "empty request"},
+		{name: "Truncated Object", r: `{"jsonrpc":"2.0","method":`, wantCode: -32700, wantData: "truncated request"},
+		{name: "Truncated Batch", r: `[{"jsonrpc":"2.0","method":"echo","params":"a"}`, wantCode: -32700, wantData: "truncated request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := manager.Handle(context.Background(), strings.NewReader(tt.r), &out)
+			rpcErr, ok := err.(*jrpc.Error)
+			if !ok {
+				t.Fatalf("Handle() error = %v, want *jrpc.Error", err)
+			}
+			if rpcErr.Code != tt.wantCode || rpcErr.Data != tt.wantData {
+				t.Errorf("Handle() error = %v %v, want %v %v", rpcErr.Code, rpcErr.Data, tt.wantCode, tt.wantData)
+			}
+
+			// The error is also replied to the client
+			var resp struct {
+				ID    *json.RawMessage `json:"id"`
+				Error *jrpc.Error      `json:"error"`
+			}
+			if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+				t.Fatalf("Handle() invalid response %q: %v", out.String(), err)
+			}
+			if resp.ID != nil || resp.Error == nil || resp.Error.Code != tt.wantCode {
+				t.Errorf("Handle() response = %v, want error %v with null id", out.String(), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestManager_Handle_InvalidBatchElement(t *testing.T) {
+	manager := jrpc.NewManagerBuilder().Add("echo", &echoMethod{}).Build()
+	invalid := `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request","data":"request must be an object"}}`
+
+	tests := []struct {
+		name     string
+		r        string
+		wantW    string
+		wantCode jrpc.ErrorCode
+	}{
+		{
+			name:  "Null Element",
+			r:     `[{"jsonrpc":"2.0","method":"echo","params":"a","id":1},null,{"jsonrpc":"2.0","method":"echo","params":"c","id":3}]`,
+			wantW: `[{"jsonrpc":"2.0","id":1,"result":"a"},` + invalid + `,{"jsonrpc":"2.0","id":3,"result":"c"}]`,
+		},
+		{
+			name:  "Only Null Elements",
+			r:     `[null,null]`,
+			wantW: `[` + invalid + `,` + invalid + `]`,
+		},
+		{
+			name:     "Null Request",
+			r:        `null`,
+			wantW:    invalid,
+			wantCode: -32600,
+		},
+		{
+			name:     "Truncated After Valid Elements",
+			r:        `[{"jsonrpc":"2.0","method":"echo","params":"a","id":1},{"jsonrpc":"2.0","method":"echo","params":"b"},{"jsonrpc":"2.0","method":"echo","params":"c","id":3}`,
+			wantW:    `[{"jsonrpc":"2.0","id":1,"result":"a"},{"jsonrpc":"2.0","id":3,"result":"c"},{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error","data":"truncated request"}}]`,
+			wantCode: -32700,
+		},
+		{
+			name:     "Malformed After Notifications",
+			r:        `[{"jsonrpc":"2.0","method":"echo","params":"a"},{"jsonrpc":"2.0","method":`,
+			wantW:    `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error","data":"truncated request"}}`,
+			wantCode: -32700,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := manager.Handle(context.Background(), strings.NewReader(tt.r), &out)
+			var rpcErr *jrpc.Error
+			if tt.wantCode == 0 && err != nil || tt.wantCode != 0 && (!errors.As(err, &rpcErr) || rpcErr.Code != tt.wantCode) {
+				t.Errorf("Handle() error = %v, want code %v", err, tt.wantCode)
+			}
+			if got := strings.TrimSpace(out.String()); got != tt.wantW {
+				t.Errorf("Handle() = %v, want %v", got, tt.wantW)
+			}
+		})
+	}
+}
+
+func TestManager_Handle_LeadingWhitespace(t *testing.T) {
+	manager := jrpc.NewManagerBuilder().Add("echo", &echoMethod{}).Build()
+
+	batch := `[{"jsonrpc":"2.0","id":1,"result":"a"},{"jsonrpc":"2.0","id":2,"result":"b"}]` + "\n"
+	tests := []struct {
+		name  string
+		r     string
+		wantW string
+	}{
+		{
+			name: "Pretty Printed Batch",
+			r: "\n  \t[\n" +
+				"    {\"jsonrpc\": \"2.0\", \"method\": \"echo\", \"params\": \"a\", \"id\": 1},\n" +
+				"    {\"jsonrpc\": \"2.0\", \"method\": \"echo\", \"params\": \"b\", \"id\": 2}\n" +
+				"]\n",
+			wantW: batch,
+		},
+		{
+			name:  "BOM Prefixed Batch",
+			r:     "\ufeff" + `[{"jsonrpc":"2.0","method":"echo","params":"a","id":1},{"jsonrpc":"2.0","method":"echo","params":"b","id":2}]`,
+			wantW: batch,
+		},
+		{
+			name:  "BOM Prefixed Object",
+			r:     "\ufeff\r\n" + `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`,
+			wantW: `{"jsonrpc":"2.0","id":1,"result":"a"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := manager.Handle(context.Background(), strings.NewReader(tt.r), &out); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if out.String() != tt.wantW {
+				t.Errorf("Handle() = %v, want %v", out.String(), tt.wantW)
+			}
+		})
+	}
+}
+
+func TestManager_Handle_BatchOrdering(t *testing.T) {
+	newManager := func(unordered bool) jrpc.Manager {
+		// first only finishes well after second was executed
+		second := make(chan struct{})
+		mb := jrpc.NewManagerBuilder().
+			SetBatchConcurrency(2).
+			Add("first", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+				<-second
+				time.Sleep(50 * time.Millisecond)
+				resp.Result = "first"
+			})).
+			Add("second", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+				close(second)
+				resp.Result = "second"
+			}))
+		if unordered {
+			mb.AllowUnorderedBatch()
+		}
+		return mb.Build()
+	}
+
+	r := `[{"jsonrpc":"2.0","method":"first","id":1},{"jsonrpc":"2.0","method":"second","id":2}]`
+	tests := []struct {
+		name      string
+		unordered bool
+		wantW     string
+	}{
+		{
+			name:  "Request Order",
+			wantW: `[{"jsonrpc":"2.0","id":1,"result":"first"},{"jsonrpc":"2.0","id":2,"result":"second"}]` + "\n",
+		},
+		{
+			name:      "Completion Order",
+			unordered: true,
+			wantW:     `[{"jsonrpc":"2.0","id":2,"result":"second"},{"jsonrpc":"2.0","id":1,"result":"first"}]` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newManager(tt.unordered)
+			var out bytes.Buffer
+			if err := manager.Handle(context.Background(), strings.NewReader(r), &out); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if out.String() != tt.wantW {
+				t.Errorf("Handle() = %v, want %v", out.String(), tt.wantW)
+			}
+		})
+	}
+}
+
+func TestManagerBuilder_SetSortKeys(t *testing.T) {
+	type result struct {
+		Zeta  string          `json:"zeta"`
+		Alpha int64           `json:"alpha"`
+		Raw   json.RawMessage `json:"raw"`
+	}
+	method := jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+		resp.Result = result{
+			Zeta:  "a&b",
+			Alpha: 9007199254740993,
+			Raw:   json.RawMessage(`{"y":[{"d":1,"c":2}],"x":true}`),
+		}
+	})
+
+	tests := []struct {
+		name  string
+		mb    *jrpc.ManagerBuilder
+		wantW string
+	}{
+		{
+			name:  "Field Order",
+			mb:    jrpc.NewManagerBuilder(),
+			wantW: `{"jsonrpc":"2.0","id":1,"result":{"zeta":"a\u0026b","alpha":9007199254740993,"raw":{"y":[{"d":1,"c":2}],"x":true}}}` + "\n",
+		},
+		{
+			name:  "Sorted Keys",
+			mb:    jrpc.NewManagerBuilder().SetSortKeys(true),
+			wantW: `{"jsonrpc":"2.0","id":1,"result":{"alpha":9007199254740993,"raw":{"x":true,"y":[{"c":2,"d":1}]},"zeta":"a\u0026b"}}` + "\n",
+		},
+		{
+			name:  "Sorted Keys No HTML Escape",
+			mb:    jrpc.NewManagerBuilder().SetSortKeys(true).SetEscapeHTML(false),
+			wantW: `{"jsonrpc":"2.0","id":1,"result":{"alpha":9007199254740993,"raw":{"x":true,"y":[{"c":2,"d":1}]},"zeta":"a&b"}}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.mb.Add("result", method).Build()
+			var out bytes.Buffer
+			r := strings.NewReader(`{"jsonrpc":"2.0","method":"result","id":1}`)
+			if err := m.Handle(context.Background(), r, &out); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if out.String() != tt.wantW {
+				t.Errorf("Handle() = %v, want %v", out.String(), tt.wantW)
+			}
+		})
+	}
+}
+
+type point struct {
+	X int64 `json:"x"`
+	Y int64 `json:"y"`
+}
+
+func encodePoint(dst []byte, v interface{}) ([]byte, error) {
+	p := v.(point)
+	dst = append(dst, `{"x":`...)
+	dst = strconv.AppendInt(dst, p.X, 10)
+	dst = append(dst, `,"y":`...)
+	dst = strconv.AppendInt(dst, p.Y, 10)
+	return append(dst, '}'), nil
+}
+
+func TestManagerBuilder_SetResultEncoder(t *testing.T) {
+	tests := []struct {
+		name   string
+		result interface{}
+		wantW  string
+	}{
+		{name: "Registered Encoder", result: point{X: 1, Y: -2}, wantW: `{"x":1,"y":-2}`},
+		{name: "Int64", result: int64(9007199254740993), wantW: `9007199254740993`},
+		{name: "Bool", result: false, wantW: `false`},
+		{name: "String", result: "abc", wantW: `"abc"`},
+		{name: "String With Escapes", result: "a\"<\n", wantW: `"a\"\u003c\n"`},
+		{name: "Raw", result: json.RawMessage(`{"a":[1,"b"]}`), wantW: `{"a":[1,"b"]}`},
+		{name: "Raw Compacted", result: json.RawMessage("{ \"a\" :\n1 }"), wantW: `{"a":1}`},
+		{name: "Other Types", result: []int{1, 2}, wantW: `[1,2]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := jrpc.NewManagerBuilder().
+				SetResultEncoder(point{}, encodePoint).
+				Add("result", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) { resp.Result = tt.result })).
+				Build()
+			var out bytes.Buffer
+			r := strings.NewReader(`[{"jsonrpc":"2.0","method":"result","id":"a"},{"jsonrpc":"2.0","method":"result","id":2}]`)
+			if err := m.Handle(context.Background(), r, &out); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			want := `[{"jsonrpc":"2.0","id":"a","result":` + tt.wantW + `},{"jsonrpc":"2.0","id":2,"result":` + tt.wantW + "}]\n"
+			if out.String() != want {
+				t.Errorf("Handle() = %v, want %v", out.String(), want)
+			}
+		})
+	}
+}
+
+type failReader struct{}
+
+func (failReader) Read(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestManager_Handle_Errors(t *testing.T) {
+	manager := jrpc.NewManagerBuilder().Add("echo", &echoMethod{}).Build()
+	req := `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`
+
+	if err := manager.Handle(context.Background(), nil, &bytes.Buffer{}); err != jrpc.ErrNilReader {
+		t.Errorf("Handle() error = %v, want %v", err, jrpc.ErrNilReader)
+	}
+	if err := manager.Handle(context.Background(), strings.NewReader(req), nil); err != jrpc.ErrNilWriter {
+		t.Errorf("Handle() error = %v, want %v", err, jrpc.ErrNilWriter)
+	}
+
+	err := manager.Handle(context.Background(), strings.NewReader(`[]`), &bytes.Buffer{})
+	var rpcErr *jrpc.Error
+	if !errors.Is(err, jrpc.ErrEmptyBatch) || !errors.As(err, &rpcErr) || rpcErr.Code != -32600 {
+		t.Errorf("Handle() error = %v, want Invalid Request matching %v", err, jrpc.ErrEmptyBatch)
+	}
+
+	err = manager.Handle(context.Background(), failReader{}, &bytes.Buffer{})
+	var te *jrpc.TransportError
+	if !errors.As(err, &te) || te.Op != "read" || !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Handle() error = %v, want read TransportError", err)
+	}
+
+	err = manager.Handle(context.Background(), strings.NewReader(req), failWriter{})
+	if !errors.As(err, &te) || te.Op != "write" || !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Handle() error = %v, want write TransportError", err)
+	}
+
+	batch := `[{"jsonrpc":"2.0","method":"echo","params":"a","id":1},{"jsonrpc":"2.0","method":"echo","params":"b"},{"jsonrpc":"2.0","method":"echo","params":"c","id":"c"}]`
+	err = manager.Handle(context.Background(), strings.NewReader(batch), failWriter{})
+	var bwe *jrpc.BatchWriteError
+	if !errors.As(err, &bwe) || !errors.As(err, &te) || len(bwe.Delivered) != 0 || len(bwe.Undelivered) != 2 ||
+		string(*bwe.Undelivered[0]) != "1" || string(*bwe.Undelivered[1]) != `"c"` {
+		t.Errorf("Handle() error = %v, want BatchWriteError with 1 and \"c\" undelivered", err)
+	}
+}
+
+// failFlushWriter keeps the writes but fails to flush them to the client.
+type failFlushWriter struct {
+	bytes.Buffer
+}
+
+func (w *failFlushWriter) Flush() error {
+	return io.ErrClosedPipe
+}
+
+func TestManager_Handle_BatchWriteError(t *testing.T) {
+	manager := jrpc.NewManagerBuilder().
+		Add("echo", &echoMethod{}).
+		Add("nan", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) { resp.Result = math.NaN() })).
+		Build()
+
+	tests := []struct {
+		name            string
+		r               string
+		w               io.Writer
+		wantErr         bool
+		wantUndelivered []string
+		wantBatchErr    bool
+		wantTransport   bool
+	}{
+		{
+			name:            "Batch",
+			r:               `[{"jsonrpc":"2.0","method":"echo","params":"a","id":1},{"jsonrpc":"2.0","method":"echo","params":"b"},{"jsonrpc":"2.0","method":"echo","params":"c","id":"c"}]`,
+			w:               failWriter{},
+			wantErr:         true,
+			wantUndelivered: []string{`1`, `"c"`},
+			wantBatchErr:    true,
+			wantTransport:   true,
+		},
+		{
+			name:            "Batch With One Response",
+			r:               `[{"jsonrpc":"2.0","method":"echo","params":"a","id":1},{"jsonrpc":"2.0","method":"echo","params":"b"}]`,
+			w:               failWriter{},
+			wantErr:         true,
+			wantUndelivered: []string{`1`},
+			wantBatchErr:    true,
+			wantTransport:   true,
+		},
+		{
+			name:            "Error Response Without ID",
+			r:               `[{"jsonrpc":"2.0","method":"echo","params":"a","id":1},{"jsonrpc":"2.0","method":"echo","params":1}]`,
+			w:               failWriter{},
+			wantErr:         true,
+			wantUndelivered: []string{`1`},
+			wantBatchErr:    true,
+			wantTransport:   true,
+		},
+		{
+			name:            "Flush Failure",
+			r:               `[{"jsonrpc":"2.0","method":"echo","params":"a","id":1},{"jsonrpc":"2.0","method":"echo","params":"b","id":2}]`,
+			w:               &failFlushWriter{},
+			wantErr:         true,
+			wantUndelivered: []string{`1`, `2`},
+			wantBatchErr:    true,
+			wantTransport:   true,
+		},
+		{
+			name: "Only Notifications",
+			r:    `[{"jsonrpc":"2.0","method":"echo","params":"a"},{"jsonrpc":"2.0","method":"echo","params":"b"}]`,
+			w:    failWriter{},
+		},
+		{
+			name:          "Single Request",
+			r:             `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`,
+			w:             failWriter{},
+			wantErr:       true,
+			wantTransport: true,
+		},
+		{
+			name:    "Encoding Failure",
+			r:       `[{"jsonrpc":"2.0","method":"nan","id":1},{"jsonrpc":"2.0","method":"echo","params":"b","id":2}]`,
+			w:       &bytes.Buffer{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := manager.Handle(context.Background(), strings.NewReader(tt.r), tt.w)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+
+			var bwe *jrpc.BatchWriteError
+			if got := errors.As(err, &bwe); got != tt.wantBatchErr {
+				t.Fatalf("Handle() error = %v, BatchWriteError = %v, want %v", err, got, tt.wantBatchErr)
+			}
+			var te *jrpc.TransportError
+			if got := errors.As(err, &te); got != tt.wantTransport {
+				t.Errorf("Handle() error = %v, TransportError = %v, want %v", err, got, tt.wantTransport)
+			}
+			if !tt.wantBatchErr {
+				return
+			}
+
+			if !errors.Is(err, io.ErrClosedPipe) {
+				t.Errorf("Handle() error = %v, want to wrap %v", err, io.ErrClosedPipe)
+			}
+			if bwe.Delivered == nil || len(bwe.Delivered) != 0 {
+				t.Errorf("BatchWriteError.Delivered = %v, want empty", bwe.Delivered)
+			}
+			var undelivered []string
+			for _, id := range bwe.Undelivered {
+				undelivered = append(undelivered, string(*id))
+			}
+			if !reflect.DeepEqual(undelivered, tt.wantUndelivered) {
+				t.Errorf("BatchWriteError.Undelivered = %v, want %v", undelivered, tt.wantUndelivered)
+			}
+			want := fmt.Sprintf("jsonrpc: batch write: 0 delivered, %d undelivered: %v", len(tt.wantUndelivered), te)
+			if bwe.Error() != want {
+				t.Errorf("BatchWriteError.Error() = %v, want %v", bwe.Error(), want)
+			}
+		})
+	}
+}
+
+func TestManagerBuilder_SetStrictVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		strict bool
+		r      string
+		wantW  string
+	}{
+		{
+			name:  "Compatibility",
+			r:     `{"jsonrpc":"1.0","method":"echo","params":"a","id":1}`,
+			wantW: `{"jsonrpc":"1.0","id":1,"error":{"code":-32001,"message":"JSON RPC Version must be 2.0","data":"1.0"}}` + "\n",
+		},
+		{
+			name:   "Strict",
+			strict: true,
+			r:      `{"jsonrpc":"1.0","method":"echo","params":"a","id":1}`,
+			wantW:  `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"Invalid Request","data":"jsonrpc must be \"2.0\""}}` + "\n",
+		},
+		{
+			name:   "Strict Missing Version",
+			strict: true,
+			r:      `{"method":"echo","params":"a","id":1}`,
+			wantW:  `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"Invalid Request","data":"jsonrpc must be \"2.0\""}}` + "\n",
+		},
+		{
+			name:   "Strict Valid",
+			strict: true,
+			r:      `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`,
+			wantW:  `{"jsonrpc":"2.0","id":1,"result":"a"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := jrpc.NewManagerBuilder().
+				SetStrictVersion(tt.strict).
+				Add("echo", &echoMethod{}).
+				Build()
+			var out bytes.Buffer
+			if err := m.Handle(context.Background(), strings.NewReader(tt.r), &out); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if out.String() != tt.wantW {
+				t.Errorf("Handle() = %v, want %v", out.String(), tt.wantW)
+			}
+		})
+	}
+}
+
+func TestManager_Handle_ClientCanceled(t *testing.T) {
+	block := &blockMethod{release: make(chan struct{})}
+	defer close(block.release)
+	m := jrpc.NewManagerBuilder().
+		SetTimeout(time.Minute).
+		EnableStats().
+		Add("block", block).
+		Build()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	w := &bytes.Buffer{}
+	err := m.Handle(ctx, strings.NewReader(`{"jsonrpc":"2.0","method":"block","id":1}`), w)
+	if err != context.Canceled {
+		t.Errorf("Manager.Handle() error = %v, want %v", err, context.Canceled)
+	}
+	if w.Len() != 0 {
+		t.Errorf("Manager.Handle() wrote %v, want nothing", w.String())
+	}
+	if st := m.Stats(); st.Canceled != 1 {
+		t.Errorf("Stats().Canceled = %v, want 1", st.Canceled)
+	}
+}
+
+func TestManagerBuilder_SetMaxResponseSize(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		SetMaxResponseSize(10).
+		Add("echo", &echoMethod{}).
+		Build()
+
+	tests := []struct {
+		name  string
+		r     string
+		wantW string
+	}{
+		{
+			name:  "Within Limit",
+			r:     `{"jsonrpc":"2.0","method":"echo","id":1,"params":"12345678"}`,
+			wantW: `{"jsonrpc":"2.0","id":1,"result":"12345678"}`,
+		},
+		{
+			name:  "Over Limit",
+			r:     `{"jsonrpc":"2.0","method":"echo","id":2,"params":"123456789"}`,
+			wantW: `{"jsonrpc":"2.0","id":2,"error":{"code":-32006,"message":"Response too large","data":{"size":11,"limit":10}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			if err := m.Handle(context.Background(), strings.NewReader(tt.r), w); err != nil {
+				t.Fatalf("Manager.Handle() error = %v", err)
+			}
+			if gotW := strings.TrimSpace(w.String()); gotW != tt.wantW {
+				t.Errorf("Manager.Handle() result = %v, want %v", gotW, tt.wantW)
+			}
+		})
+	}
+}
+
+func TestManagerBuilder_SetDefaultParams(t *testing.T) {
+	params := jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+		var p json.RawMessage
+		if err := req.ParseParams(&p); err != nil {
+			resp.Error = err
+			return
+		}
+		resp.Result = p
+	})
+	m := jrpc.NewManagerBuilder().
+		SetDefaultParams("search", map[string]interface{}{"limit": 10, "filter": map[string]interface{}{"active": true, "kind": "all"}}).
+		Add("search", params).
+		Build()
+
+	tests := []struct {
+		name   string
+		params string
+		want   string
+	}{
+		{name: "No Params", params: ``, want: `{"filter":{"active":true,"kind":"all"},"limit":10}`},
+		{name: "Merged", params: `,"params":{"q":"go","filter":{"kind":"doc"}}`, want: `{"filter":{"active":true,"kind":"doc"},"limit":10,"q":"go"}`},
+		{name: "Overridden", params: `,"params":{"limit":5}`, want: `{"filter":{"active":true,"kind":"all"},"limit":5}`},
+		{name: "Positional", params: `,"params":["go"]`, want: `["go"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			r := `{"jsonrpc":"2.0","method":"search","id":1` + tt.params + `}`
+			if err := m.Handle(context.Background(), strings.NewReader(r), w); err != nil {
+				t.Fatalf("Manager.Handle() error = %v", err)
+			}
+			want := `{"jsonrpc":"2.0","id":1,"result":` + tt.want + `}`
+			if gotW := strings.TrimSpace(w.String()); gotW != want {
+				t.Errorf("Manager.Handle() result = %v, want %v", gotW, want)
+			}
+		})
+	}
+}
+
+func TestManagerBuilder_SetSlowRequestThreshold_Log(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	m := jrpc.NewManagerBuilder().
+		SetSlowRequestThreshold(time.Millisecond, nil).
+		AddPattern("*", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) { time.Sleep(2 * time.Millisecond) })).
+		Build()
+
+	r := `{"jsonrpc":"2.0","method":"a\nb","id":"1\nslow request method=forged"}`
+	if err := m.Handle(context.Background(), strings.NewReader(r), ioutil.Discard); err != nil {
+		t.Fatalf("Manager.Handle() error = %v", err)
+	}
+	got := out.String()
+	if want := `slow request method="a\nb" id="1\nslow request method=forged" `; !strings.Contains(got, want) {
+		t.Errorf("slow request log = %q, want %q", got, want)
+	}
+	if strings.Count(got, "\n") != 1 {
+		t.Errorf("slow request log = %q, want a single line", got)
+	}
+}
+
+func TestManagerBuilder_SetNotificationWorkers(t *testing.T) {
+	block := &blockMethod{release: make(chan struct{})}
+	defer close(block.release)
+
+	failures := make(chan jrpc.RequestInfo, 2)
+	m := jrpc.NewManagerBuilder().
+		SetNotificationWorkers(1, 50*time.Millisecond, func(info jrpc.RequestInfo) { failures <- info }).
+		Add("block", block).
+		Add("add", &addMethod{}).
+		Build()
+
+	r := `[{"jsonrpc":"2.0","method":"block"},{"jsonrpc":"2.0","method":"add","params":{"v1":1,"v2":2},"id":1},{"jsonrpc":"2.0","method":"block"}]`
+	w := &bytes.Buffer{}
+	if err := m.Handle(context.Background(), strings.NewReader(r), w); err != nil {
+		t.Fatalf("Manager.Handle() error = %v", err)
+	}
+	if want := `{"jsonrpc":"2.0","id":1,"result":3}`; strings.TrimSpace(w.String()) != want {
+		t.Errorf("Manager.Handle() result = %v, want %v", w.String(), want)
+	}
+
+	// The second notification is rejected while the first one is running until the budget
+	for _, code := range []jrpc.ErrorCode{-32003, -32002} {
+		select {
+		case info := <-failures:
+			if info.Method != "block" || info.Error.Code != code {
+				t.Errorf("failure = %v %v, want block %v", info.Method, info.Error.Code, code)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("failure %v not reported", code)
+		}
+	}
+}
+
+// logWriter sends each write of the standard logger to the channel.
+type logWriter chan string
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestManagerBuilder_SetNotificationWorkers_Log(t *testing.T) {
+	out := make(logWriter, 1)
+	log.SetOutput(out)
+	defer log.SetOutput(os.Stderr)
+
+	m := jrpc.NewManagerBuilder().
+		SetNotificationWorkers(1, time.Second, nil).
+		AddPattern("*", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) { resp.Error = jrpc.NewError(1, "failed") })).
+		Build()
+
+	r := `{"jsonrpc":"2.0","method":"a\nnotification failed method=forged"}`
+	if err := m.Handle(context.Background(), strings.NewReader(r), ioutil.Discard); err != nil {
+		t.Fatalf("Manager.Handle() error = %v", err)
+	}
+	select {
+	case got := <-out:
+		if want := `notification failed method="a\nnotification failed method=forged" `; !strings.Contains(got, want) {
+			t.Errorf("notification failure log = %q, want %q", got, want)
+		}
+		if strings.Count(got, "\n") != 1 {
+			t.Errorf("notification failure log = %q, want a single line", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("notification failure not logged")
+	}
+}
+
+func TestManager_Handle_RequestDecoding(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("echo", &echoMethod{}).
+		Build()
+
+	tests := []struct {
+		name   string
+		r      string
+		wantW  string
+		prefix bool
+	}{
+		{
+			name:  "ID Echoed As Sent",
+			r:     `{"id" : {"a":[1,"}"]} , "params":"x","method":"echo","jsonrpc":"2.0"}`,
+			wantW: `{"jsonrpc":"2.0","id":{"a":[1,"}"]},"result":"x"}`,
+		},
+		{
+			name:  "Members Case Insensitive",
+			r:     `{"JSONRPC":"2.0","Method":"echo","ID":1,"Params":"x"}`,
+			wantW: `{"jsonrpc":"2.0","id":1,"result":"x"}`,
+		},
+		{
+			name:  "Escaped Method",
+			r:     `{"jsonrpc":"2.0","method":"ech\u006f","id":2,"params":"x\"y"}`,
+			wantW: `{"jsonrpc":"2.0","id":2,"result":"x\"y"}`,
+		},
+		{
+			name:  "Null ID Is A Notification",
+			r:     `{"jsonrpc":"2.0","method":"echo","id":null,"params":"x"}`,
+			wantW: ``,
+		},
+		{
+			name: "Method Of Wrong Type",
+			r:    `{"jsonrpc":"2.0","method":1,"id":3}`,
+			// The message of encoding/json changes between releases
+			wantW:  `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request","data":"json: cannot unmarshal number`,
+			prefix: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			_ = m.Handle(context.Background(), strings.NewReader(tt.r), w)
+			gotW := strings.TrimSpace(w.String())
+			if tt.prefix && strings.HasPrefix(gotW, tt.wantW) {
+				gotW = tt.wantW
+			}
+			if gotW != tt.wantW {
+				t.Errorf("Manager.Handle() result = %v, want %v", gotW, tt.wantW)
+			}
+		})
+	}
+}
+
+func TestManagerBuilder_SetMemoryLimit(t *testing.T) {
+	block := &blockMethod{release: make(chan struct{})}
+	m := jrpc.NewManagerBuilder().
+		SetMemoryLimit(6000).
+		Add("block", block).
+		Add("add", &addMethod{}).
+		Build()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"block","id":1}`), ioutil.Discard)
+	}()
+	for len(m.InFlight()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	add := `{"jsonrpc":"2.0","method":"add","params":{"v1":1,"v2":2},"id":2}`
+	w := &bytes.Buffer{}
+	_ = m.Handle(context.Background(), strings.NewReader(add), w)
+	if want := `{"jsonrpc":"2.0","id":2,"error":{"code":-32003,"message":"Server overloaded"}}`; strings.TrimSpace(w.String()) != want {
+		t.Errorf("Manager.Handle() over the limit = %v, want %v", w.String(), want)
+	}
+
+	// The memory is released once the method of the blocked request returns
+	close(block.release)
+	<-done
+	for i := 0; i < 100; i++ {
+		w.Reset()
+		_ = m.Handle(context.Background(), strings.NewReader(add), w)
+		if !strings.Contains(w.String(), "-32003") {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if want := `{"jsonrpc":"2.0","id":2,"result":3}`; strings.TrimSpace(w.String()) != want {
+		t.Errorf("Manager.Handle() within the limit = %v, want %v", w.String(), want)
+	}
+}
+
+func TestManagerBuilder_SetVersionCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		vc    jrpc.VersionCheck
+		r     string
+		wantW string
+	}{
+		{
+			name:  "Strict Whitespace",
+			vc:    jrpc.VersionStrict,
+			r:     `{"jsonrpc":"2.0 ","method":"echo","params":"a","id":1}`,
+			wantW: `{"jsonrpc":"2.0 ","id":1,"error":{"code":-32001,"message":"JSON RPC Version must be 2.0","data":"2.0 "}}`,
+		},
+		{
+			name:  "Lenient Whitespace",
+			vc:    jrpc.VersionLenient,
+			r:     `{"jsonrpc":"2.0 ","method":"echo","params":"a","id":1}`,
+			wantW: `{"jsonrpc":"2.0","id":1,"result":"a"}`,
+		},
+		{
+			name:  "Strict Notification Without Version",
+			vc:    jrpc.VersionStrict,
+			r:     `{"method":"echo","params":"a"}`,
+			wantW: `{"jsonrpc":"","id":null,"error":{"code":-32001,"message":"JSON RPC Version must be 2.0","data":""}}`,
+		},
+		{
+			name:  "Lenient Notification Without Version",
+			vc:    jrpc.VersionLenient,
+			r:     `{"method":"echo","params":"a"}`,
+			wantW: ``,
+		},
+		{
+			name:  "Lenient Request Without Version",
+			vc:    jrpc.VersionLenient,
+			r:     `{"method":"echo","params":"a","id":1}`,
+			wantW: `{"jsonrpc":"","id":1,"error":{"code":-32001,"message":"JSON RPC Version must be 2.0","data":""}}`,
+		},
+		{
+			name:  "Off",
+			vc:    jrpc.VersionOff,
+			r:     `{"jsonrpc":"1.0","method":"echo","params":"a","id":1}`,
+			wantW: `{"jsonrpc":"2.0","id":1,"result":"a"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := jrpc.NewManagerBuilder().
+				SetVersionCheck(tt.vc).
+				Add("echo", &echoMethod{}).
+				Build()
+			var out bytes.Buffer
+			if err := m.Handle(context.Background(), strings.NewReader(tt.r), &out); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if got := strings.TrimSpace(out.String()); got != tt.wantW {
+				t.Errorf("Handle() = %v, want %v", got, tt.wantW)
+			}
+		})
+	}
+}
+
+// TestManager_Handle_LateMethod is meant for the race detector, the method keeps writing the
+// response after the timeout while the timeout error is encoded.
+func TestManager_Handle_LateMethod(t *testing.T) {
+	done := make(chan struct{})
+	m := jrpc.NewManagerBuilder().
+		Add("late", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			defer close(done)
+			<-req.Context().Done()
+			for i := 0; i < 100; i++ {
+				resp.SetResult(i)
+				resp.SetError(jrpc.NewError(1001, i))
+			}
+		})).
+		SetTimeout(10 * time.Millisecond).
+		Build()
+
+	var out bytes.Buffer
+	if err := m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"late","id":1}`), &out); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	<-done
+	if !strings.Contains(out.String(), `"code":-32002`) {
+		t.Errorf("Handle() = %v, want the timeout error", out.String())
+	}
+}
+
+func TestManagerBuilder_SetDeduplication(t *testing.T) {
+	clock := newFakeClock()
+	var calls int32
+	m := jrpc.NewManagerBuilder().
+		Add("create", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			resp.Result = atomic.AddInt32(&calls, 1)
+		})).
+		Add("other", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			resp.Result = atomic.AddInt32(&calls, 1)
+		})).
+		SetClock(clock).
+		SetDeduplication(time.Minute, func(ctx context.Context) string {
+			client, _ := ctx.Value(tenantKey{}).(string)
+			return client
+		}, "create").
+		Build()
+
+	call := func(client, req string) string {
+		ctx := context.WithValue(context.Background(), tenantKey{}, client)
+		var out bytes.Buffer
+		if err := m.Handle(ctx, strings.NewReader(req), &out); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+		return out.String()
+	}
+	create := `{"jsonrpc":"2.0","method":"create","params":["a"],"id":1}`
+
+	tests := []struct {
+		name    string
+		client  string
+		req     string
+		advance time.Duration
+		want    string
+	}{
+		{name: "First", client: "a", req: create, want: `"result":1`},
+		{name: "Retransmission", client: "a", req: create, want: `"result":1`},
+		{name: "Other Client", client: "b", req: create, want: `"result":2`},
+		{name: "Other Params", client: "a", req: `{"jsonrpc":"2.0","method":"create","params":["b"],"id":1}`, want: `"result":3`},
+		{name: "Not Deduplicated", client: "a", req: `{"jsonrpc":"2.0","method":"other","id":1}`, want: `"result":4`},
+		{name: "Not Deduplicated Again", client: "a", req: `{"jsonrpc":"2.0","method":"other","id":1}`, want: `"result":5`},
+		{name: "Anonymous", client: "", req: create, want: `"result":6`},
+		{name: "Window Elapsed", client: "b", req: create, advance: time.Minute, want: `"result":7`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Advance(tt.advance)
+			if got := call(tt.client, tt.req); !strings.Contains(got, tt.want) {
+				t.Errorf("Handle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagerBuilder_SetFlagProvider(t *testing.T) {
+	var disabled atomic.Value
+	disabled.Store("")
+	flags := jrpc.FlagProviderFunc(func(ctx context.Context, method string) bool {
+		return method != disabled.Load().(string)
+	})
+
+	tests := []struct {
+		name     string
+		code     jrpc.ErrorCode
+		disabled string
+		want     string
+	}{
+		{name: "Enabled", want: `{"jsonrpc":"2.0","id":1,"result":"a"}`},
+		{name: "Disabled", disabled: "echo", want: `{"jsonrpc":"2.0","id":1,"error":{"code":-32007,"message":"Method disabled","data":"echo"}}`},
+		{name: "Custom Code", code: 2001, disabled: "echo", want: `{"jsonrpc":"2.0","id":1,"error":{"code":2001,"message":"Error 2001","data":"echo"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := jrpc.NewManagerBuilder().Add("echo", &echoMethod{}).SetFlagProvider(flags, tt.code).Build()
+			disabled.Store(tt.disabled)
+
+			var out bytes.Buffer
+			if err := m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`), &out); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if got := strings.TrimSpace(out.String()); got != tt.want {
+				t.Errorf("Handle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagerBuilder_SetMemoryLimit_Pending(t *testing.T) {
+	block := &blockMethod{release: make(chan struct{})}
+	// Fits three block requests or two and the add request
+	m := jrpc.NewManagerBuilder().
+		SetMemoryLimit(12400).
+		SetBatchConcurrency(2).
+		Add("block", block).
+		Add("add", &addMethod{}).
+		Build()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		batch := `[{"jsonrpc":"2.0","method":"block","id":1},{"jsonrpc":"2.0","method":"block","id":2},{"jsonrpc":"2.0","method":"block","id":3}]`
+		_ = m.Handle(context.Background(), strings.NewReader(batch), ioutil.Discard)
+	}()
+	for len(m.InFlight()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	// The third request is decoded and waiting for the concurrency of the batch
+	time.Sleep(10 * time.Millisecond)
+
+	w := &bytes.Buffer{}
+	_ = m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"add","params":{"v1":1,"v2":2},"id":4}`), w)
+	if want := `{"jsonrpc":"2.0","id":4,"error":{"code":-32003,"message":"Server overloaded"}}`; strings.TrimSpace(w.String()) != want {
+		t.Errorf("Manager.Handle() with a pending request = %v, want %v", w.String(), want)
+	}
+	close(block.release)
+	<-done
+}
+
+func BenchmarkManager_Handle_MemoryLimit(b *testing.B) {
+	single := `{"jsonrpc":"2.0","method":"add","params":{"v1":1,"v2":2},"id":1}`
+	batch := "[" + strings.TrimSuffix(strings.Repeat(single+",", 10), ",") + "]"
+	for _, limit := range []int64{0, 1 << 30} {
+		m := jrpc.NewManagerBuilder().
+			SetMemoryLimit(limit).
+			Add("add", &addMethod{}).
+			Build()
+		for _, bb := range []struct {
+			name string
+			body string
+		}{
+			{name: "Single", body: single},
+			{name: "Batch", body: batch},
+		} {
+			b.Run(fmt.Sprintf("Limit=%d/%s", limit, bb.name), func(b *testing.B) {
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						if err := m.Handle(context.Background(), strings.NewReader(bb.body), ioutil.Discard); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+func BenchmarkManager_Handle(b *testing.B) {
+	single := `{"jsonrpc":"2.0","method":"echo","params":{"name":"ana","tags":["a","b","c"],"n":1},"id":"req-1"}`
+	large := `{"jsonrpc":"2.0","method":"echo","params":[` + strings.TrimSuffix(strings.Repeat(`{"id":1,"name":"ana"},`, 500), ",") + `],"id":1}`
+	batch := "[" + strings.TrimSuffix(strings.Repeat(single+",", 10), ",") + "]"
+	m := jrpc.NewManagerBuilder().
+		Add("echo", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) { resp.Result = true })).
+		Build()
+
+	for _, bb := range []struct {
+		name string
+		body string
+	}{
+		{name: "Single", body: single},
+		{name: "Single Large Params", body: large},
+		{name: "Batch", body: batch},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bb.body)))
+			for i := 0; i < b.N; i++ {
+				if err := m.Handle(context.Background(), strings.NewReader(bb.body), ioutil.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// benchmarkEncode will benchmark the encoding of the response of a single request and a batch of
+// 10 requests with each result.
+func benchmarkEncode(b *testing.B, mb func() *jrpc.ManagerBuilder, results []struct {
+	name   string
+	result interface{}
+}) {
+	single := `{"jsonrpc":"2.0","method":"result","id":1}`
+	batch := "[" + strings.TrimSuffix(strings.Repeat(single+",", 10), ",") + "]"
+	for _, rr := range results {
+		result := rr.result
+		m := mb().
+			Add("result", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) { resp.Result = result })).
+			Build()
+		for _, bb := range []struct {
+			name string
+			body string
+		}{
+			{name: "Single", body: single},
+			{name: "Batch", body: batch},
+		} {
+			b.Run(rr.name+"/"+bb.name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if err := m.Handle(context.Background(), strings.NewReader(bb.body), ioutil.Discard); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkEncode_FastPath(b *testing.B) {
+	benchmarkEncode(b, func() *jrpc.ManagerBuilder {
+		return jrpc.NewManagerBuilder().SetResultEncoder(point{}, encodePoint)
+	}, []struct {
+		name   string
+		result interface{}
+	}{
+		{name: "Int64", result: int64(9007199254740993)},
+		{name: "String", result: "a plain string result"},
+		{name: "Raw", result: json.RawMessage(`{"a":[1,"b"],"c":{"d":true}}`)},
+		{name: "Registered Encoder", result: point{X: 1, Y: -2}},
+	})
+}
+
+func BenchmarkEncode_Fallback(b *testing.B) {
+	results := []struct {
+		name   string
+		result interface{}
+	}{
+		{name: "Struct", result: point{X: 1, Y: -2}},
+		{name: "String With Escapes", result: "a \"quoted\" <string>"},
+		{name: "Raw With Spaces", result: json.RawMessage(`{ "a": [1, "b"], "c": { "d": true } }`)},
+	}
+	benchmarkEncode(b, jrpc.NewManagerBuilder, results)
+	b.Run("Indent", func(b *testing.B) {
+		benchmarkEncode(b, func() *jrpc.ManagerBuilder {
+			return jrpc.NewManagerBuilder().SetResultEncoder(point{}, encodePoint).SetIndent("", "  ")
+		}, results[:1])
+	})
+	b.Run("Sort Keys", func(b *testing.B) {
+		benchmarkEncode(b, func() *jrpc.ManagerBuilder {
+			return jrpc.NewManagerBuilder().SetResultEncoder(point{}, encodePoint).SetSortKeys(true)
+		}, results[:1])
+	})
+}
@@ -0,0 +1,101 @@
+package jrpc2go
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MediaCodec converts the bodies of a media type, like application/msgpack or application/cbor,
+// from and to JSON, so the clients of each format are served on the same endpoint. The package
+// has no dependencies so the codecs are adapters over the libraries of each format.
+type MediaCodec interface {
+	// Decode returns the JSON of the request body.
+	Decode(body []byte) (json.RawMessage, error)
+
+	// Encode returns the response body of the JSON.
+	Encode(msg json.RawMessage) ([]byte, error)
+}
+
+// WithMediaCodec will accept the requests with the media type on the Content-Type header and
+// reply with it when it's preferred by the Accept header, the responses are in the media type
+// of the request when the Accept header has no preference.
+func WithMediaCodec(mediaType string, c MediaCodec) HTTPOption {
+	return func(h *httpHandler) {
+		if h.codecs == nil {
+			h.codecs = make(map[string]MediaCodec)
+		}
+		h.codecs[strings.ToLower(mediaType)] = c
+	}
+}
+
+// requestCodec returns the media type and the codec of the Content-Type header, the codec is nil
+// for JSON and ok is false if the media type is not accepted.
+func (h *httpHandler) requestCodec(header string) (mediaType string, c MediaCodec, ok bool) {
+	if acceptContentType(header, h.contentTypes) {
+		return contentTypeValue, nil, true
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", nil, false
+	}
+	c, ok = h.codecs[mediaType]
+	return mediaType, c, ok
+}
+
+// responseCodec returns the media type and the codec of the response, the one preferred by the
+// Accept header or the one of the request.
+func (h *httpHandler) responseCodec(accept, reqType string, reqCodec MediaCodec) (string, MediaCodec) {
+	if len(h.codecs) == 0 || accept == "" {
+		return reqType, reqCodec
+	}
+	type option struct {
+		mediaType string
+		q         float64
+	}
+	var options []option
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			options = append(options, option{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(options, func(i, j int) bool { return options[i].q > options[j].q })
+
+	for _, o := range options {
+		switch {
+		case o.mediaType == reqType || o.mediaType == "*/*" || o.mediaType == "application/*":
+			return reqType, reqCodec
+		case o.mediaType == contentTypeValue:
+			return contentTypeValue, nil
+		}
+		if c, ok := h.codecs[o.mediaType]; ok {
+			return o.mediaType, c
+		}
+	}
+	return reqType, reqCodec
+}
+
+// decodeBody returns the JSON of the request body, the body is returned as it is for JSON.
+func decodeBody(c MediaCodec, body []byte) ([]byte, *HTTPError) {
+	if c == nil {
+		return body, nil
+	}
+	b, err := c.Decode(body)
+	if err != nil {
+		return nil, &HTTPError{Status: http.StatusBadRequest, Err: err}
+	}
+	return b, nil
+}
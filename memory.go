@@ -0,0 +1,123 @@
+package jrpc2go
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// requestOverhead is the approximate memory held by each request besides its method, ID and
+// params, like the Request, the Response and the goroutine that executes it.
+const requestOverhead = 4096
+
+// SetMemoryLimit sets the approximate bytes that can be held by the requests, the size of a
+// request is its method, ID and params plus a fixed overhead. A request is charged once it's
+// decoded, so the ones waiting for the concurrency of a batch or a notification worker are
+// counted too, until its method returns. The requests over the limit are rejected with the
+// server overloaded error, so a burst of large requests on the batches or the queues can't
+// exhaust the memory of the process.
+//
+// The limit is shared by the derived managers created with Manager.With.
+//
+// Default is 0, which means no limit.
+func (mb *ManagerBuilder) SetMemoryLimit(bytes int64) *ManagerBuilder {
+	mb.memoryLimit = bytes
+	return mb
+}
+
+// memoryBudget tracks the bytes held by the requests decoded and not finished yet.
+type memoryBudget struct {
+	// used is accessed atomically and it's the first field to keep it 64-bit aligned
+	used  int64
+	limit int64
+}
+
+// newMemoryBudget returns the budget or nil if the limit is not positive.
+func newMemoryBudget(limit int64) *memoryBudget {
+	if limit <= 0 {
+		return nil
+	}
+	return &memoryBudget{limit: limit}
+}
+
+// acquire will reserve the bytes and return true if they are within the limit, nothing is
+// reserved when it returns false.
+func (b *memoryBudget) acquire(n int64) bool {
+	if b == nil {
+		return true
+	}
+	if atomic.AddInt64(&b.used, n) > b.limit {
+		atomic.AddInt64(&b.used, -n)
+		return false
+	}
+	return true
+}
+
+// release will return the bytes reserved by acquire.
+func (b *memoryBudget) release(n int64) {
+	if b != nil {
+		atomic.AddInt64(&b.used, -n)
+	}
+}
+
+// memoryCharge is the bytes of a request reserved on the budget, they are returned when all
+// its holders released it, like the batch waiting for the response and the goroutine of the
+// method that can outlive it after a timeout.
+type memoryCharge struct {
+	b    *memoryBudget
+	n    int64
+	refs int32
+}
+
+// charge will reserve the bytes of the request with one holder, it returns false if they are
+// over the limit. The charge is nil when there is no limit.
+func (b *memoryBudget) charge(req *Request) (*memoryCharge, bool) {
+	if b == nil {
+		return nil, true
+	}
+	n := requestSize(req)
+	if !b.acquire(n) {
+		return nil, false
+	}
+	return &memoryCharge{b: b, n: n, refs: 1}, true
+}
+
+// hold will add a holder to the charge.
+func (c *memoryCharge) hold() {
+	if c != nil {
+		atomic.AddInt32(&c.refs, 1)
+	}
+}
+
+// release will remove a holder from the charge, the bytes are returned by the last one.
+func (c *memoryCharge) release() {
+	if c != nil && atomic.AddInt32(&c.refs, -1) == 0 {
+		c.b.release(c.n)
+	}
+}
+
+// withMemoryCharge returns a copy of ctx with the charge of the request being handled, it
+// returns ctx if the charge is nil.
+func withMemoryCharge(ctx context.Context, c *memoryCharge) context.Context {
+	if c == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, memoryKey, c)
+}
+
+// memoryChargeFromContext returns the charge set with withMemoryCharge or nil if none.
+func memoryChargeFromContext(ctx context.Context) *memoryCharge {
+	c, _ := ctx.Value(memoryKey).(*memoryCharge)
+	return c
+}
+
+// requestSize returns the approximate bytes held by the request.
+func requestSize(req *Request) int64 {
+	n := int64(requestOverhead + len(req.Method))
+	if req.ID != nil {
+		n += int64(len(*req.ID))
+	}
+	if req.Params != nil {
+		n += int64(len(*req.Params))
+	}
+	return n
+}
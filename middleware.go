@@ -0,0 +1,21 @@
+package jrpc2go
+
+// MethodFunc type is an adapter to allow the use of ordinary functions as a Method.
+type MethodFunc func(req *Request, resp *Response)
+
+// Execute calls f(req, resp).
+func (f MethodFunc) Execute(req *Request, resp *Response) {
+	f(req, resp)
+}
+
+// Middleware wraps a Method to run logic before and after its execution, like logging or
+// authorization, it should call the next Method to continue the execution.
+type Middleware func(next Method) Method
+
+// chain will wrap the method with the middleware, the first middleware is the outermost one.
+func chain(method Method, mw []Middleware) Method {
+	for i := len(mw) - 1; i >= 0; i-- {
+		method = mw[i](method)
+	}
+	return method
+}
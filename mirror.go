@@ -0,0 +1,120 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// MirrorTarget receives the copies of the requests duplicated by the Mirror middleware, the
+// target is called on its own goroutine and any result is ignored.
+type MirrorTarget interface {
+	Mirror(ctx context.Context, req *Request)
+}
+
+// MirrorFunc type is an adapter to allow the use of ordinary functions as a MirrorTarget.
+type MirrorFunc func(ctx context.Context, req *Request)
+
+// Mirror calls f(ctx, req).
+func (f MirrorFunc) Mirror(ctx context.Context, req *Request) {
+	f(ctx, req)
+}
+
+// MirrorPolicy configures the Mirror middleware.
+//
+// Match - Accepts the requests duplicated, if it's nil all requests are duplicated.
+//
+// MaxInFlight - The maximum number of copies the target executes at the same time, the requests
+// over it are not duplicated so a slow target can't pile up goroutines. Default is 64.
+//
+// Timeout - The maximum time of each copy, its context is canceled after it. Default is 5 seconds.
+type MirrorPolicy struct {
+	Match       func(req *Request) bool
+	MaxInFlight int
+	Timeout     time.Duration
+}
+
+// Mirror returns a middleware that duplicates the requests accepted by the policy to the target
+// without waiting for it, so a new implementation can be validated with production traffic safely.
+//
+// The target receives a copy of the request with a background context, since the original
+// context is canceled once the request finishes, and its own values, so the values set by the
+// target and by the primary method don't mix.
+func Mirror(target MirrorTarget, policy MirrorPolicy) Middleware {
+	if target == nil {
+		panic("jsonrpc: mirror target should not be nil")
+	}
+	if policy.MaxInFlight <= 0 {
+		policy.MaxInFlight = 64
+	}
+	if policy.Timeout <= 0 {
+		policy.Timeout = 5 * time.Second
+	}
+	sem := make(chan struct{}, policy.MaxInFlight)
+	return func(next Method) Method {
+		return MethodFunc(func(req *Request, resp *Response) {
+			if policy.Match == nil || policy.Match(req) {
+				select {
+				case sem <- struct{}{}:
+					// The copy is made before the primary method can change the values
+					ctx, cancel := context.WithTimeout(context.Background(), policy.Timeout)
+					if id := CorrelationIDFromContext(req.Context()); id != "" {
+						ctx = ContextWithCorrelationID(ctx, id)
+					}
+					mreq := req.WithContext(ctx)
+					mreq.values = req.values.clone()
+					go func() {
+						defer func() {
+							cancel()
+							<-sem
+						}()
+						target.Mirror(ctx, mreq)
+					}()
+				default:
+					// The target is saturated, the copy is dropped
+				}
+			}
+			next.Execute(req, resp)
+		})
+	}
+}
+
+// ManagerMirror returns a MirrorTarget that executes the requests on the secondary Manager m
+// and ignores its responses, the timeout and other policies of m are applied.
+func ManagerMirror(m *Manager) MirrorTarget {
+	return MirrorFunc(func(ctx context.Context, req *Request) {
+		_ = m.execMethod(ctx, req)
+	})
+}
+
+// HTTPMirror returns a MirrorTarget that posts the requests to the JSON RPC endpoint at url and
+// ignores its responses. If client is nil a client with a 5 seconds timeout is used.
+func HTTPMirror(url string, client *http.Client) MirrorTarget {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return MirrorFunc(func(ctx context.Context, req *Request) {
+		body, err := json.Marshal(req)
+		if err != nil {
+			return
+		}
+		hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+		hreq.Header.Set(contentTypeKey, contentTypeValue)
+		if id := CorrelationIDFromContext(ctx); id != "" {
+			hreq.Header.Set(CorrelationIDHeader, id)
+		}
+		hresp, err := client.Do(hreq)
+		if err != nil {
+			return
+		}
+		_, _ = io.Copy(ioutil.Discard, hresp.Body)
+		_ = hresp.Body.Close()
+	})
+}
@@ -0,0 +1,141 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+type recordMethod struct {
+	calls chan string
+}
+
+func (m *recordMethod) Execute(req *jrpc.Request, resp *jrpc.Response) {
+	m.calls <- req.Method
+	resp.Result = "ignored"
+}
+
+func TestMirror(t *testing.T) {
+	shadow := &recordMethod{calls: make(chan string, 2)}
+	secondary := jrpc.NewManagerBuilder().
+		AddPattern("*", shadow).
+		Build()
+
+	posted := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		posted <- string(b)
+	}))
+	defer srv.Close()
+
+	onlyEcho := func(req *jrpc.Request) bool { return req.Method == "echo" }
+	m := jrpc.NewManagerBuilder().
+		Use(jrpc.Mirror(jrpc.ManagerMirror(&secondary), jrpc.MirrorPolicy{Match: onlyEcho})).
+		Use(jrpc.Mirror(jrpc.HTTPMirror(srv.URL, nil), jrpc.MirrorPolicy{Match: onlyEcho})).
+		Add("echo", &echoMethod{}).
+		Add("add", &addMethod{}).
+		Build()
+
+	for _, r := range []string{
+		`{"jsonrpc":"2.0","method":"add","id":1,"params":{"v1":1,"v2":1}}`,
+		`{"jsonrpc":"2.0","method":"echo","id":2,"params":"x"}`,
+	} {
+		w := &bytes.Buffer{}
+		if err := m.Handle(context.Background(), strings.NewReader(r), w); err != nil {
+			t.Fatalf("Manager.Handle() error = %v", err)
+		}
+		if strings.Contains(w.String(), "ignored") {
+			t.Errorf("Manager.Handle() result = %v, want the primary response", w.String())
+		}
+	}
+
+	select {
+	case got := <-shadow.calls:
+		if got != "echo" {
+			t.Errorf("mirrored method = %v, want echo", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request not mirrored to the manager")
+	}
+
+	select {
+	case got := <-posted:
+		want := `{"jsonrpc":"2.0","method":"echo","id":2,"params":"x"}`
+		if got != want {
+			t.Errorf("mirrored body = %v, want %v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request not mirrored to the endpoint")
+	}
+
+	select {
+	case got := <-shadow.calls:
+		t.Errorf("unexpected mirrored method %v", got)
+	default:
+	}
+}
+
+func TestMirror_Policy(t *testing.T) {
+	release := make(chan struct{})
+	mirrored := make(chan error, 2)
+	target := jrpc.MirrorFunc(func(ctx context.Context, req *jrpc.Request) {
+		// The copy has its own values
+		req.Set("mirror", true)
+		if _, ok := req.Get("user"); !ok {
+			mirrored <- errors.New("value set before the mirror is missing")
+			return
+		}
+		select {
+		case <-release:
+			mirrored <- nil
+		case <-ctx.Done():
+			mirrored <- ctx.Err()
+		}
+	})
+	m := jrpc.NewManagerBuilder().
+		Use(func(next jrpc.Method) jrpc.Method {
+			return jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+				req.Set("user", "alice")
+				next.Execute(req, resp)
+			})
+		}).
+		Use(jrpc.Mirror(target, jrpc.MirrorPolicy{MaxInFlight: 1, Timeout: 50 * time.Millisecond})).
+		Add("echo", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			_, resp.Result = req.Get("mirror")
+		})).
+		Build()
+
+	for i := 0; i < 2; i++ {
+		w := &bytes.Buffer{}
+		if err := m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"echo","id":1}`), w); err != nil {
+			t.Fatalf("Manager.Handle() error = %v", err)
+		}
+		if want := `{"jsonrpc":"2.0","id":1,"result":false}` + "\n"; w.String() != want {
+			t.Errorf("Manager.Handle() = %v, want %v", w.String(), want)
+		}
+	}
+
+	// The second copy is dropped while the first one is in flight, which is canceled on timeout
+	select {
+	case err := <-mirrored:
+		if err != context.DeadlineExceeded {
+			t.Errorf("mirror error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request not mirrored")
+	}
+	close(release)
+	select {
+	case err := <-mirrored:
+		t.Errorf("unexpected mirrored request, error = %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,108 @@
+package jrpc2go
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// SetNotificationWorkers will execute the notifications asynchronously on a pool of up to n
+// workers, decoupled from the responses, so a slow notification doesn't hold the reply of the
+// batch and its timeout doesn't produce an error nobody receives.
+//
+// The notifications are executed with the budget as timeout, instead of the method timeouts, or
+// the timeout of the Manager if the budget is 0. Their failures, including the ones rejected
+// because all the workers are busy, are reported to fn. When fn is nil the failures are written
+// to the standard logger.
+//
+// Default is 0, which means the notifications are executed like the other requests.
+func (mb *ManagerBuilder) SetNotificationWorkers(n int, budget time.Duration, fn func(RequestInfo)) *ManagerBuilder {
+	mb.notificationWorkers = n
+	mb.notificationBudget = budget
+	mb.notificationFunc = fn
+	return mb
+}
+
+// notificationPool limits the notifications being executed asynchronously.
+type notificationPool struct {
+	sem    chan struct{}
+	budget time.Duration
+	fn     func(RequestInfo)
+}
+
+// newNotificationPool returns the pool or nil if n is not positive.
+func newNotificationPool(n int, budget time.Duration, fn func(RequestInfo)) *notificationPool {
+	if n <= 0 {
+		return nil
+	}
+	if fn == nil {
+		fn = logNotificationFailure
+	}
+	return &notificationPool{sem: make(chan struct{}, n), budget: budget, fn: fn}
+}
+
+// logNotificationFailure is the default callback for the failed notifications.
+func logNotificationFailure(info RequestInfo) {
+	// The method is quoted since it's sent by the client
+	log.Printf("jrpc2go: notification failed method=%q correlation=%s elapsed=%v error=%v",
+		info.Method, info.CorrelationID, info.Elapsed, info.Error)
+}
+
+// dispatch will execute the notification on a worker without waiting for it, the notification
+// is rejected if all the workers are busy.
+func (m *Manager) dispatch(ctx context.Context, req *Request) {
+	p := m.notifications
+	ctx = correlate(detach(ctx), req)
+	select {
+	case p.sem <- struct{}{}:
+	default:
+		p.report(req, CorrelationIDFromContext(ctx), newError(errCodeServerOverloaded, m.retryData()), 0)
+		m.emitRequest(ctx, OverloadRejected, req)
+		return
+	}
+	// The charge of the request is held while it waits for the worker to start
+	charge := memoryChargeFromContext(ctx)
+	charge.hold()
+	go func() {
+		defer func() { <-p.sem }()
+		defer charge.release()
+		start := m.clock.Now()
+		budget := p.budget
+		if budget <= 0 {
+			budget = m.timeout
+		}
+		res := m.run(ctx, req, budget)
+		if res.Error != nil {
+			p.report(req, CorrelationIDFromContext(ctx), res.Error, m.clock.Now().Sub(start))
+		}
+	}()
+}
+
+// report will invoke the callback with the failure of the notification.
+func (p *notificationPool) report(req *Request, id string, err *Error, elapsed time.Duration) {
+	info := RequestInfo{
+		Method:        req.Method,
+		CorrelationID: id,
+		Elapsed:       elapsed,
+		Error:         err,
+	}
+	if req.Params != nil {
+		info.ParamsSize = len(*req.Params)
+	}
+	p.fn(info)
+}
+
+// detachedContext keeps the values of the parent context without its cancellation and deadline,
+// so the notifications outlive the request that carried them.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// detach returns a context with the values of ctx that is never canceled.
+func detach(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
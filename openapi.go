@@ -0,0 +1,127 @@
+package jrpc2go
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// openAPIPath is the route, relative to the REST bridge prefix, where the OpenAPI document is served.
+const openAPIPath = "openapi.json"
+
+// MethodInfo describes a method for the generated documentation.
+//
+// Summary - A short summary of what the method does.
+//
+// Description - A verbose explanation of the method behavior.
+//
+// Params - A value of the type the method parses the params into, like addMethodParams{}.
+//
+// Result - A value of the type the method replies with.
+type MethodInfo struct {
+	Summary     string
+	Description string
+	Params      interface{}
+	Result      interface{}
+}
+
+// Describe will add the description of a method that is used by the generated documentation,
+// the method doesn't need to be added before it's described.
+func (mb *ManagerBuilder) Describe(name string, info MethodInfo) *ManagerBuilder {
+	mb.infos[name] = info
+	return mb
+}
+
+// OpenAPI returns the OpenAPI 3 document of the routes exposed by the REST bridge with the prefix,
+// generated from the method descriptions and the types of the params and results.
+//
+// Only the methods added with Add are documented, the methods without a description have
+// schemas that accept any value.
+func (m *Manager) OpenAPI(prefix, title, version string) ([]byte, error) {
+	m.table.mu.RLock()
+	names := make([]string, 0, len(m.table.methods))
+	for name := range m.table.methods {
+		names = append(names, name)
+	}
+	infos := make(map[string]MethodInfo, len(names))
+	deprecated := make(map[string]bool, len(m.table.deprecated))
+	for _, name := range names {
+		infos[name] = m.table.infos[name]
+		_, deprecated[name] = m.table.deprecated[name]
+	}
+	m.table.mu.RUnlock()
+	sort.Strings(names)
+
+	g := newSchemaGenerator("#/components/schemas/")
+	errorRef := map[string]interface{}{"$ref": "#/components/schemas/Error"}
+	prefix = trimSlash(prefix)
+
+	paths := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		info := infos[name]
+		op := map[string]interface{}{
+			"operationId": name,
+			"requestBody": map[string]interface{}{
+				"required": info.Params != nil,
+				"content":  jsonContent(g.schemaOf(info.Params)),
+			},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "The method result",
+					"content":     jsonContent(g.schemaOf(info.Result)),
+				},
+				"default": map[string]interface{}{
+					"description": "The JSON RPC error",
+					"content":     jsonContent(errorRef),
+				},
+			},
+		}
+		if info.Summary != "" {
+			op["summary"] = info.Summary
+		}
+		if info.Description != "" {
+			op["description"] = info.Description
+		}
+		if deprecated[name] {
+			op["deprecated"] = true
+		}
+		paths[prefix+"/"+name] = map[string]interface{}{"post": op}
+	}
+
+	schemas := g.definitions
+	schemas["Error"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"code":    map[string]interface{}{"type": "integer"},
+			"message": map[string]interface{}{"type": "string"},
+			"data":    map[string]interface{}{},
+		},
+		"required": []string{"code", "message"},
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	})
+}
+
+// jsonContent returns the OpenAPI content object for a JSON media type with the schema.
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		contentTypeValue: map[string]interface{}{"schema": schema},
+	}
+}
+
+// trimSlash returns s without the trailing slashes.
+func trimSlash(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '/' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,259 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrContractViolation is matched with errors.Is by the ContractError returned by the Client when
+// a result doesn't match the OpenRPC document.
+var ErrContractViolation = errors.New("jsonrpc: response violates the contract")
+
+// ContractError is returned by Client.Call when the result of a method doesn't match its schema
+// on the OpenRPC document.
+//
+// Method - The name of the method called.
+//
+// Path - The JSON path of the invalid value on the result, like "$.items[2].name".
+//
+// Reason - Why the value is invalid.
+type ContractError struct {
+	Method string
+	Path   string
+	Reason string
+}
+
+func (e *ContractError) Error() string {
+	return fmt.Sprintf("jsonrpc: %s result %s %s", e.Method, e.Path, e.Reason)
+}
+
+// Unwrap returns ErrContractViolation.
+func (e *ContractError) Unwrap() error {
+	return ErrContractViolation
+}
+
+// OpenRPC is an OpenRPC document loaded to validate the results received by the Client.
+//
+// The schemas support the keywords type, enum, properties, required, additionalProperties,
+// items, allOf, anyOf, oneOf and the $ref to #/components/schemas, the others are ignored.
+type OpenRPC struct {
+	results map[string]json.RawMessage
+	schemas map[string]json.RawMessage
+}
+
+// LoadOpenRPC reads the OpenRPC document from r.
+func LoadOpenRPC(r io.Reader) (*OpenRPC, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Methods []struct {
+			Name   string `json:"name"`
+			Result *struct {
+				Schema json.RawMessage `json:"schema"`
+			} `json:"result"`
+		} `json:"methods"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("jsonrpc: invalid OpenRPC document: %v", err)
+	}
+
+	o := &OpenRPC{results: make(map[string]json.RawMessage), schemas: doc.Components.Schemas}
+	for _, m := range doc.Methods {
+		if m.Result != nil && len(m.Result.Schema) > 0 {
+			o.results[m.Name] = m.Result.Schema
+		}
+	}
+	return o, nil
+}
+
+// validateResult returns a *ContractError if the raw result doesn't match the result schema of
+// the method, the methods not on the document are not validated.
+func (o *OpenRPC) validateResult(method string, raw json.RawMessage) error {
+	schema, ok := o.results[method]
+	if !ok {
+		return nil
+	}
+	if len(raw) == 0 {
+		raw = json.RawMessage("null")
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return &ContractError{Method: method, Path: "$", Reason: err.Error()}
+	}
+	if path, reason := o.validate(schema, v, "$", 0); reason != "" {
+		return &ContractError{Method: method, Path: path, Reason: reason}
+	}
+	return nil
+}
+
+// jsonSchema is the subset of the JSON Schema keywords validated.
+type jsonSchema struct {
+	Ref                  string                     `json:"$ref"`
+	Type                 interface{}                `json:"type"`
+	Enum                 []interface{}              `json:"enum"`
+	Properties           map[string]json.RawMessage `json:"properties"`
+	Required             []string                   `json:"required"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+	Items                json.RawMessage            `json:"items"`
+	AllOf                []json.RawMessage          `json:"allOf"`
+	AnyOf                []json.RawMessage          `json:"anyOf"`
+	OneOf                []json.RawMessage          `json:"oneOf"`
+}
+
+// maxSchemaDepth stops the validation of recursive references without data to consume.
+const maxSchemaDepth = 64
+
+// validate returns the path and the reason of the first value of v that doesn't match the
+// schema, the reason is empty if v is valid.
+func (o *OpenRPC) validate(raw json.RawMessage, v interface{}, path string, depth int) (string, string) {
+	if depth > maxSchemaDepth {
+		return path, "has a schema too deep"
+	}
+	if b := bytes.TrimSpace(raw); len(b) == 0 || string(b) == "true" {
+		return "", ""
+	} else if string(b) == "false" {
+		return path, "is not allowed"
+	}
+	var s jsonSchema
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&s); err != nil {
+		return path, "has an invalid schema: " + err.Error()
+	}
+
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		ref, ok := o.schemas[name]
+		if !ok {
+			return path, "references the unknown schema " + s.Ref
+		}
+		if p, r := o.validate(ref, v, path, depth+1); r != "" {
+			return p, r
+		}
+	}
+
+	if s.Type != nil && !matchesType(s.Type, v) {
+		return path, fmt.Sprintf("is %s, want %v", jsonType(v), s.Type)
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		return path, "is not one of the enum values"
+	}
+
+	for _, sub := range s.AllOf {
+		if p, r := o.validate(sub, v, path, depth+1); r != "" {
+			return p, r
+		}
+	}
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, sub := range s.AnyOf {
+			if _, r := o.validate(sub, v, path, depth+1); r == "" {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return path, "doesn't match any of the anyOf schemas"
+		}
+	}
+	if len(s.OneOf) > 0 {
+		matched := 0
+		for _, sub := range s.OneOf {
+			if _, r := o.validate(sub, v, path, depth+1); r == "" {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return path, fmt.Sprintf("matches %d of the oneOf schemas, want 1", matched)
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				return path, "is missing the required property " + name
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub, ok := s.Properties[k]
+			if !ok {
+				sub = s.AdditionalProperties
+			}
+			if p, r := o.validate(sub, val[k], path+"."+k, depth+1); r != "" {
+				return p, r
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			if p, r := o.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i), depth+1); r != "" {
+				return p, r
+			}
+		}
+	}
+	return "", ""
+}
+
+// matchesType reports if v is of the JSON Schema type t, a string or a list of strings.
+func matchesType(t interface{}, v interface{}) bool {
+	types, ok := t.([]interface{})
+	if !ok {
+		types = []interface{}{t}
+	}
+	got := jsonType(v)
+	for _, want := range types {
+		if want == got || (want == "number" && got == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type of the decoded value v.
+func jsonType(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := val.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+// inEnum reports if v is equal to one of the enum values.
+func inEnum(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package jrpc2go
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ManagerEventKind is the kind of an operational ManagerEvent.
+type ManagerEventKind int
+
+const (
+	// MethodRegistered is emitted by Build for every method and by Manager.Replace.
+	MethodRegistered ManagerEventKind = iota + 1
+	// TimeoutOccurred is emitted when the execution of a request exceeds its timeout.
+	TimeoutOccurred
+	// OverloadRejected is emitted when a request or a notification is rejected with the
+	// Server Overloaded error, or a connection by the Server admission limits.
+	OverloadRejected
+	// ConnectionOpened is emitted when the Server accepts a connection.
+	ConnectionOpened
+	// ConnectionClosed is emitted when a connection of the Server is closed.
+	ConnectionClosed
+)
+
+var eventKindNames = map[ManagerEventKind]string{
+	MethodRegistered: "MethodRegistered",
+	TimeoutOccurred:  "TimeoutOccurred",
+	OverloadRejected: "OverloadRejected",
+	ConnectionOpened: "ConnectionOpened",
+	ConnectionClosed: "ConnectionClosed",
+}
+
+func (k ManagerEventKind) String() string {
+	if name, ok := eventKindNames[k]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// ManagerEvent is an operational event of the Manager or of a Server of the Manager.
+//
+// Method - The method registered or of the request, empty for the connection events.
+//
+// ID - The ID of the request, nil for notifications and the other events.
+//
+// RemoteAddr - The address of the connection, nil for the events of the requests not received
+// by a Server.
+type ManagerEvent struct {
+	Kind       ManagerEventKind
+	Time       time.Time
+	Method     string
+	ID         *json.RawMessage
+	RemoteAddr net.Addr
+}
+
+// OnEvent registers fn to receive the operational events, so the application can react to them
+// like raising an alert or scaling out. It can be called more than once to register more callbacks.
+//
+// The callbacks are called on the goroutine that caused the event so they must not block.
+func (mb *ManagerBuilder) OnEvent(fn func(ManagerEvent)) *ManagerBuilder {
+	mb.eventFuncs = append(mb.eventFuncs, fn)
+	return mb
+}
+
+// SubscribeEvents returns a channel that receives the events emitted after the call, the events are
+// dropped when the buffer is full so a slow reader never blocks the Manager. The channel is
+// closed by the cancel function.
+func (m *Manager) SubscribeEvents(buffer int) (events <-chan ManagerEvent, cancel func()) {
+	ch := make(chan ManagerEvent, buffer)
+	bus := m.events
+	bus.mu.Lock()
+	bus.seq++
+	key := bus.seq
+	bus.subs[key] = ch
+	bus.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			bus.mu.Lock()
+			delete(bus.subs, key)
+			bus.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// eventHub delivers the events to the callbacks and to the subscribed channels, it's shared by
+// the Manager and its derived managers.
+type eventHub struct {
+	fns []func(ManagerEvent)
+
+	mu   sync.RWMutex
+	seq  int
+	subs map[int]chan ManagerEvent
+}
+
+func newEventHub(fns []func(ManagerEvent)) *eventHub {
+	return &eventHub{fns: fns, subs: make(map[int]chan ManagerEvent)}
+}
+
+// emit will deliver the event stamped with the Manager clock.
+func (m *Manager) emit(e ManagerEvent) {
+	bus := m.events
+	if bus == nil {
+		return
+	}
+	e.Time = m.clock.Now()
+	for _, fn := range bus.fns {
+		fn(e)
+	}
+	bus.mu.RLock()
+	defer bus.mu.RUnlock()
+	for _, ch := range bus.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// emitRequest will emit the event of the request.
+func (m *Manager) emitRequest(ctx context.Context, kind ManagerEventKind, req *Request) {
+	m.emit(ManagerEvent{Kind: kind, Method: req.Method, ID: req.ID, RemoteAddr: RemoteAddrFromContext(ctx)})
+}
+
+// emitRegistered will emit the registration of all the methods of the table, the versions are
+// named `name@version`.
+func (m *Manager) emitRegistered() {
+	if len(m.events.fns) == 0 {
+		return
+	}
+	names := make([]string, 0, len(m.table.methods))
+	for name := range m.table.methods {
+		names = append(names, name)
+	}
+	for name, vs := range m.table.versions {
+		for _, v := range vs {
+			names = append(names, name+"@"+v.version)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m.emit(ManagerEvent{Kind: MethodRegistered, Method: name})
+	}
+}
@@ -0,0 +1,52 @@
+package jrpc2go_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestManagerBuilder_OnEvent(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Add("slow", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			time.Sleep(50 * time.Millisecond)
+		})).
+		SetTimeout(10 * time.Millisecond).
+		OnEvent(func(e jrpc.ManagerEvent) {
+			mu.Lock()
+			got = append(got, e.Kind.String()+" "+e.Method)
+			mu.Unlock()
+		}).
+		Build()
+	events, cancel := m.SubscribeEvents(1)
+	defer cancel()
+
+	var out strings.Builder
+	_ = m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"slow","id":1}`), &out)
+	if err := m.Replace("add", &addMethod{}); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"MethodRegistered add", "MethodRegistered slow", "TimeoutOccurred slow", "MethodRegistered add"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	// The buffer of the subscription only keeps the first event
+	if e := <-events; e.Kind != jrpc.TimeoutOccurred || e.Method != "slow" || e.Time.IsZero() {
+		t.Errorf("SubscribeEvents() = %+v, want the timeout of slow", e)
+	}
+	select {
+	case e := <-events:
+		t.Errorf("SubscribeEvents() = %+v, want the event dropped", e)
+	default:
+	}
+}
@@ -0,0 +1,84 @@
+package jrpc2go
+
+import "time"
+
+// Option overrides a configuration of a derived Manager created with Manager.With.
+type Option func(*Manager)
+
+// WithTimeout overrides the timeout for each method execution.
+func WithTimeout(timeout time.Duration) Option {
+	return func(m *Manager) {
+		m.timeout = timeout
+	}
+}
+
+// WithBatchTimeout overrides the deadline for the execution of a whole batch request.
+func WithBatchTimeout(timeout time.Duration) Option {
+	return func(m *Manager) {
+		m.batchTimeout = timeout
+	}
+}
+
+// WithMaxInFlight overrides the limit of methods executing at the same time.
+//
+// Derived managers count their executions independently from the Manager they derive from.
+func WithMaxInFlight(n int) Option {
+	return func(m *Manager) {
+		m.maxInFlight = int64(n)
+	}
+}
+
+// WithRetryAfter overrides the retry delay suggested on the timeout and overload errors.
+func WithRetryAfter(d time.Duration) Option {
+	return func(m *Manager) {
+		m.retryAfter = d
+	}
+}
+
+// WithMiddleware replaces the middleware that wrap the execution of every method.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(m *Manager) {
+		m.middleware = mw
+	}
+}
+
+// WithRedactor overrides the Redactor applied to the params and results before they are logged.
+func WithRedactor(r Redactor) Option {
+	return func(m *Manager) {
+		m.redactor = r
+	}
+}
+
+// WithSlowRequestThreshold overrides the threshold and the callback for the slow requests, when fn
+// is nil the slow requests are written to the standard logger.
+func WithSlowRequestThreshold(d time.Duration, fn func(RequestInfo)) Option {
+	return func(m *Manager) {
+		m.slowThreshold = d
+		m.slowFunc = fn
+	}
+}
+
+// With returns a lightweight Manager that shares the methods with m but has the configuration
+// overridden by the options, so the same methods can be exposed with different policies.
+//
+// Methods replaced on m are also replaced on the derived Manager and the other way around.
+func (m *Manager) With(opts ...Option) *Manager {
+	d := &Manager{
+		settings:        m.settings,
+		table:           m.table,
+		inFlightTracker: m.inFlightTracker,
+		capture:         m.capture,
+		stats:           m.stats,
+		notifications:   m.notifications,
+		memory:          m.memory,
+		events:          m.events,
+		dedup:           m.dedup,
+		quota:           m.quota,
+	}
+	// Copy the middleware so appending on the derived manager doesn't change m
+	d.middleware = append([]Middleware(nil), m.middleware...)
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
@@ -0,0 +1,104 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func tagMiddleware(tag string) jrpc.Middleware {
+	return func(next jrpc.Method) jrpc.Method {
+		return jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			next.Execute(req, resp)
+			if s, ok := resp.Result.(string); ok {
+				resp.Result = tag + "(" + s + ")"
+			}
+		})
+	}
+}
+
+func TestManager_With(t *testing.T) {
+	block := &blockMethod{release: make(chan struct{})}
+	defer close(block.release)
+
+	m := jrpc.NewManagerBuilder().
+		Use(tagMiddleware("a"), tagMiddleware("b")).
+		Add("echo", &echoMethod{}).
+		Add("block", block).
+		Build()
+
+	tests := []struct {
+		name  string
+		m     *jrpc.Manager
+		r     string
+		wantW string
+	}{
+		{
+			name:  "Base Middleware",
+			m:     &m,
+			r:     `{"jsonrpc":"2.0","method":"echo","id":1,"params":"x"}`,
+			wantW: `{"jsonrpc":"2.0","id":1,"result":"a(b(x))"}`,
+		},
+		{
+			name:  "Overridden Middleware",
+			m:     m.With(jrpc.WithMiddleware(tagMiddleware("c"))),
+			r:     `{"jsonrpc":"2.0","method":"echo","id":1,"params":"x"}`,
+			wantW: `{"jsonrpc":"2.0","id":1,"result":"c(x)"}`,
+		},
+		{
+			name:  "Overridden Timeout",
+			m:     m.With(jrpc.WithTimeout(50 * time.Millisecond)),
+			r:     `{"jsonrpc":"2.0","method":"block","id":1}`,
+			wantW: `{"jsonrpc":"2.0","id":1,"error":{"code":-32002,"message":"Method execution timeout","data":{"method":"block","id":1,"timeout":50,"elapsed":50}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			if err := tt.m.Handle(context.Background(), strings.NewReader(tt.r), w); err != nil {
+				t.Fatalf("Manager.Handle() error = %v", err)
+			}
+			if gotW := roundElapsed(strings.TrimSpace(w.String()), 50); gotW != tt.wantW {
+				t.Errorf("Manager.Handle() result = %v, want %v", gotW, tt.wantW)
+			}
+		})
+	}
+}
+
+func TestWithSlowRequestThreshold(t *testing.T) {
+	sleep := jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+		time.Sleep(20 * time.Millisecond)
+		resp.Result = "done"
+	})
+	base := jrpc.NewManagerBuilder().
+		Add("echo", &echoMethod{}).
+		Add("sleep", sleep).
+		Build()
+
+	var slow []jrpc.RequestInfo
+	m := base.With(jrpc.WithSlowRequestThreshold(10*time.Millisecond, func(info jrpc.RequestInfo) {
+		slow = append(slow, info)
+	}))
+
+	reqs := []string{
+		`{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`,
+		`{"jsonrpc":"2.0","method":"sleep","params":[1,2],"id":2}`,
+	}
+	for _, r := range reqs {
+		var out bytes.Buffer
+		if err := m.Handle(context.Background(), strings.NewReader(r), &out); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+
+	if len(slow) != 1 {
+		t.Fatalf("slow requests = %v, want 1", len(slow))
+	}
+	if slow[0].Method != "sleep" || slow[0].ParamsSize != 5 || slow[0].Elapsed < 20*time.Millisecond {
+		t.Errorf("slow request = %+v, want sleep with 5 bytes params", slow[0])
+	}
+}
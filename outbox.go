@@ -0,0 +1,288 @@
+package jrpc2go
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Outbox is a persistent queue of the notifications of each client, so they are delivered after
+// the client reconnects instead of being dropped.
+type Outbox interface {
+	// Push appends the notification to the queue of the client.
+	Push(client string, n Notification) error
+	// Peek returns up to max notifications from the head of the queue of the client.
+	Peek(client string, max int) ([]Notification, error)
+	// Ack removes n notifications from the head of the queue of the client.
+	Ack(client string, n int) error
+}
+
+// MemoryOutbox is an Outbox that keeps the notifications in memory, they survive the client
+// reconnections but not the server restarts.
+type MemoryOutbox struct {
+	mu     sync.Mutex
+	queues map[string][]Notification
+}
+
+// NewMemoryOutbox returns an empty MemoryOutbox.
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{queues: make(map[string][]Notification)}
+}
+
+// Push appends the notification to the queue of the client.
+func (o *MemoryOutbox) Push(client string, n Notification) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.queues[client] = append(o.queues[client], n)
+	return nil
+}
+
+// Peek returns up to max notifications from the head of the queue of the client.
+func (o *MemoryOutbox) Peek(client string, max int) ([]Notification, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	q := o.queues[client]
+	if len(q) > max {
+		q = q[:max]
+	}
+	return append([]Notification(nil), q...), nil
+}
+
+// Ack removes n notifications from the head of the queue of the client.
+func (o *MemoryOutbox) Ack(client string, n int) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	q := o.queues[client]
+	if n >= len(q) {
+		delete(o.queues, client)
+		return nil
+	}
+	o.queues[client] = q[n:]
+	return nil
+}
+
+// FileOutbox is an Outbox that keeps the notifications of each client on a JSON lines file in a
+// directory, so they also survive the server restarts.
+type FileOutbox struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileOutbox returns a FileOutbox that keeps the files in dir, it's created if it doesn't exist.
+func NewFileOutbox(dir string) (*FileOutbox, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileOutbox{dir: dir}, nil
+}
+
+// path returns the file of the client, the name is encoded so it can't escape the directory.
+func (o *FileOutbox) path(client string) string {
+	return filepath.Join(o.dir, hex.EncodeToString([]byte(client))+".jsonl")
+}
+
+// Push appends the notification to the file of the client and syncs it to the disk.
+func (o *FileOutbox) Push(client string, n Notification) error {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	f, err := os.OpenFile(o.path(client), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Peek returns up to max notifications from the head of the file of the client.
+func (o *FileOutbox) Peek(client string, max int) ([]Notification, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	lines, err := o.read(client)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) > max {
+		lines = lines[:max]
+	}
+	ns := make([]Notification, 0, len(lines))
+	for _, l := range lines {
+		var n struct {
+			Version string          `json:"jsonrpc"`
+			Method  string          `json:"method"`
+			Params  json.RawMessage `json:"params,omitempty"`
+		}
+		if err := json.Unmarshal(l, &n); err != nil {
+			return nil, err
+		}
+		nt := Notification{Version: n.Version, Method: n.Method}
+		if n.Params != nil {
+			nt.Params = n.Params
+		}
+		ns = append(ns, nt)
+	}
+	return ns, nil
+}
+
+// Ack removes n notifications from the head of the file of the client, the file is replaced
+// atomically.
+func (o *FileOutbox) Ack(client string, n int) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	lines, err := o.read(client)
+	if err != nil {
+		return err
+	}
+	if n >= len(lines) {
+		if err := os.Remove(o.path(client)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	tmp, err := ioutil.TempFile(o.dir, ".outbox-")
+	if err != nil {
+		return err
+	}
+	for _, l := range lines[n:] {
+		if _, err := tmp.Write(append(l, '\n')); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), o.path(client))
+}
+
+// read returns the lines of the file of the client.
+func (o *FileOutbox) read(client string) ([][]byte, error) {
+	b, err := ioutil.ReadFile(o.path(client))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lines [][]byte
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(make([]byte, 0, 64*1024), len(b)+1)
+	for s.Scan() {
+		if l := bytes.TrimSpace(s.Bytes()); len(l) > 0 {
+			lines = append(lines, append([]byte(nil), l...))
+		}
+	}
+	return lines, s.Err()
+}
+
+// outboxBatch is the number of notifications read from the Outbox at a time.
+const outboxBatch = 100
+
+// ReliableNotifier is a Notifier that stores the notifications of a client on an Outbox and
+// delivers them in order to the Notifier of the current connection of the client, the ones that
+// fail are kept and delivered once the client reconnects and it's attached again.
+type ReliableNotifier struct {
+	outbox Outbox
+	client string
+
+	mu     sync.Mutex
+	target Notifier
+}
+
+// NewReliableNotifier returns a detached ReliableNotifier for the client identified by client.
+func NewReliableNotifier(o Outbox, client string) *ReliableNotifier {
+	if o == nil {
+		panic("jsonrpc: outbox should not be nil")
+	}
+	return &ReliableNotifier{outbox: o, client: client}
+}
+
+// Notify will store the notification on the Outbox and deliver the pending notifications if the
+// client is attached. It only returns an error if the notification can't be stored.
+func (n *ReliableNotifier) Notify(ctx context.Context, method string, params interface{}) error {
+	nt := Notification{Version: version, Method: method}
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		nt.Params = json.RawMessage(b)
+	}
+	if err := n.outbox.Push(n.client, nt); err != nil {
+		return err
+	}
+	return n.deliver(ctx)
+}
+
+// Attach will set the Notifier of the new connection of the client, like the Session or the
+// NotifierFromContext of a request, and deliver the pending notifications to it.
+func (n *ReliableNotifier) Attach(ctx context.Context, target Notifier) error {
+	n.mu.Lock()
+	n.target = target
+	n.mu.Unlock()
+	return n.deliver(ctx)
+}
+
+// Detach will keep the notifications on the Outbox until the client is attached again.
+func (n *ReliableNotifier) Detach() {
+	n.mu.Lock()
+	n.target = nil
+	n.mu.Unlock()
+}
+
+// deliver will send the pending notifications in order until one fails, then the client is
+// detached and the notification is kept for the next attach.
+//
+// The notifications delivered are acknowledged once per batch read from the Outbox, so a file
+// isn't rewritten for each one, the ones of a batch interrupted by a crash are delivered again.
+func (n *ReliableNotifier) deliver(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for n.target != nil {
+		pending, err := n.outbox.Peek(n.client, outboxBatch)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		delivered := 0
+		for _, nt := range pending {
+			if err := n.target.Notify(ctx, nt.Method, nt.Params); err != nil {
+				n.target = nil
+				break
+			}
+			delivered++
+		}
+		if delivered > 0 {
+			if err := n.outbox.Ack(n.client, delivered); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package jrpc2go_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+type flakyNotifier struct {
+	fail bool
+	got  []string
+}
+
+func (n *flakyNotifier) Notify(ctx context.Context, method string, params interface{}) error {
+	if n.fail {
+		return errors.New("disconnected")
+	}
+	b, _ := json.Marshal(params)
+	n.got = append(n.got, method+string(b))
+	return nil
+}
+
+func TestReliableNotifier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file, err := jrpc.NewFileOutbox(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outboxes := map[string]jrpc.Outbox{
+		"memory": jrpc.NewMemoryOutbox(),
+		"file":   file,
+	}
+	for name, o := range outboxes {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			rn := jrpc.NewReliableNotifier(o, "client/1")
+			conn := &flakyNotifier{}
+
+			// Detached, the notification is kept.
+			if err := rn.Notify(ctx, "a", 1); err != nil {
+				t.Fatal(err)
+			}
+			if err := rn.Attach(ctx, conn); err != nil {
+				t.Fatal(err)
+			}
+			if err := rn.Notify(ctx, "b", map[string]int{"x": 2}); err != nil {
+				t.Fatal(err)
+			}
+
+			// Delivery fails, the client is detached and nothing is dropped.
+			conn.fail = true
+			if err := rn.Notify(ctx, "c", nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := rn.Notify(ctx, "d", "y"); err != nil {
+				t.Fatal(err)
+			}
+
+			conn.fail = false
+			if err := rn.Attach(ctx, conn); err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"a1", `b{"x":2}`, "cnull", `d"y"`}
+			if len(conn.got) != len(want) {
+				t.Fatalf("got %v, want %v", conn.got, want)
+			}
+			for i := range want {
+				if conn.got[i] != want[i] {
+					t.Errorf("got %v, want %v", conn.got, want)
+					break
+				}
+			}
+			if pending, _ := o.Peek("client/1", 10); len(pending) != 0 {
+				t.Errorf("got %d pending, want 0", len(pending))
+			}
+		})
+	}
+}
+
+// ackOutbox records the acknowledgements of the Outbox.
+type ackOutbox struct {
+	jrpc.Outbox
+	acks []int
+}
+
+func (o *ackOutbox) Ack(client string, n int) error {
+	o.acks = append(o.acks, n)
+	return o.Outbox.Ack(client, n)
+}
+
+// limitNotifier fails the notifications after the first n.
+type limitNotifier struct {
+	n   int
+	got int
+}
+
+func (l *limitNotifier) Notify(ctx context.Context, method string, params interface{}) error {
+	if l.got == l.n {
+		return errors.New("disconnected")
+	}
+	l.got++
+	return nil
+}
+
+func TestReliableNotifier_BatchAck(t *testing.T) {
+	ctx := context.Background()
+	o := &ackOutbox{Outbox: jrpc.NewMemoryOutbox()}
+	rn := jrpc.NewReliableNotifier(o, "client")
+	for i := 0; i < 5; i++ {
+		if err := rn.Notify(ctx, "n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The delivery stops at the third notification, the ones delivered are acknowledged at once
+	if err := rn.Attach(ctx, &limitNotifier{n: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rn.Attach(ctx, &limitNotifier{n: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 3}; len(o.acks) != len(want) || o.acks[0] != want[0] || o.acks[1] != want[1] {
+		t.Errorf("acks = %v, want %v", o.acks, want)
+	}
+	if pending, _ := o.Peek("client", 10); len(pending) != 0 {
+		t.Errorf("got %d pending, want 0", len(pending))
+	}
+}
+
+func TestFileOutbox_Reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	o, _ := jrpc.NewFileOutbox(dir)
+	for _, m := range []string{"a", "b", "c"} {
+		if err := o.Push("client", jrpc.Notification{Version: "2.0", Method: m}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := o.Ack("client", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	o, _ = jrpc.NewFileOutbox(dir)
+	pending, err := o.Peek("client", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].Method != "b" || pending[1].Method != "c" {
+		t.Errorf("got %+v, want b and c", pending)
+	}
+}
@@ -0,0 +1,70 @@
+package jrpc2go
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// PageParams are the params of the list methods with cursor-based pagination, they can be
+// embedded on the params struct of a method with its filters.
+//
+// Cursor - The opaque cursor of the page, from the NextCursor of the previous page, empty for the
+// first page.
+//
+// Limit - The maximum number of items of the page, 0 for the default limit.
+type PageParams struct {
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// Validate will set the default limit when the limit is 0, it returns ErrInvalidParams if the
+// limit is negative or greater than max.
+func (p *PageParams) Validate(def, max int) *Error {
+	switch {
+	case p.Limit == 0:
+		p.Limit = def
+	case p.Limit < 0:
+		return newError(errCodeInvalidParams, "limit must be positive")
+	case p.Limit > max:
+		return newError(errCodeInvalidParams, fmt.Sprintf("limit must be at most %d", max))
+	}
+	return nil
+}
+
+// DecodeCursor will decode the cursor created by EncodeCursor into the value pointed to by v,
+// it returns false for the first page, when the cursor is empty, and ErrInvalidParams if the
+// cursor is not valid.
+func (p *PageParams) DecodeCursor(v interface{}) (bool, *Error) {
+	if p.Cursor == "" {
+		return false, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err != nil {
+		return false, newError(errCodeInvalidParams, "invalid cursor")
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, newError(errCodeInvalidParams, "invalid cursor")
+	}
+	return true, nil
+}
+
+// EncodeCursor returns the opaque cursor of the position v, like the key of the last item of the
+// page, so the clients can't depend on its format.
+func EncodeCursor(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Page is the result of the list methods with cursor-based pagination.
+//
+// Items - The items of the page, it should be an empty list instead of null when there are none.
+//
+// NextCursor - The cursor of the next page, it's omitted on the last page.
+type Page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,68 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestPageParams(t *testing.T) {
+	numbers := []int{1, 2, 3, 4, 5}
+	list := jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+		var p jrpc.PageParams
+		if err := req.ParseParams(&p); err != nil {
+			resp.Error = err
+			return
+		}
+		if err := p.Validate(2, 3); err != nil {
+			resp.Error = err
+			return
+		}
+		start := 0
+		if _, err := p.DecodeCursor(&start); err != nil {
+			resp.Error = err
+			return
+		}
+
+		end := start + p.Limit
+		page := jrpc.Page{Items: []int{}}
+		if end < len(numbers) {
+			page.NextCursor, _ = jrpc.EncodeCursor(end)
+		} else {
+			end = len(numbers)
+		}
+		if start < end {
+			page.Items = numbers[start:end]
+		}
+		resp.Result = page
+	})
+	m := jrpc.NewManagerBuilder().Add("list", list).Build()
+
+	cursor, _ := jrpc.EncodeCursor(3)
+	tests := []struct {
+		name   string
+		params string
+		want   string
+	}{
+		{name: "Default Limit", params: `{}`, want: `"result":{"items":[1,2],"nextCursor":"Mg"}`},
+		{name: "Next Page", params: `{"cursor":"` + cursor + `","limit":3}`, want: `"result":{"items":[4,5]}`},
+		{name: "Limit Too Large", params: `{"limit":4}`, want: `"error":{"code":-32602,"message":"Invalid method parameter(s)","data":"limit must be at most 3"}`},
+		{name: "Invalid Cursor", params: `{"cursor":"!"}`, want: `"error":{"code":-32602,"message":"Invalid method parameter(s)","data":"invalid cursor"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			r := `{"jsonrpc":"2.0","method":"list","id":1,"params":` + tt.params + `}`
+			if err := m.Handle(context.Background(), strings.NewReader(r), w); err != nil {
+				t.Fatalf("Manager.Handle() error = %v", err)
+			}
+			want := `{"jsonrpc":"2.0","id":1,` + tt.want + `}`
+			if gotW := strings.TrimSpace(w.String()); gotW != want {
+				t.Errorf("Manager.Handle() result = %v, want %v", gotW, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// PanicReport describes a panic recovered from the execution of a method.
+//
+// Params - The raw params redacted by the Manager Redactor.
+//
+// Value - The value passed to panic.
+//
+// Stack - The stack trace of the goroutine as formatted by runtime/debug.Stack.
+//
+// Frames - The frames of the stack from the panic to the goroutine start.
+type PanicReport struct {
+	Time          time.Time
+	Method        string
+	ID            *json.RawMessage
+	CorrelationID string
+	Params        json.RawMessage
+	Value         interface{}
+	Stack         []byte
+	Frames        []runtime.Frame
+}
+
+// PanicReporter receives the panics recovered from the methods, like an error tracking service.
+// It's called on the goroutine of the method so it must not block.
+type PanicReporter interface {
+	ReportPanic(ctx context.Context, r PanicReport)
+}
+
+// PanicReporterFunc is a function that implements the PanicReporter interface.
+type PanicReporterFunc func(ctx context.Context, r PanicReport)
+
+// ReportPanic calls f(ctx, r).
+func (f PanicReporterFunc) ReportPanic(ctx context.Context, r PanicReport) {
+	f(ctx, r)
+}
+
+// SetPanicRecovery will recover the panics of the methods, the request is replied with an
+// Internal Error and the panic is reported to r. When r is nil the panics are written to the
+// standard logger.
+//
+// Default is no recovery, which means a panic on a method stops the process.
+func (mb *ManagerBuilder) SetPanicRecovery(r PanicReporter) *ManagerBuilder {
+	if r == nil {
+		r = PanicReporterFunc(logPanic)
+	}
+	mb.panics = r
+	return mb
+}
+
+// logPanic is the default reporter of the panics.
+func logPanic(ctx context.Context, r PanicReport) {
+	id := "null"
+	if r.ID != nil {
+		id = idString(*r.ID)
+	}
+	log.Printf("jrpc2go: panic method=%s id=%s correlation=%s params=%s: %v\n%s",
+		r.Method, id, r.CorrelationID, r.Params, r.Value, r.Stack)
+}
+
+// callMethod will execute the method, its panic is recovered when the recovery is enabled.
+func (m *Manager) callMethod(method Method, req *Request, res *Response) {
+	if m.panics != nil {
+		defer m.recoverPanic(req, res)
+	}
+	method.Execute(req, res)
+}
+
+// recoverPanic will reply the request with an Internal Error and report the panic, it must be
+// deferred so it can recover.
+func (m *Manager) recoverPanic(req *Request, res *Response) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	res.SetError(newError(errCodeInternal, nil))
+
+	pcs := make([]uintptr, 64)
+	// Skip runtime.Callers and recoverPanic, the frames of the runtime panic are kept
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var list []runtime.Frame
+	for {
+		f, more := frames.Next()
+		list = append(list, f)
+		if !more {
+			break
+		}
+	}
+
+	ctx := req.Context()
+	r := PanicReport{
+		Time:          m.clock.Now(),
+		Method:        req.Method,
+		ID:            req.ID,
+		CorrelationID: CorrelationIDFromContext(ctx),
+		Value:         v,
+		Stack:         debug.Stack(),
+		Frames:        list,
+	}
+	if req.Params != nil {
+		r.Params = m.Redact(req.Method, *req.Params)
+	}
+	m.panics.ReportPanic(ctx, r)
+}
+
+// SentryReporter is a sample PanicReporter that sends the panics as events to the store API of
+// Sentry or any service compatible with it. The events are sent on their own goroutine and the
+// delivery errors are ignored.
+type SentryReporter struct {
+	endpoint string
+	auth     string
+	client   *http.Client
+}
+
+// NewSentryReporter returns the reporter for the DSN of the project, like
+// https://key@sentry.example.com/42. The client is http.DefaultClient if nil.
+func NewSentryReporter(dsn string, client *http.Client) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	project := strings.Trim(u.Path, "/")
+	if u.User == nil || u.User.Username() == "" || project == "" {
+		return nil, errors.New("jsonrpc: invalid sentry dsn")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	key := u.User.Username()
+	u.User = nil
+	u.Path = "/api/" + project + "/store/"
+	return &SentryReporter{
+		endpoint: u.String(),
+		auth:     "Sentry sentry_version=7, sentry_client=jrpc2go, sentry_key=" + key,
+		client:   client,
+	}, nil
+}
+
+// sentryEvent is the event of the Sentry store API.
+type sentryEvent struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Platform    string                 `json:"platform"`
+	Transaction string                 `json:"transaction"`
+	Tags        map[string]string      `json:"tags"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	Exception   struct {
+		Values []sentryException `json:"values"`
+	} `json:"exception"`
+}
+
+type sentryException struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	Stacktrace struct {
+		Frames []sentryFrame `json:"frames"`
+	} `json:"stacktrace"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+}
+
+// ReportPanic will send the panic as an event.
+func (s *SentryReporter) ReportPanic(ctx context.Context, r PanicReport) {
+	body, err := json.Marshal(s.event(r))
+	if err != nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set(contentTypeKey, contentTypeValue)
+		req.Header.Set("X-Sentry-Auth", s.auth)
+		if res, err := s.client.Do(req); err == nil {
+			res.Body.Close()
+		}
+	}()
+}
+
+// event returns the Sentry event of the panic, the frames are ordered from the oldest call.
+func (s *SentryReporter) event(r PanicReport) *sentryEvent {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	e := &sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   r.Time.UTC().Format(time.RFC3339),
+		Level:       "fatal",
+		Platform:    "go",
+		Transaction: r.Method,
+		Tags:        map[string]string{"method": r.Method},
+	}
+	if r.CorrelationID != "" {
+		e.Tags["correlation_id"] = r.CorrelationID
+	}
+	if r.Params != nil {
+		e.Extra = map[string]interface{}{"params": r.Params}
+	}
+	ex := sentryException{Type: "panic", Value: fmt.Sprint(r.Value)}
+	for i := len(r.Frames) - 1; i >= 0; i-- {
+		f := r.Frames[i]
+		ex.Stacktrace.Frames = append(ex.Stacktrace.Frames, sentryFrame{Function: f.Function, Filename: f.File, Lineno: f.Line})
+	}
+	e.Exception.Values = []sentryException{ex}
+	return e
+}
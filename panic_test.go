@@ -0,0 +1,80 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestManagerBuilder_SetPanicRecovery(t *testing.T) {
+	reports := make(chan jrpc.PanicReport, 1)
+	m := jrpc.NewManagerBuilder().
+		Add("login", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			panic("boom")
+		})).
+		SetRedactor(jrpc.FieldMask{"login": {"password"}}).
+		SetPanicRecovery(jrpc.PanicReporterFunc(func(ctx context.Context, r jrpc.PanicReport) {
+			reports <- r
+		})).
+		Build()
+
+	ctx := jrpc.ContextWithCorrelationID(context.Background(), "c-1")
+	var out bytes.Buffer
+	req := `{"jsonrpc":"2.0","method":"login","params":{"user":"a","password":"secret"},"id":1}`
+	if err := m.Handle(ctx, strings.NewReader(req), &out); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if want := `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"Internal error"}}`; strings.TrimSpace(out.String()) != want {
+		t.Errorf("Handle() = %v, want %v", out.String(), want)
+	}
+
+	r := <-reports
+	if r.Method != "login" || r.CorrelationID != "c-1" || r.Value != "boom" || string(*r.ID) != "1" {
+		t.Errorf("report = %+v, want the login panic", r)
+	}
+	if strings.Contains(string(r.Params), "secret") {
+		t.Errorf("report params = %s, want the password redacted", r.Params)
+	}
+	if len(r.Frames) == 0 || !bytes.Contains(r.Stack, []byte("panic")) {
+		t.Errorf("report stack = %s, want the panic frames", r.Stack)
+	}
+}
+
+func TestSentryReporter(t *testing.T) {
+	events := make(chan *http.Request, 1)
+	bodies := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		events <- r
+		bodies <- string(b)
+	}))
+	defer srv.Close()
+
+	if _, err := jrpc.NewSentryReporter("https://sentry.example.com/42", nil); err == nil {
+		t.Errorf("NewSentryReporter() without key error = nil, want error")
+	}
+	rep, err := jrpc.NewSentryReporter(strings.Replace(srv.URL, "http://", "http://key@", 1)+"/42", srv.Client())
+	if err != nil {
+		t.Fatalf("NewSentryReporter() error = %v", err)
+	}
+	rep.ReportPanic(context.Background(), jrpc.PanicReport{Time: time.Unix(0, 0), Method: "login", Value: "boom"})
+
+	select {
+	case r := <-events:
+		if r.URL.Path != "/api/42/store/" || !strings.Contains(r.Header.Get("X-Sentry-Auth"), "sentry_key=key") {
+			t.Errorf("request = %v %v, want the store API with the key", r.URL.Path, r.Header.Get("X-Sentry-Auth"))
+		}
+		if body := <-bodies; !strings.Contains(body, `"transaction":"login"`) || !strings.Contains(body, `"value":"boom"`) {
+			t.Errorf("event = %v, want the panic of login", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the event was not sent")
+	}
+}
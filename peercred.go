@@ -0,0 +1,57 @@
+package jrpc2go
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// ErrPeerCredUnsupported is returned when the credentials of the peer can't be read on the
+// platform, they are only supported on Linux.
+var ErrPeerCredUnsupported = errors.New("jsonrpc: peer credentials are not supported")
+
+// PeerCred are the credentials of the process on the other end of a Unix socket connection, as
+// reported by the kernel when the connection was established.
+type PeerCred struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// PeerCredFromContext returns the credentials of the peer of the Unix socket connection that
+// sent the request, ok is false for the other transports or if they couldn't be read.
+func PeerCredFromContext(ctx context.Context) (cred PeerCred, ok bool) {
+	cred, ok = ctx.Value(peerCredKey).(PeerCred)
+	return cred, ok
+}
+
+// WithAllowedUIDs restricts the Unix socket connections to the peers running with one of the
+// uids, the other connections and the ones without credentials are closed and counted on the
+// RejectedConns of Manager.Stats. The TCP connections are not restricted.
+//
+// The credentials of the Unix socket peers are always available with PeerCredFromContext.
+func WithAllowedUIDs(uids ...uint32) ServerOption {
+	return func(s *Server) {
+		s.allowedUIDs = make(map[uint32]bool, len(uids))
+		for _, uid := range uids {
+			s.allowedUIDs[uid] = true
+		}
+	}
+}
+
+// authorizePeer will add the credentials of the Unix socket peer to the ctx, ok is false if the
+// peer is not allowed to connect.
+func (s *Server) authorizePeer(ctx context.Context, c net.Conn) (_ context.Context, ok bool) {
+	uc, isUnix := c.(*net.UnixConn)
+	if !isUnix {
+		return ctx, true
+	}
+	cred, err := peerCred(uc)
+	if err != nil {
+		return ctx, s.allowedUIDs == nil
+	}
+	if s.allowedUIDs != nil && !s.allowedUIDs[cred.UID] {
+		return ctx, false
+	}
+	return context.WithValue(ctx, peerCredKey, cred), true
+}
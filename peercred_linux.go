@@ -0,0 +1,29 @@
+//go:build linux
+// +build linux
+
+package jrpc2go
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCred returns the SO_PEERCRED credentials of the connection.
+func peerCred(c *net.UnixConn) (PeerCred, error) {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return PeerCred{}, err
+	}
+	var ucred *syscall.Ucred
+	var serr error
+	err = raw.Control(func(fd uintptr) {
+		ucred, serr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return PeerCred{}, err
+	}
+	if serr != nil {
+		return PeerCred{}, serr
+	}
+	return PeerCred{PID: ucred.Pid, UID: ucred.Uid, GID: ucred.Gid}, nil
+}
@@ -0,0 +1,86 @@
+package jrpc2go_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+type peerCredMethod struct{}
+
+func (peerCredMethod) Execute(req *jrpc.Request, resp *jrpc.Response) {
+	cred, ok := jrpc.PeerCredFromContext(req.Context())
+	resp.Result = fmt.Sprintf("%v:%v", ok, cred.UID)
+}
+
+func TestServer_WithAllowedUIDs(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials are only supported on linux")
+	}
+	dir, err := ioutil.TempDir("", "jrpc")
+	if err != nil {
+		t.Fatalf("TempDir() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+	uid := uint32(os.Getuid())
+
+	tests := []struct {
+		name string
+		uids []uint32
+		want string
+	}{
+		{
+			name: "Allowed",
+			uids: []uint32{uid},
+			want: fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":"true:%d"}`+"\n", uid),
+		},
+		{
+			name: "Not Allowed",
+			uids: []uint32{uid + 1},
+		},
+	}
+	m := jrpc.NewManagerBuilder().Add("cred", peerCredMethod{}).Build()
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("%d.sock", i))
+			l, err := net.Listen("unix", path)
+			if err != nil {
+				t.Fatalf("net.Listen() error = %v", err)
+			}
+			srv := jrpc.NewServer(&m, jrpc.WithAllowedUIDs(tt.uids...))
+			go func() { _ = srv.Serve(l) }()
+			defer srv.Shutdown(context.Background())
+
+			c, err := net.Dial("unix", path)
+			if err != nil {
+				t.Fatalf("net.Dial() error = %v", err)
+			}
+			defer c.Close()
+			_ = c.SetReadDeadline(time.Now().Add(time.Second))
+			// The write can fail if the connection was already rejected
+			_, err = c.Write([]byte(`{"jsonrpc":"2.0","method":"cred","id":1}` + "\n"))
+			if err != nil && tt.want != "" {
+				t.Fatalf("Write() error = %v", err)
+			}
+			got, err := bufio.NewReader(c).ReadString('\n')
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("ReadString() = %v, want the connection closed", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ReadString() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package jrpc2go
+
+import "net"
+
+// peerCred is not supported on this platform, SO_PEERCRED is specific to Linux.
+func peerCred(c *net.UnixConn) (PeerCred, error) {
+	return PeerCred{}, ErrPeerCredUnsupported
+}
@@ -0,0 +1,34 @@
+//go:build (linux && cgo) || (darwin && cgo) || (freebsd && cgo)
+// +build linux,cgo darwin,cgo freebsd,cgo
+
+package jrpc2go
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// LoadPlugin opens the Go plugin (.so) file at path and returns the methods created by its
+// constructor, the plugin must export a function named by PluginSymbol with the signature
+//
+//	func NewMethods() map[string]jrpc2go.Method
+//
+// The plugin must be built with the same version of Go and of this package as the program.
+func LoadPlugin(path string) (map[string]Method, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("jsonrpc: fail to open plugin %s: %v", path, err)
+	}
+
+	sym, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("jsonrpc: fail to load plugin %s: %v", path, err)
+	}
+
+	newMethods, ok := sym.(func() map[string]Method)
+	if !ok {
+		return nil, fmt.Errorf("jsonrpc: plugin %s symbol %s has type %T, want func() map[string]jrpc2go.Method", path, PluginSymbol, sym)
+	}
+
+	return newMethods(), nil
+}
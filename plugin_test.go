@@ -0,0 +1,101 @@
+//go:build plugin && ((linux && cgo) || (darwin && cgo) || (freebsd && cgo))
+// +build plugin
+// +build linux,cgo darwin,cgo freebsd,cgo
+
+// The plugins must be built with the same flags as the test binary, so these tests are not
+// built by default, run them with: go test -tags plugin -run LoadPlugin
+
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+// buildPlugin will build the plugin of testdata/plugin/name into dir and return its path.
+func buildPlugin(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name+".so")
+	cmd := exec.Command("go", "build", "-buildmode=plugin", "-o", path, "./testdata/plugin/"+name)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build %s error = %v\n%s", name, err, out)
+	}
+	return path
+}
+
+func TestLoadPlugin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{
+			name:    "Missing File",
+			path:    filepath.Join(dir, "missing.so"),
+			wantErr: "jsonrpc: fail to open plugin " + filepath.Join(dir, "missing.so"),
+		},
+		{
+			name:    "Missing Symbol",
+			path:    buildPlugin(t, dir, "nosymbol"),
+			wantErr: "jsonrpc: fail to load plugin " + filepath.Join(dir, "nosymbol.so") + ": plugin: symbol NewMethods not found",
+		},
+		{
+			name:    "Wrong Symbol Type",
+			path:    buildPlugin(t, dir, "wrongtype"),
+			wantErr: "jsonrpc: plugin " + filepath.Join(dir, "wrongtype.so") + " symbol NewMethods has type func() map[string]interface {}, want func() map[string]jrpc2go.Method",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			methods, err := jrpc.LoadPlugin(tt.path)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("LoadPlugin() error = %v, want %v", err, tt.wantErr)
+			}
+			if methods != nil {
+				t.Errorf("LoadPlugin() = %v, want nil", methods)
+			}
+		})
+	}
+}
+
+func TestLoadPlugin_AddMethods(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	methods, err := jrpc.LoadPlugin(buildPlugin(t, dir, "methods"))
+	if err != nil {
+		t.Fatalf("LoadPlugin() error = %v", err)
+	}
+	if len(methods) != 1 || methods["plugin/add"] == nil {
+		t.Fatalf("LoadPlugin() = %v, want the plugin/add method", methods)
+	}
+
+	m := jrpc.NewManagerBuilder().
+		AddMethods(methods).
+		Build()
+	var out bytes.Buffer
+	in := strings.NewReader(`{"jsonrpc":"2.0","method":"plugin/add","params":{"v1":40,"v2":2},"id":1}`)
+	if err := m.Handle(context.Background(), in, &out); err != nil {
+		t.Fatalf("Manager.Handle() error = %v", err)
+	}
+	if want := `{"jsonrpc":"2.0","id":1,"result":42}`; strings.TrimSpace(out.String()) != want {
+		t.Errorf("Manager.Handle() = %v, want %v", out.String(), want)
+	}
+}
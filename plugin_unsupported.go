@@ -0,0 +1,14 @@
+//go:build (!linux && !darwin && !freebsd) || !cgo
+// +build !linux,!darwin,!freebsd !cgo
+
+package jrpc2go
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// LoadPlugin is not supported on this platform, Go plugins require cgo on Linux, macOS or FreeBSD.
+func LoadPlugin(path string) (map[string]Method, error) {
+	return nil, fmt.Errorf("jsonrpc: plugins are not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+}
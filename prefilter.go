@@ -0,0 +1,72 @@
+package jrpc2go
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrRejected is the cause of the HTTPError of the requests rejected by a PreFilter.
+var ErrRejected = errors.New("jsonrpc: request rejected")
+
+// RawRequest is the request received by a transport before it's decoded.
+//
+// Body - The raw JSON of the request or the batch, it must not be modified.
+//
+// Header - The HTTP headers, nil for the Server connections.
+//
+// RemoteAddr - The network address of the client.
+type RawRequest struct {
+	Body       []byte
+	Header     http.Header
+	RemoteAddr string
+	Transport  TransportInfo
+}
+
+// PreFilter inspects a request before the JSON decoding and returns an error to reject it, so
+// the obviously bad traffic, like the one from an address with bad reputation, doesn't cost the
+// decoding.
+//
+// On HTTP the rejection is replied with the status of an *HTTPError or 403 Forbidden. On a Server
+// connection an *Error is replied with a null ID and any other error closes the connection.
+type PreFilter func(r *RawRequest) error
+
+// WithPreFilter will apply the filter to the body of the requests before the signature is
+// verified and the body is decoded.
+func WithPreFilter(f PreFilter) HTTPOption {
+	return func(h *httpHandler) {
+		h.preFilter = f
+	}
+}
+
+// WithServerPreFilter will apply the filter to the requests of the connections before they are
+// decoded. The stream is still scanned to find where each request ends.
+func WithServerPreFilter(f PreFilter) ServerOption {
+	return func(s *Server) {
+		s.preFilter = f
+	}
+}
+
+// requestTransportInfo returns the description of the transport of the HTTP request, the one set
+// on its context by the handlers built on the HTTP handler, like the LongPoll, if any.
+func requestTransportInfo(r *http.Request) TransportInfo {
+	if info, ok := TransportInfoFromContext(r.Context()); ok {
+		return info
+	}
+	return httpTransportInfo(r, "http")
+}
+
+// filterHTTP returns the error to reply if the filter rejects the request.
+func (h *httpHandler) filterHTTP(r *http.Request, body []byte) *HTTPError {
+	if h.preFilter == nil {
+		return nil
+	}
+	err := h.preFilter(&RawRequest{Body: body, Header: r.Header, RemoteAddr: r.RemoteAddr, Transport: requestTransportInfo(r)})
+	if err == nil {
+		return nil
+	}
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he
+	}
+	return &HTTPError{Status: http.StatusForbidden, Err: ErrRejected}
+}
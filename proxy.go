@@ -0,0 +1,148 @@
+package jrpc2go
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidProxyHeader is returned when a connection doesn't start with a valid PROXY header.
+var ErrInvalidProxyHeader = errors.New("jsonrpc: invalid proxy protocol header")
+
+// proxyHeaderTimeout is the maximum time to receive the PROXY header of a connection.
+const proxyHeaderTimeout = 5 * time.Second
+
+// proxyV2Signature is the first 12 bytes of a PROXY protocol version 2 header.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// WithProxyProtocol will require the HAProxy PROXY protocol header, version 1 or 2, at the start
+// of each connection, so the address of the client behind a L4 load balancer is available with
+// RemoteAddrFromContext. The connections without a valid header are closed.
+//
+// WithMaxConnsPerIP is applied to the address of the load balancer since the header is only read
+// after the connection is accepted.
+func WithProxyProtocol() ServerOption {
+	return func(s *Server) {
+		s.proxyProtocol = true
+	}
+}
+
+// proxyConn is a connection with the PROXY header consumed, the remote address is the source
+// address of the header.
+type proxyConn struct {
+	net.Conn
+	r      *bufio.Reader
+	remote net.Addr
+}
+
+// newProxyConn will read the PROXY header of the connection. The remote address is the one of
+// the connection for the LOCAL command and the unknown protocols.
+func newProxyConn(c net.Conn) (*proxyConn, error) {
+	_ = c.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+	defer c.SetReadDeadline(time.Time{})
+
+	pc := &proxyConn{Conn: c, r: bufio.NewReader(c)}
+	sig, err := pc.r.Peek(len(proxyV2Signature))
+	if err != nil {
+		return nil, ErrInvalidProxyHeader
+	}
+	if bytes.Equal(sig, proxyV2Signature) {
+		pc.remote, err = readProxyV2(pc.r)
+	} else {
+		pc.remote, err = readProxyV1(pc.r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pc, nil
+}
+
+func (c *proxyConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// RemoteAddr returns the source address of the PROXY header.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// readProxyV1 will read the text header, like "PROXY TCP4 192.0.2.1 192.0.2.2 56324 443\r\n".
+func readProxyV1(r *bufio.Reader) (net.Addr, error) {
+	// The header is at most 107 bytes including the CRLF
+	var line []byte
+	for len(line) < 107 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrInvalidProxyHeader
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, ErrInvalidProxyHeader
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) < 2 || fields[0] != "PROXY" {
+		return nil, ErrInvalidProxyHeader
+	}
+	if fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || fields[1] != "TCP4" && fields[1] != "TCP6" {
+		return nil, ErrInvalidProxyHeader
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil {
+		return nil, ErrInvalidProxyHeader
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyV2 will read the binary header, only the TCP over IPv4 and IPv6 addresses are used.
+func readProxyV2(r *bufio.Reader) (net.Addr, error) {
+	hdr := make([]byte, 16)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, ErrInvalidProxyHeader
+	}
+	if hdr[12]>>4 != 2 {
+		return nil, ErrInvalidProxyHeader
+	}
+	body := make([]byte, binary.BigEndian.Uint16(hdr[14:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, ErrInvalidProxyHeader
+	}
+	switch hdr[12] & 0x0f {
+	case 0x0:
+		// LOCAL, like the health checks of the load balancer
+		return nil, nil
+	case 0x1:
+		// PROXY, the addresses follow
+	default:
+		return nil, ErrInvalidProxyHeader
+	}
+	switch hdr[13] {
+	case 0x11:
+		if len(body) < 12 {
+			return nil, ErrInvalidProxyHeader
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:4]), Port: int(binary.BigEndian.Uint16(body[8:]))}, nil
+	case 0x21:
+		if len(body) < 36 {
+			return nil, ErrInvalidProxyHeader
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:16]), Port: int(binary.BigEndian.Uint16(body[32:]))}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,73 @@
+package jrpc2go_test
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+type remoteAddrMethod struct{}
+
+func (remoteAddrMethod) Execute(req *jrpc.Request, resp *jrpc.Response) {
+	resp.Result = jrpc.RemoteAddrFromContext(req.Context()).String()
+}
+
+func TestServer_WithProxyProtocol(t *testing.T) {
+	m := jrpc.NewManagerBuilder().Add("addr", remoteAddrMethod{}).Build()
+	v2 := "\r\n\r\n\x00\r\nQUIT\n" + "\x21\x11\x00\x0c" + "\xc0\x00\x02\x07" + "\xc0\x00\x02\x01" + "\xdc\x05" + "\x01\xbb"
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{
+			name:   "Version 1",
+			header: "PROXY TCP4 198.51.100.7 192.0.2.1 56324 443\r\n",
+			want:   `"result":"198.51.100.7:56324"`,
+		},
+		{
+			name:   "Version 1 IPv6",
+			header: "PROXY TCP6 2001:db8::7 2001:db8::1 56324 443\r\n",
+			want:   `"result":"[2001:db8::7]:56324"`,
+		},
+		{
+			name:   "Version 2",
+			header: v2,
+			want:   `"result":"192.0.2.7:56325"`,
+		},
+		{
+			name:   "Missing Header",
+			header: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, c := startServer(t, &m, jrpc.WithProxyProtocol())
+			defer srv.Shutdown(context.Background())
+			defer c.Close()
+
+			_ = c.SetReadDeadline(time.Now().Add(time.Second))
+			if _, err := c.Write([]byte(tt.header + `{"jsonrpc":"2.0","method":"addr","id":1}` + "\n")); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			got, err := bufio.NewReader(c).ReadString('\n')
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("ReadString() = %v, want the connection closed", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadString() error = %v", err)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("ReadString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+)
+
+// CorrelationHeader is the message header that correlates the responses with the requests on
+// queue transports, it's copied from the request message to the response message.
+const CorrelationHeader = "correlation-id"
+
+// ReplyToHeader is the message header with the topic where the response should be sent, it
+// overrides the reply topic of the QueueTransport.
+const ReplyToHeader = "reply-to"
+
+// QueueMessage is a message of a queue or log system like a Kafka topic.
+//
+// Topic - The topic of the message, on the consumed messages it's informative.
+//
+// Key - The partition key, messages with the same key are processed in order.
+//
+// Value - The JSON RPC request or response.
+//
+// Headers - The message headers, like the CorrelationHeader.
+type QueueMessage struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// QueueConsumer receives the request messages from a queue, an adapter for the client library of
+// the queue system must implement it.
+type QueueConsumer interface {
+	// Receive blocks until the next message is available or the ctx is done.
+	Receive(ctx context.Context) (*QueueMessage, error)
+	// Ack marks the message as processed, like committing the offset on Kafka.
+	Ack(ctx context.Context, msg *QueueMessage) error
+}
+
+// QueueProducer sends the response messages to a queue, an adapter for the client library of
+// the queue system must implement it.
+type QueueProducer interface {
+	Send(ctx context.Context, msg *QueueMessage) error
+}
+
+// DeliverySemantics selects when the QueueTransport acknowledges the messages.
+type DeliverySemantics int
+
+const (
+	// AtLeastOnce acknowledges the message after the response is sent, a message interrupted by a
+	// crash is delivered again so the methods must tolerate duplicates. It's the default.
+	AtLeastOnce DeliverySemantics = iota
+	// AtMostOnce acknowledges the message before its execution, a message interrupted by a crash
+	// is lost but it's never executed twice.
+	AtMostOnce
+)
+
+// QueueOption configures the QueueTransport.
+type QueueOption func(*QueueTransport)
+
+// WithQueueWorkers sets the number of requests processed at the same time, the messages with the
+// same key are always processed by the same worker to keep their order. Default is 1.
+func WithQueueWorkers(n int) QueueOption {
+	return func(q *QueueTransport) {
+		if n > 0 {
+			q.workers = n
+		}
+	}
+}
+
+// WithReplyTopic sets the topic of the responses when the request doesn't have the ReplyToHeader.
+func WithReplyTopic(topic string) QueueOption {
+	return func(q *QueueTransport) {
+		q.replyTopic = topic
+	}
+}
+
+// WithQueueErrorHandler sets the function called when a message fails to be processed,
+// acknowledged or replied, the transport keeps consuming after calling it.
+func WithQueueErrorHandler(f func(msg *QueueMessage, err error)) QueueOption {
+	return func(q *QueueTransport) {
+		q.onError = f
+	}
+}
+
+// WithDeliverySemantics sets when the messages are acknowledged, AtLeastOnce after the response
+// is sent or AtMostOnce before the execution. Default is AtLeastOnce.
+//
+// A message that fails to be acknowledged with AtMostOnce is not executed.
+func WithDeliverySemantics(ds DeliverySemantics) QueueOption {
+	return func(q *QueueTransport) {
+		q.semantics = ds
+	}
+}
+
+// WithJournal sets the Journal where the messages are recorded before their execution and
+// completed after their response is sent, the incomplete ones are executed again by Recover.
+// It's only used with AtLeastOnce.
+func WithJournal(j Journal) QueueOption {
+	return func(q *QueueTransport) {
+		q.journal = j
+	}
+}
+
+// QueueTransport reads the JSON RPC requests from a queue, like a Kafka topic, and writes the
+// responses to a reply topic with the correlation ID of the request, so event-driven systems can
+// reuse the same methods.
+//
+// The messages are acknowledged after the response is sent, so the delivery is at-least-once
+// and the requests can be executed again if the transport stops before the acknowledgement,
+// WithDeliverySemantics selects at-most-once instead.
+type QueueTransport struct {
+	m          *Manager
+	consumer   QueueConsumer
+	producer   QueueProducer
+	workers    int
+	replyTopic string
+	onError    func(msg *QueueMessage, err error)
+	journal    Journal
+	semantics  DeliverySemantics
+}
+
+// NewQueueTransport returns the transport for the Manager, the producer can be nil if the
+// requests are only notifications.
+func NewQueueTransport(m *Manager, c QueueConsumer, p QueueProducer, opts ...QueueOption) *QueueTransport {
+	if c == nil {
+		panic("jsonrpc: queue consumer should not be nil")
+	}
+	q := &QueueTransport{
+		m:        m,
+		consumer: c,
+		producer: p,
+		workers:  1,
+		onError:  func(msg *QueueMessage, err error) {},
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// Serve will consume and process the messages until the ctx is done or the consumer fails, it
+// waits for the messages being processed before returning.
+//
+// It returns the consumer error or the ctx error.
+func (q *QueueTransport) Serve(ctx context.Context) error {
+	queues := make([]chan *QueueMessage, q.workers)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan *QueueMessage)
+		wg.Add(1)
+		go func(msgs <-chan *QueueMessage) {
+			defer wg.Done()
+			for msg := range msgs {
+				q.process(ctx, msg)
+			}
+		}(queues[i])
+	}
+
+	var err error
+	next := 0
+	for {
+		var msg *QueueMessage
+		if msg, err = q.consumer.Receive(ctx); err != nil {
+			break
+		}
+		w := next
+		if len(msg.Key) > 0 {
+			h := fnv.New32a()
+			_, _ = h.Write(msg.Key)
+			w = int(h.Sum32() % uint32(q.workers))
+		} else {
+			next = (next + 1) % q.workers
+		}
+		select {
+		case queues[w] <- msg:
+			continue
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		break
+	}
+
+	for _, c := range queues {
+		close(c)
+	}
+	wg.Wait()
+	return err
+}
+
+// Recover will execute again the messages of the Journal that were not completed, like the
+// ones interrupted by a crash, and send their responses. It should be called on startup before
+// Serve, the messages are not acknowledged since they are from a previous consumer session.
+//
+// It returns the Journal error or the ctx error, it's a no-op without WithJournal.
+func (q *QueueTransport) Recover(ctx context.Context) error {
+	if q.journal == nil {
+		return nil
+	}
+	entries, err := q.journal.Incomplete()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if q.execute(ctx, e.Message) {
+			if err := q.journal.Complete(e.ID); err != nil {
+				q.onError(e.Message, err)
+			}
+		}
+	}
+	return nil
+}
+
+// process will record the message on the journal, handle the request and send the response,
+// the message is acknowledged before or after according to the delivery semantics.
+func (q *QueueTransport) process(ctx context.Context, msg *QueueMessage) {
+	if q.semantics == AtMostOnce {
+		if err := q.consumer.Ack(ctx, msg); err != nil {
+			q.onError(msg, err)
+			return
+		}
+		q.execute(ctx, msg)
+		return
+	}
+
+	var id string
+	if q.journal != nil {
+		var err error
+		if id, err = q.journal.Begin(msg); err != nil {
+			// Not acknowledged so it's delivered again
+			q.onError(msg, err)
+			return
+		}
+	}
+
+	if !q.execute(ctx, msg) {
+		return
+	}
+
+	if err := q.consumer.Ack(ctx, msg); err != nil {
+		q.onError(msg, err)
+	}
+	if q.journal != nil {
+		if err := q.journal.Complete(id); err != nil {
+			q.onError(msg, err)
+		}
+	}
+}
+
+// execute will handle the request of the message and send the response, it returns false if
+// the message must be processed again, like when the ctx of Serve ends during the call and
+// nothing is replied, so it's neither acknowledged nor completed on the journal.
+func (q *QueueTransport) execute(ctx context.Context, msg *QueueMessage) bool {
+	var out bytes.Buffer
+	if err := q.m.Handle(withTransportInfo(ctx, TransportInfo{Kind: "queue"}), bytes.NewReader(msg.Value), &out); err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		q.onError(msg, err)
+	}
+
+	if out.Len() > 0 && q.producer != nil {
+		if err := q.producer.Send(ctx, q.reply(msg, out.Bytes())); err != nil {
+			q.onError(msg, err)
+			return false
+		}
+	}
+	return true
+}
+
+// reply returns the response message of the request message.
+func (q *QueueTransport) reply(msg *QueueMessage, value []byte) *QueueMessage {
+	resp := &QueueMessage{
+		Topic:   q.replyTopic,
+		Key:     msg.Key,
+		Value:   value,
+		Headers: make(map[string]string),
+	}
+	if to, ok := msg.Headers[ReplyToHeader]; ok {
+		resp.Topic = to
+	}
+	if id, ok := msg.Headers[CorrelationHeader]; ok {
+		resp.Headers[CorrelationHeader] = id
+	}
+	return resp
+}
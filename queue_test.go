@@ -0,0 +1,218 @@
+package jrpc2go_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+type memQueue struct {
+	msgs chan *jrpc.QueueMessage
+	sent chan *jrpc.QueueMessage
+
+	mu    sync.Mutex
+	acked []string
+}
+
+func newMemQueue(msgs ...*jrpc.QueueMessage) *memQueue {
+	q := &memQueue{
+		msgs: make(chan *jrpc.QueueMessage, len(msgs)),
+		sent: make(chan *jrpc.QueueMessage, len(msgs)),
+	}
+	for _, m := range msgs {
+		q.msgs <- m
+	}
+	return q
+}
+
+func (q *memQueue) Receive(ctx context.Context) (*jrpc.QueueMessage, error) {
+	select {
+	case m := <-q.msgs:
+		return m, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (q *memQueue) Ack(ctx context.Context, msg *jrpc.QueueMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.acked = append(q.acked, msg.Headers[jrpc.CorrelationHeader])
+	return nil
+}
+
+func (q *memQueue) ackCount() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.acked)
+}
+
+func (q *memQueue) Send(ctx context.Context, msg *jrpc.QueueMessage) error {
+	q.sent <- msg
+	return nil
+}
+
+func TestQueueTransport(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Build()
+
+	q := newMemQueue(
+		&jrpc.QueueMessage{
+			Key:     []byte("user-1"),
+			Value:   []byte(`{"jsonrpc":"2.0","method":"add","id":1,"params":{"v1":1,"v2":2}}`),
+			Headers: map[string]string{jrpc.CorrelationHeader: "c1"},
+		},
+		&jrpc.QueueMessage{
+			Value:   []byte(`{"jsonrpc":"2.0","method":"add","params":{"v1":1,"v2":2}}`),
+			Headers: map[string]string{jrpc.CorrelationHeader: "c2"},
+		},
+		&jrpc.QueueMessage{
+			Key:     []byte("user-2"),
+			Value:   []byte(`{"jsonrpc":"2.0","method":"add","id":3,"params":{"v1":2,"v2":2}}`),
+			Headers: map[string]string{jrpc.CorrelationHeader: "c3", jrpc.ReplyToHeader: "other"},
+		},
+	)
+	qt := jrpc.NewQueueTransport(&m, q, q, jrpc.WithQueueWorkers(2), jrpc.WithReplyTopic("replies"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- qt.Serve(ctx) }()
+
+	got := map[string]*jrpc.QueueMessage{}
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-q.sent:
+			got[msg.Headers[jrpc.CorrelationHeader]] = msg
+		case <-time.After(time.Second):
+			t.Fatal("response not sent")
+		}
+	}
+	// The notification has no response, a message canceled before its ack is delivered again
+	for i := 0; i < 1000 && q.ackCount() < 3; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("QueueTransport.Serve() error = %v, want %v", err, context.Canceled)
+	}
+
+	if r := got["c1"]; r == nil || r.Topic != "replies" || string(r.Key) != "user-1" || strings.TrimSpace(string(r.Value)) != `{"jsonrpc":"2.0","id":1,"result":3}` {
+		t.Errorf("response c1 = %+v", r)
+	}
+	if r := got["c3"]; r == nil || r.Topic != "other" || strings.TrimSpace(string(r.Value)) != `{"jsonrpc":"2.0","id":3,"result":4}` {
+		t.Errorf("response c3 = %+v", r)
+	}
+	if len(q.acked) != 3 {
+		t.Errorf("acknowledged messages = %v, want 3", q.acked)
+	}
+}
+
+// orderQueue records the order of the acknowledgements and the responses.
+type orderQueue struct {
+	*memQueue
+	ackErr error
+	events chan string
+}
+
+func (q *orderQueue) Ack(ctx context.Context, msg *jrpc.QueueMessage) error {
+	q.events <- "ack"
+	return q.ackErr
+}
+
+func (q *orderQueue) Send(ctx context.Context, msg *jrpc.QueueMessage) error {
+	q.events <- "send"
+	return nil
+}
+
+func TestQueueTransport_WithDeliverySemantics(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Build()
+
+	tests := []struct {
+		name      string
+		semantics jrpc.DeliverySemantics
+		ackErr    error
+		want      string
+	}{
+		{name: "At Least Once", semantics: jrpc.AtLeastOnce, want: "send,ack"},
+		{name: "At Most Once", semantics: jrpc.AtMostOnce, want: "ack,send"},
+		{name: "At Most Once Ack Failed", semantics: jrpc.AtMostOnce, ackErr: errors.New("broker down"), want: "ack"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &orderQueue{
+				memQueue: newMemQueue(&jrpc.QueueMessage{Value: []byte(`{"jsonrpc":"2.0","method":"add","id":1,"params":{"v1":1,"v2":2}}`)}),
+				ackErr:   tt.ackErr,
+				events:   make(chan string, 2),
+			}
+			qt := jrpc.NewQueueTransport(&m, q, q, jrpc.WithDeliverySemantics(tt.semantics))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() { done <- qt.Serve(ctx) }()
+
+			var got []string
+			for len(got) < strings.Count(tt.want, ",")+1 {
+				select {
+				case e := <-q.events:
+					got = append(got, e)
+				case <-time.After(time.Second):
+					t.Fatalf("events = %v, want %v", got, tt.want)
+				}
+			}
+			cancel()
+			<-done
+			close(q.events)
+			for e := range q.events {
+				got = append(got, e)
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueueTransport_Canceled(t *testing.T) {
+	started := make(chan struct{})
+	m := jrpc.NewManagerBuilder().
+		Add("slow", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			close(started)
+			<-req.Context().Done()
+		})).
+		Build()
+
+	q := newMemQueue(&jrpc.QueueMessage{
+		Value:   []byte(`{"jsonrpc":"2.0","method":"slow","id":1}`),
+		Headers: map[string]string{jrpc.CorrelationHeader: "c1"},
+	})
+	var errs []error
+	qt := jrpc.NewQueueTransport(&m, q, q, jrpc.WithQueueErrorHandler(func(msg *jrpc.QueueMessage, err error) {
+		errs = append(errs, err)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- qt.Serve(ctx) }()
+	<-started
+	cancel()
+	<-done
+
+	// The message is not replied so it must be delivered again
+	if len(q.acked) != 0 {
+		t.Errorf("acknowledged messages = %v, want none", q.acked)
+	}
+	if len(q.sent) != 0 {
+		t.Errorf("sent responses = %v, want none", len(q.sent))
+	}
+	if len(errs) != 0 {
+		t.Errorf("errors = %v, want none", errs)
+	}
+}
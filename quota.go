@@ -0,0 +1,238 @@
+package jrpc2go
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ErrCodeQuotaExceeded means the client exhausted the budget of a quota in the current window.
+const errCodeQuotaExceeded ErrorCode = -32008
+
+// QuotaStore keeps the usage of the quotas per window, a shared store, like one backed by Redis,
+// applies the quotas across all the instances of a service.
+type QuotaStore interface {
+	// Usage returns the usage of the key in the window of now and the time the window resets.
+	Usage(key string, window time.Duration, now time.Time) (used int64, reset time.Time, err error)
+
+	// Add adds n to the usage of the key in the window of now and returns the new usage and the
+	// time the window resets.
+	Add(key string, n int64, window time.Duration, now time.Time) (used int64, reset time.Time, err error)
+}
+
+// Quota is a budget of a client per window set with SetQuotas.
+//
+// Name - Identifies the quota on the store and on the error, it must be distinct between the
+// quotas sharing a store.
+//
+// Limit - The budget of the client within the Window.
+//
+// Cost - The kind of cost reported with AddCost that is charged to the budget, when empty each
+// call is charged as 1.
+//
+// Methods - The methods the quota applies to, all when empty.
+type Quota struct {
+	Name    string
+	Limit   int64
+	Window  time.Duration
+	Cost    string
+	Methods []string
+}
+
+// QuotaData is the data of the Quota Exceeded error.
+//
+// Reset - The time the window of the quota resets.
+//
+// RetryAfter - The milliseconds until Reset.
+type QuotaData struct {
+	Quota      string    `json:"quota"`
+	Limit      int64     `json:"limit"`
+	Used       int64     `json:"used"`
+	Reset      time.Time `json:"reset"`
+	RetryAfter int64     `json:"retryAfter"`
+}
+
+// SetQuotas will reject the requests of a client with the Quota Exceeded error (-32008) and
+// QuotaData once the budget of a quota is exhausted, until its window resets. The quotas of calls
+// are charged when the request is received, even if it's rejected, and the quotas of cost when
+// the method finishes, so the last call can exceed the budget.
+//
+// The identity returns the client of the request, when it's nil the remote address of the
+// connection is used, so it must be provided for the HTTP transports, like the API key set with
+// WithContextFunc. The requests with an empty identity are not limited. The store is
+// NewMemoryQuotaStore if nil, a failure of the store lets the request through.
+//
+// Default is no quotas.
+func (mb *ManagerBuilder) SetQuotas(identity func(ctx context.Context) string, store QuotaStore, quotas ...Quota) *ManagerBuilder {
+	mb.quotaIdentity = identity
+	mb.quotaStore = store
+	mb.quotas = quotas
+	return mb
+}
+
+// quotaEngine enforces the quotas of the Manager.
+type quotaEngine struct {
+	identity func(ctx context.Context) string
+	store    QuotaStore
+	quotas   []quotaRule
+}
+
+// quotaRule is a Quota with its methods indexed.
+type quotaRule struct {
+	Quota
+	methods map[string]bool
+}
+
+// newQuotaEngine returns the engine or nil if there are no quotas.
+func newQuotaEngine(identity func(ctx context.Context) string, store QuotaStore, quotas []Quota) *quotaEngine {
+	if len(quotas) == 0 {
+		return nil
+	}
+	if identity == nil {
+		identity = remoteIdentity
+	}
+	if store == nil {
+		store = NewMemoryQuotaStore()
+	}
+	q := &quotaEngine{identity: identity, store: store}
+	for _, quota := range quotas {
+		rule := quotaRule{Quota: quota}
+		if len(quota.Methods) > 0 {
+			rule.methods = make(map[string]bool, len(quota.Methods))
+			for _, name := range quota.Methods {
+				rule.methods[name] = true
+			}
+		}
+		q.quotas = append(q.quotas, rule)
+	}
+	return q
+}
+
+// applies returns true if the quota limits the method.
+func (r *quotaRule) applies(method string) bool {
+	return r.methods == nil || r.methods[method]
+}
+
+// admitQuota returns the Quota Exceeded error if a quota of the client is exhausted, otherwise it
+// charges the call to the quotas of calls.
+func (m *Manager) admitQuota(ctx context.Context, req *Request) *Error {
+	q := m.quota
+	if q == nil {
+		return nil
+	}
+	client := q.identity(ctx)
+	if client == "" {
+		return nil
+	}
+	now := m.clock.Now()
+	for i := range q.quotas {
+		rule := &q.quotas[i]
+		if !rule.applies(req.Method) {
+			continue
+		}
+		key := rule.Name + ":" + client
+		var used int64
+		var reset time.Time
+		var err error
+		var exceeded bool
+		if rule.Cost == "" {
+			used, reset, err = q.store.Add(key, 1, rule.Window, now)
+			exceeded = used > rule.Limit
+		} else {
+			// The cost is charged after the call so the budget is exhausted once it's reached
+			used, reset, err = q.store.Usage(key, rule.Window, now)
+			exceeded = used >= rule.Limit
+		}
+		if err != nil || !exceeded {
+			continue
+		}
+		return newError(errCodeQuotaExceeded, &QuotaData{
+			Quota:      rule.Name,
+			Limit:      rule.Limit,
+			Used:       used,
+			Reset:      reset.UTC(),
+			RetryAfter: reset.Sub(now).Milliseconds(),
+		})
+	}
+	return nil
+}
+
+// chargeQuota will charge the cost reported by the method to the quotas of cost.
+func (m *Manager) chargeQuota(ctx context.Context, req *Request, costs map[string]int64) {
+	q := m.quota
+	if q == nil || costs == nil {
+		return
+	}
+	client := q.identity(ctx)
+	if client == "" {
+		return
+	}
+	now := m.clock.Now()
+	for i := range q.quotas {
+		rule := &q.quotas[i]
+		if n := costs[rule.Cost]; rule.Cost != "" && n != 0 && rule.applies(req.Method) {
+			_, _, _ = q.store.Add(rule.Name+":"+client, n, rule.Window, now)
+		}
+	}
+}
+
+// memoryQuotaStore is the QuotaStore kept in memory with a fixed window counter per key.
+type memoryQuotaStore struct {
+	mu    sync.Mutex
+	keys  map[string]*quotaWindow
+	calls int
+}
+
+// quotaWindow is the usage of a key in the window that starts at start.
+type quotaWindow struct {
+	start time.Time
+	end   time.Time
+	used  int64
+}
+
+// NewMemoryQuotaStore returns a QuotaStore kept in memory, it only counts the usage of this
+// process. The windows are aligned to the multiples of their duration since the zero time.
+func NewMemoryQuotaStore() QuotaStore {
+	return &memoryQuotaStore{keys: make(map[string]*quotaWindow)}
+}
+
+func (s *memoryQuotaStore) Usage(key string, window time.Duration, now time.Time) (int64, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	qw := s.window(key, window, now)
+	return qw.used, qw.end, nil
+}
+
+func (s *memoryQuotaStore) Add(key string, n int64, window time.Duration, now time.Time) (int64, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	qw := s.window(key, window, now)
+	qw.used += n
+	return qw.used, qw.end, nil
+}
+
+// window returns the usage of the key in the window of now, the lock must be held.
+func (s *memoryQuotaStore) window(key string, window time.Duration, now time.Time) *quotaWindow {
+	s.expire(now)
+	start := now.Truncate(window)
+	qw, ok := s.keys[key]
+	if !ok || !qw.start.Equal(start) {
+		qw = &quotaWindow{start: start, end: start.Add(window)}
+		s.keys[key] = qw
+	}
+	return qw
+}
+
+// expire will remove the keys of the windows already reset, it runs once every 1024 calls so
+// the cost is shared by the requests.
+func (s *memoryQuotaStore) expire(now time.Time) {
+	if s.calls++; s.calls < 1024 {
+		return
+	}
+	s.calls = 0
+	for key, qw := range s.keys {
+		if !now.Before(qw.end) {
+			delete(s.keys, key)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestManagerBuilder_SetQuotas(t *testing.T) {
+	clock := newFakeClock()
+	identity := func(ctx context.Context) string { return "key-1" }
+	m := jrpc.NewManagerBuilder().
+		SetClock(clock).
+		SetQuotas(identity, nil,
+			jrpc.Quota{Name: "calls", Limit: 2, Window: time.Minute, Methods: []string{"echo"}},
+			jrpc.Quota{Name: "rows", Limit: 100, Window: time.Hour, Cost: "rows"},
+		).
+		Add("echo", &echoMethod{}).
+		Add("scan", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			jrpc.AddCost(req.Context(), "rows", 60)
+			resp.Result = true
+		})).
+		Build()
+
+	call := func(method string) string {
+		var out bytes.Buffer
+		r := `{"jsonrpc":"2.0","method":"` + method + `","params":"a","id":1}`
+		if err := m.Handle(context.Background(), strings.NewReader(r), &out); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+		return strings.TrimSpace(out.String())
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		advance time.Duration
+		want    string
+	}{
+		{name: "Calls Within Budget", method: "echo", want: `{"jsonrpc":"2.0","id":1,"result":"a"}`},
+		{name: "Calls Last", method: "echo", want: `{"jsonrpc":"2.0","id":1,"result":"a"}`},
+		{name: "Calls Exceeded", method: "echo", advance: 15 * time.Second,
+			want: `{"jsonrpc":"2.0","id":1,"error":{"code":-32008,"message":"Quota exceeded","data":{"quota":"calls","limit":2,"used":3,"reset":"1970-01-01T00:01:00Z","retryAfter":45000}}}`},
+		{name: "Calls Reset", method: "echo", advance: 45 * time.Second, want: `{"jsonrpc":"2.0","id":1,"result":"a"}`},
+		{name: "Cost Within Budget", method: "scan", want: `{"jsonrpc":"2.0","id":1,"result":true}`},
+		{name: "Cost Over Budget", method: "scan", want: `{"jsonrpc":"2.0","id":1,"result":true}`},
+		{name: "Cost Exceeded", method: "scan",
+			want: `{"jsonrpc":"2.0","id":1,"error":{"code":-32008,"message":"Quota exceeded","data":{"quota":"rows","limit":100,"used":120,"reset":"1970-01-01T01:00:00Z","retryAfter":3540000}}}`},
+	}
+	for _, tt := range tests {
+		clock.Advance(tt.advance)
+		if got := call(tt.method); got != tt.want {
+			t.Errorf("%v: Handle() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package jrpc2go
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateStore keeps the request counters of the HTTP rate limits, a shared store, like one backed
+// by Redis, applies the limits across all the instances of a service.
+type RateStore interface {
+	// Allow records a request of the key at now if it's within the limit of requests per
+	// window, otherwise it returns false and the time until a request is allowed again.
+	Allow(key string, limit int, window time.Duration, now time.Time) (ok bool, retryAfter time.Duration, err error)
+}
+
+// RateLimit configures a request rate limit of WithRateLimit.
+//
+// Limit - The maximum number of requests of a key within the Window.
+//
+// KeyFunc - Returns the key the requests are counted by, like RemoteIPKey or BearerTokenKey,
+// the requests with an empty key are not limited. The keys must be distinct between the limits
+// sharing a Store.
+//
+// Store - Where the counters are kept, NewMemoryRateStore if nil.
+type RateLimit struct {
+	Limit   int
+	Window  time.Duration
+	KeyFunc func(r *http.Request) string
+	Store   RateStore
+}
+
+// WithRateLimit will limit the rate of the requests before their body is read, it can be used
+// more than once, like one limit per IP and another per token. The requests over a limit are
+// replied with 429 Too Many Requests, the Retry-After header and a Server Overloaded error with
+// RetryInfo.
+//
+// A failure of the store lets the request through, so an unavailable store doesn't stop the
+// service.
+//
+// If the Limit or the Window are not greater than 0 this function will panic.
+func WithRateLimit(rl RateLimit) HTTPOption {
+	if rl.Limit <= 0 || rl.Window <= 0 {
+		panic("jsonrpc: rate limit and window should be greater than 0")
+	}
+	return func(h *httpHandler) {
+		if rl.KeyFunc == nil {
+			rl.KeyFunc = RemoteIPKey
+		}
+		if rl.Store == nil {
+			rl.Store = NewMemoryRateStore()
+		}
+		h.rateLimits = append(h.rateLimits, rl)
+	}
+}
+
+// RemoteIPKey returns the IP of the client prefixed by "ip:".
+func RemoteIPKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// BearerTokenKey returns the bearer token of the Authorization header prefixed by "token:", it's
+// empty for the requests without a token.
+func BearerTokenKey(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return ""
+	}
+	return "token:" + strings.TrimSpace(auth[7:])
+}
+
+// limitRate returns false and replies with the error if the request is over a rate limit.
+func (h *httpHandler) limitRate(w http.ResponseWriter, r *http.Request) bool {
+	now := h.m.clock.Now()
+	for _, rl := range h.rateLimits {
+		key := rl.KeyFunc(r)
+		if key == "" {
+			continue
+		}
+		ok, retryAfter, err := rl.Store.Allow(key, rl.Limit, rl.Window, now)
+		if err != nil || ok {
+			continue
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		w.Header().Set(contentTypeKey, contentTypeValue)
+		w.WriteHeader(http.StatusTooManyRequests)
+		e := newError(errCodeServerOverloaded, &RetryInfo{RetryAfter: retryAfter.Milliseconds()})
+		_ = h.m.encoder.encode(w, &Response{Version: version, Error: e})
+		return false
+	}
+	return true
+}
+
+// memoryRateStore is the RateStore kept in memory with a sliding window counter per key.
+type memoryRateStore struct {
+	mu    sync.Mutex
+	keys  map[string]*rateWindow
+	hits  int
+	sweep int
+}
+
+// rateWindow is the count of requests of the current fixed window and the previous one, the
+// count of the sliding window is interpolated from both.
+type rateWindow struct {
+	window time.Duration
+	start  time.Time
+	prev   int
+	cur    int
+}
+
+// NewMemoryRateStore returns a RateStore kept in memory, it only limits the requests received by
+// this process.
+func NewMemoryRateStore() RateStore {
+	return &memoryRateStore{keys: make(map[string]*rateWindow), sweep: 1024}
+}
+
+func (s *memoryRateStore) Allow(key string, limit int, window time.Duration, now time.Time) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(now)
+
+	rw, ok := s.keys[key]
+	if !ok {
+		rw = &rateWindow{window: window, start: now.Truncate(window)}
+		s.keys[key] = rw
+	}
+	rw.advance(window, now)
+
+	// The previous window is weighted by the part of it still inside the sliding window
+	elapsed := now.Sub(rw.start)
+	weight := 1 - float64(elapsed)/float64(window)
+	if float64(rw.prev)*weight+float64(rw.cur) < float64(limit) {
+		rw.cur++
+		return true, 0, nil
+	}
+
+	// The time until the weighted previous window drops below the free capacity
+	wait := window - elapsed
+	if free := limit - rw.cur; free > 0 && rw.prev > 0 {
+		wait = time.Duration(float64(window)*(1-float64(free)/float64(rw.prev))) - elapsed
+	}
+	if wait <= 0 {
+		wait = time.Millisecond
+	}
+	return false, wait, nil
+}
+
+// advance will move the window to the one of now.
+func (rw *rateWindow) advance(window time.Duration, now time.Time) {
+	start := now.Truncate(window)
+	switch {
+	case start.Equal(rw.start):
+	case start.Sub(rw.start) == window:
+		rw.prev, rw.cur = rw.cur, 0
+		rw.start = start
+	default:
+		rw.prev, rw.cur = 0, 0
+		rw.start = start
+	}
+}
+
+// expire will remove the keys without requests in their last two windows, it runs once every
+// sweep calls so the cost is shared by the requests.
+func (s *memoryRateStore) expire(now time.Time) {
+	if s.hits++; s.hits < s.sweep {
+		return
+	}
+	s.hits = 0
+	for key, rw := range s.keys {
+		if now.Sub(rw.start) >= 2*rw.window {
+			delete(s.keys, key)
+		}
+	}
+}
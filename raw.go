@@ -0,0 +1,60 @@
+package jrpc2go
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// RawMethod responds to a JSON RPC request with the raw params and result, without decoding
+// them, for proxies and relays that only forward the bytes.
+//
+// The params are nil when the request doesn't have them and a nil result is replied as null.
+type RawMethod interface {
+	ExecuteRaw(ctx context.Context, params json.RawMessage) (json.RawMessage, *Error)
+}
+
+// RawMethodFunc is an adapter to allow the use of ordinary functions as RawMethod.
+type RawMethodFunc func(ctx context.Context, params json.RawMessage) (json.RawMessage, *Error)
+
+// ExecuteRaw calls f(ctx, params).
+func (f RawMethodFunc) ExecuteRaw(ctx context.Context, params json.RawMessage) (json.RawMessage, *Error) {
+	return f(ctx, params)
+}
+
+// Raw returns a Method that executes the RawMethod rm, so it can be used with the middleware,
+// the versions and Manager.Replace.
+func Raw(rm RawMethod) Method {
+	if rm == nil {
+		panic("jsonrpc: raw method should not be nil")
+	}
+	return rawMethod{rm: rm}
+}
+
+// rawMethod is the Method adapter of a RawMethod.
+type rawMethod struct {
+	rm RawMethod
+}
+
+// Execute calls the RawMethod with the raw params of the request.
+func (m rawMethod) Execute(req *Request, resp *Response) {
+	var params json.RawMessage
+	if req.Params != nil {
+		params = *req.Params
+	}
+	result, err := m.rm.ExecuteRaw(req.Context(), params)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	resp.Result = result
+}
+
+// AddRaw will append a new RawMethod to the manager to be executed, like Add.
+//
+// If the name is empty or the rm is nil this function will panic.
+func (mb *ManagerBuilder) AddRaw(name string, rm RawMethod) *ManagerBuilder {
+	return mb.Add(name, Raw(rm))
+}
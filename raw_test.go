@@ -0,0 +1,59 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestManagerBuilder_AddRaw(t *testing.T) {
+	relay := jrpc.RawMethodFunc(func(ctx context.Context, params json.RawMessage) (json.RawMessage, *jrpc.Error) {
+		if params == nil {
+			return nil, nil
+		}
+		if string(params) == `"fail"` {
+			return nil, &jrpc.Error{Code: 1, Message: "relay failed"}
+		}
+		return params, nil
+	})
+	manager := jrpc.NewManagerBuilder().
+		AddRaw("relay", relay).
+		Build()
+
+	tests := []struct {
+		name  string
+		r     string
+		wantW string
+	}{
+		{
+			name:  "Passthrough",
+			r:     `{"jsonrpc":"2.0","method":"relay","params":{"b":[1,2],"a":"x"},"id":1}`,
+			wantW: `{"jsonrpc":"2.0","id":1,"result":{"b":[1,2],"a":"x"}}` + "\n",
+		},
+		{
+			name:  "No Params",
+			r:     `{"jsonrpc":"2.0","method":"relay","id":2}`,
+			wantW: `{"jsonrpc":"2.0","id":2,"result":null}` + "\n",
+		},
+		{
+			name:  "Error",
+			r:     `{"jsonrpc":"2.0","method":"relay","params":"fail","id":3}`,
+			wantW: `{"jsonrpc":"2.0","id":3,"error":{"code":1,"message":"relay failed"}}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := manager.Handle(context.Background(), strings.NewReader(tt.r), &out); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if out.String() != tt.wantW {
+				t.Errorf("Handle() = %v, want %v", out.String(), tt.wantW)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package jrpc2go
+
+import (
+	"encoding/json"
+)
+
+// redactedValue is the value that replaces the redacted fields.
+const redactedValue = "[REDACTED]"
+
+// Redactor removes the sensitive data from the params and results before they are logged, audited
+// or captured, so passwords and tokens never land on logs even with verbose middleware enabled.
+//
+// Redact receives the method name and the raw JSON value and must return a copy of the value
+// without the sensitive data, it must never change the raw value.
+type Redactor interface {
+	Redact(method string, raw json.RawMessage) json.RawMessage
+}
+
+// RedactorFunc type is an adapter to allow the use of ordinary functions as a Redactor.
+type RedactorFunc func(method string, raw json.RawMessage) json.RawMessage
+
+// Redact calls f(method, raw).
+func (f RedactorFunc) Redact(method string, raw json.RawMessage) json.RawMessage {
+	return f(method, raw)
+}
+
+// FieldMask is a Redactor that replaces the value of object members by name, the members are
+// matched at any depth of the value, including objects inside arrays.
+//
+// The key is the method name, the key "*" applies to all methods.
+type FieldMask map[string][]string
+
+// Redact returns a copy of raw with the masked members replaced by "[REDACTED]", values that
+// are not valid JSON are fully replaced.
+func (fm FieldMask) Redact(method string, raw json.RawMessage) json.RawMessage {
+	fields := make(map[string]bool)
+	for _, k := range []string{"*", method} {
+		for _, f := range fm[k] {
+			fields[f] = true
+		}
+	}
+	if len(fields) == 0 || len(raw) == 0 {
+		return raw
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return json.RawMessage(`"` + redactedValue + `"`)
+	}
+	b, err := json.Marshal(maskFields(v, fields))
+	if err != nil {
+		return json.RawMessage(`"` + redactedValue + `"`)
+	}
+	return b
+}
+
+// maskFields replaces the value of the object members in fields at any depth of v.
+func maskFields(v interface{}, fields map[string]bool) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if fields[k] {
+				t[k] = redactedValue
+				continue
+			}
+			t[k] = maskFields(e, fields)
+		}
+	case []interface{}:
+		for i := range t {
+			t[i] = maskFields(t[i], fields)
+		}
+	}
+	return v
+}
+
+// Redact returns the params or result v of the method redacted by the Manager Redactor, so it can
+// be used by middleware before logging. Values that are not raw JSON are encoded first.
+//
+// It returns nil if v is nil or can't be encoded.
+func (m *Manager) Redact(method string, v interface{}) json.RawMessage {
+	var raw json.RawMessage
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case json.RawMessage:
+		raw = t
+	case *json.RawMessage:
+		if t == nil {
+			return nil
+		}
+		raw = *t
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		raw = b
+	}
+	if m.redactor == nil {
+		return raw
+	}
+	return m.redactor.Redact(method, raw)
+}
@@ -0,0 +1,68 @@
+package jrpc2go_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestFieldMask_Redact(t *testing.T) {
+	fm := jrpc.FieldMask{
+		"*":     {"token"},
+		"login": {"password"},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		raw    string
+		want   string
+	}{
+		{
+			name:   "Method Field",
+			method: "login",
+			raw:    `{"user":"a","password":"b","token":"c"}`,
+			want:   `{"password":"[REDACTED]","token":"[REDACTED]","user":"a"}`,
+		},
+		{
+			name:   "Other Method",
+			method: "add",
+			raw:    `{"password":"b","nested":[{"token":"c"}]}`,
+			want:   `{"nested":[{"token":"[REDACTED]"}],"password":"b"}`,
+		},
+		{
+			name:   "Invalid JSON",
+			method: "login",
+			raw:    `{password`,
+			want:   `"[REDACTED]"`,
+		},
+		{
+			name:   "No Fields",
+			method: "add",
+			raw:    `[1,2]`,
+			want:   `[1,2]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fm.Redact(tt.method, json.RawMessage(tt.raw)); string(got) != tt.want {
+				t.Errorf("FieldMask.Redact() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_Redact(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		SetRedactor(jrpc.FieldMask{"login": {"password"}}).
+		Build()
+
+	got := m.Redact("login", map[string]string{"user": "a", "password": "b"})
+	if want := `{"password":"[REDACTED]","user":"a"}`; string(got) != want {
+		t.Errorf("Manager.Redact() = %s, want %s", got, want)
+	}
+	if got := m.Redact("login", nil); got != nil {
+		t.Errorf("Manager.Redact() = %s, want nil", got)
+	}
+}
@@ -0,0 +1,91 @@
+package jrpc2go
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// ErrConnNotFound is returned when sending to a connection that is not on the Registry.
+var ErrConnNotFound = errors.New("jsonrpc: connection not found")
+
+// Registry tracks the live connections of the transports, so the application can send
+// notifications to all of them or to a specific one from anywhere.
+//
+// The Server registers its connections when configured WithRegistry, other transports can
+// register their Notifiers with Register.
+type Registry struct {
+	mu    sync.RWMutex
+	next  uint64
+	conns map[string]Notifier
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{conns: make(map[string]Notifier)}
+}
+
+// Register will add the Notifier of a connection and return its ID, the returned function
+// removes it and must be called when the connection is closed.
+func (r *Registry) Register(n Notifier) (id string, unregister func()) {
+	if n == nil {
+		panic("jsonrpc: notifier should not be nil")
+	}
+	r.mu.Lock()
+	r.next++
+	id = strconv.FormatUint(r.next, 10)
+	r.conns[id] = n
+	r.mu.Unlock()
+
+	return id, func() {
+		r.mu.Lock()
+		delete(r.conns, id)
+		r.mu.Unlock()
+	}
+}
+
+// Len returns the number of live connections.
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.conns)
+}
+
+// SendTo will send the notification to the connection with the id, it returns ErrConnNotFound
+// if the connection is not registered.
+func (r *Registry) SendTo(ctx context.Context, id, method string, params interface{}) error {
+	r.mu.RLock()
+	n, ok := r.conns[id]
+	r.mu.RUnlock()
+	if !ok {
+		return ErrConnNotFound
+	}
+	return n.Notify(ctx, method, params)
+}
+
+// Broadcast will send the notification to all the live connections and return how many it was
+// delivered to, a failed connection doesn't stop the delivery to the others.
+func (r *Registry) Broadcast(ctx context.Context, method string, params interface{}) int {
+	r.mu.RLock()
+	targets := make([]Notifier, 0, len(r.conns))
+	for _, n := range r.conns {
+		targets = append(targets, n)
+	}
+	r.mu.RUnlock()
+
+	sent := 0
+	for _, n := range targets {
+		if n.Notify(ctx, method, params) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// ConnIDFromContext returns the Registry ID of the connection that sent the request or empty if
+// the connection is not registered.
+func ConnIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(connIDKey).(string)
+	return id
+}
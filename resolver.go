@@ -0,0 +1,164 @@
+package jrpc2go
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoAddresses is returned by ResolvingTransport when the Resolver doesn't return any address.
+var ErrNoAddresses = errors.New("jsonrpc: no server addresses")
+
+// Resolver returns the addresses of the servers, like the URLs of the HTTP endpoints, so the
+// client can follow the servers of dynamic environments like Kubernetes.
+type Resolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// ResolverFunc is an adapter to use a function as a Resolver.
+type ResolverFunc func(ctx context.Context) ([]string, error)
+
+// Resolve calls f(ctx).
+func (f ResolverFunc) Resolve(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// StaticResolver is a Resolver with a fixed list of addresses.
+type StaticResolver []string
+
+// Resolve returns the addresses.
+func (r StaticResolver) Resolve(ctx context.Context) ([]string, error) {
+	return r, nil
+}
+
+// SRVResolver is a Resolver of the DNS SRV records of a service, the addresses are URLs like
+// "http://host:port/path" ordered by priority and weight.
+type SRVResolver struct {
+	// Service, Proto and Name are looked up as _service._proto.name, like the records of a
+	// Kubernetes headless service.
+	Service string
+	Proto   string
+	Name    string
+	// Scheme of the URLs, if empty "http" is used.
+	Scheme string
+	// Path of the URLs, like "/rpc".
+	Path string
+	// Resolver is used for the lookup, if nil net.DefaultResolver is used.
+	Resolver *net.Resolver
+}
+
+// Resolve will look up the SRV records and return their URLs.
+func (r *SRVResolver) Resolve(ctx context.Context) ([]string, error) {
+	res := r.Resolver
+	if res == nil {
+		res = net.DefaultResolver
+	}
+	_, srvs, err := res.LookupSRV(ctx, r.Service, r.Proto, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	scheme := r.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	addrs := make([]string, 0, len(srvs))
+	for _, srv := range srvs {
+		host := strings.TrimSuffix(srv.Target, ".")
+		addrs = append(addrs, scheme+"://"+net.JoinHostPort(host, strconv.Itoa(int(srv.Port)))+r.Path)
+	}
+	return addrs, nil
+}
+
+// ResolvingTransport is a Transport that sends the requests to the addresses of a Resolver,
+// balanced in round robin, and resolves them again after the refresh interval.
+type ResolvingTransport struct {
+	resolver     Resolver
+	refresh      time.Duration
+	newTransport func(addr string) Transport
+
+	mu         sync.Mutex
+	addrs      []string
+	transports map[string]Transport
+	resolved   time.Time
+	next       int
+}
+
+// NewResolvingTransport returns a ResolvingTransport that creates the Transport of each address
+// with newTransport, if nil an HTTPTransport with the address as URL is used. The addresses are
+// only resolved once if the refresh interval is 0.
+func NewResolvingTransport(r Resolver, refresh time.Duration, newTransport func(addr string) Transport) *ResolvingTransport {
+	if r == nil {
+		panic("jsonrpc: resolver should not be nil")
+	}
+	if newTransport == nil {
+		newTransport = func(addr string) Transport {
+			return &HTTPTransport{URL: addr}
+		}
+	}
+	return &ResolvingTransport{
+		resolver:     r,
+		refresh:      refresh,
+		newTransport: newTransport,
+		transports:   make(map[string]Transport),
+	}
+}
+
+// RoundTrip will send the body to the next address.
+func (t *ResolvingTransport) RoundTrip(ctx context.Context, body []byte) ([]byte, error) {
+	tr, err := t.pick(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tr.RoundTrip(ctx, body)
+}
+
+// Refresh will resolve the addresses now, the previous addresses are kept if it fails.
+func (t *ResolvingTransport) Refresh(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.resolve(ctx)
+}
+
+// pick returns the Transport of the next address, resolving them first if they are stale.
+func (t *ResolvingTransport) pick(ctx context.Context) (Transport, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stale := t.resolved.IsZero() || (t.refresh > 0 && time.Since(t.resolved) > t.refresh)
+	if stale {
+		// A failed refresh keeps using the previous addresses
+		if err := t.resolve(ctx); err != nil && len(t.addrs) == 0 {
+			return nil, err
+		}
+	}
+	if len(t.addrs) == 0 {
+		return nil, ErrNoAddresses
+	}
+	addr := t.addrs[t.next%len(t.addrs)]
+	t.next++
+	return t.transports[addr], nil
+}
+
+// resolve will replace the addresses and keep the Transports of the ones still present, t.mu
+// must be held.
+func (t *ResolvingTransport) resolve(ctx context.Context) error {
+	addrs, err := t.resolver.Resolve(ctx)
+	if err != nil {
+		return err
+	}
+	transports := make(map[string]Transport, len(addrs))
+	for _, addr := range addrs {
+		if tr, ok := t.transports[addr]; ok {
+			transports[addr] = tr
+		} else {
+			transports[addr] = t.newTransport(addr)
+		}
+	}
+	t.addrs = append([]string(nil), addrs...)
+	t.transports = transports
+	t.resolved = time.Now()
+	return nil
+}
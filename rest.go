@@ -0,0 +1,143 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// RESTOption configures the handler returned by RESTHandler.
+type RESTOption func(*restHandler)
+
+// WithOpenAPIInfo sets the title and the version of the API on the OpenAPI document.
+func WithOpenAPIInfo(title, version string) RESTOption {
+	return func(h *restHandler) {
+		h.title = title
+		h.version = version
+	}
+}
+
+// restHandler maps the methods of a Manager to HTTP routes.
+type restHandler struct {
+	m       *Manager
+	prefix  string
+	title   string
+	version string
+}
+
+// RESTHandler returns an adapter that exposes the methods as HTTP endpoints, so clients and
+// webhooks that don't speak JSON RPC can call the same methods.
+//
+// A POST to prefix + method name, like `POST /rpc/add`, executes the method with the JSON body as
+// params. The result is sent as the JSON body with 200 OK and the errors are sent as the JSON
+// Error with the HTTP status matching the error code.
+//
+// The OpenAPI document of the routes is served on `GET prefix/openapi.json`.
+func RESTHandler(m *Manager, prefix string, opts ...RESTOption) http.Handler {
+	h := &restHandler{
+		m:       m,
+		prefix:  trimSlash(prefix) + "/",
+		title:   "JSON RPC API",
+		version: "1.0.0",
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// ServeHTTP will execute the method of the route.
+func (h *restHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, h.prefix) || len(r.URL.Path) == len(h.prefix) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method == http.MethodGet && r.URL.Path == h.prefix+openAPIPath {
+		h.serveOpenAPI(w)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	req := &Request{
+		Version: version,
+		Method:  strings.TrimPrefix(r.URL.Path, h.prefix),
+		values:  newValues(),
+	}
+	if body = bytes.TrimSpace(body); len(body) > 0 {
+		if !strings.HasPrefix(r.Header.Get(contentTypeKey), contentTypeValue) {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		params := json.RawMessage(body)
+		req.Params = &params
+	}
+
+	resp := h.m.execMethod(withTransportInfo(r.Context(), httpTransportInfo(r, "rest")), req)
+
+	var out bytes.Buffer
+	status := http.StatusOK
+	if resp.Error != nil {
+		status = restStatus(resp.Error.Code)
+		err = h.m.encoder.encode(&out, resp.Error)
+	} else {
+		err = h.m.encoder.encode(&out, resp.Result)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(contentTypeKey, contentTypeValue)
+	w.WriteHeader(status)
+	if _, err := w.Write(out.Bytes()); err != nil {
+		//TODO not sure what to do here
+	}
+}
+
+// restStatus returns the HTTP status for the error code.
+func restStatus(code ErrorCode) int {
+	switch code {
+	case errCodeParseError, errCodeInvalidRequest, errCodeInvalidParams:
+		return http.StatusBadRequest
+	case errCodeMethodNotFound, errCodeMethodDisabled:
+		return http.StatusNotFound
+	case errCodeExecutionTimeout:
+		return http.StatusGatewayTimeout
+	case errCodeServerOverloaded:
+		return http.StatusServiceUnavailable
+	case errCodeQuotaExceeded:
+		return http.StatusTooManyRequests
+	case errCodeInternal, errCodeExecutionCanceled:
+		return http.StatusInternalServerError
+	}
+	// Application errors are considered a problem of the request
+	return http.StatusUnprocessableEntity
+}
+
+// serveOpenAPI will reply with the OpenAPI document of the routes.
+func (h *restHandler) serveOpenAPI(w http.ResponseWriter) {
+	doc, err := h.m.OpenAPI(h.prefix, h.title, h.version)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(contentTypeKey, contentTypeValue)
+	if _, err := w.Write(doc); err != nil {
+		//TODO not sure what to do here
+	}
+}
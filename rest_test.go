@@ -0,0 +1,113 @@
+package jrpc2go_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestRESTHandler(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Add("files/read", &nameMethod{tag: "read"}).
+		Build()
+	h := jrpc.RESTHandler(&m, "/rpc/")
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "Valid Call", method: http.MethodPost, path: "/rpc/add", body: `{"v1":1,"v2":2}`, wantStatus: http.StatusOK, wantBody: `3`},
+		{name: "Nested Name No Body", method: http.MethodPost, path: "/rpc/files/read", wantStatus: http.StatusOK, wantBody: `"read:files/read"`},
+		{name: "Invalid Params", method: http.MethodPost, path: "/rpc/add", body: `[1]`, wantStatus: http.StatusBadRequest},
+		{name: "Application Error", method: http.MethodPost, path: "/rpc/add", body: `{"v1":0,"v2":1}`, wantStatus: http.StatusUnprocessableEntity, wantBody: `{"code":1,"message":"Fake error for test"}`},
+		{name: "Method Not Found", method: http.MethodPost, path: "/rpc/sub", body: `{}`, wantStatus: http.StatusNotFound},
+		{name: "Route Not Found", method: http.MethodPost, path: "/other/add", wantStatus: http.StatusNotFound},
+		{name: "Method Not Allowed", method: http.MethodGet, path: "/rpc/add", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("RESTHandler() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := strings.TrimSpace(w.Body.String()); tt.wantBody != "" && got != tt.wantBody {
+				t.Errorf("RESTHandler() body = %v, want %v", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+type tree struct {
+	Name     string  `json:"name"`
+	Children []*tree `json:"children,omitempty"`
+}
+
+func TestRESTHandler_OpenAPI(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Describe("add", jrpc.MethodInfo{Summary: "Adds two numbers", Params: addMethodParams{}, Result: int64(0)}).
+		Add("tree", &nameMethod{}).
+		Describe("tree", jrpc.MethodInfo{Result: &tree{}}).
+		Build()
+	h := jrpc.RESTHandler(&m, "/rpc", jrpc.WithOpenAPIInfo("Test API", "2.0.0"))
+
+	r := httptest.NewRequest(http.MethodGet, "/rpc/openapi.json", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("RESTHandler() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var doc struct {
+		Info  map[string]string `json:"info"`
+		Paths map[string]struct {
+			Post struct {
+				Summary     string `json:"summary"`
+				RequestBody struct {
+					Content map[string]struct {
+						Schema json.RawMessage `json:"schema"`
+					} `json:"content"`
+				} `json:"requestBody"`
+			} `json:"post"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if doc.Info["title"] != "Test API" || doc.Info["version"] != "2.0.0" {
+		t.Errorf("OpenAPI info = %v", doc.Info)
+	}
+	add := doc.Paths["/rpc/add"].Post
+	if add.Summary != "Adds two numbers" {
+		t.Errorf("OpenAPI add summary = %v", add.Summary)
+	}
+	if got := string(add.RequestBody.Content["application/json"].Schema); got != `{"$ref":"#/components/schemas/addMethodParams"}` {
+		t.Errorf("OpenAPI add params schema = %v", got)
+	}
+	want := `{"properties":{"v1":{"type":"integer"},"v2":{"type":"integer"}},"required":["v1","v2"],"type":"object"}`
+	if got := string(doc.Components.Schemas["addMethodParams"]); got != want {
+		t.Errorf("OpenAPI addMethodParams schema = %v, want %v", got, want)
+	}
+	want = `{"properties":{"children":{"items":{"$ref":"#/components/schemas/tree"},"type":"array"},"name":{"type":"string"}},"required":["name"],"type":"object"}`
+	if got := string(doc.Components.Schemas["tree"]); got != want {
+		t.Errorf("OpenAPI tree schema = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+package jrpc2go
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	marshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaGenerator creates JSON Schemas from Go types, the named structs are added to the
+// definitions and referenced with $ref so recursive types are supported.
+type schemaGenerator struct {
+	refPrefix   string
+	definitions map[string]interface{}
+}
+
+// newSchemaGenerator returns a generator with the references prefixed by refPrefix,
+// like "#/components/schemas/".
+func newSchemaGenerator(refPrefix string) *schemaGenerator {
+	return &schemaGenerator{
+		refPrefix:   refPrefix,
+		definitions: make(map[string]interface{}),
+	}
+}
+
+// schemaOf returns the JSON Schema of the value v, nil values have an empty schema.
+func (g *schemaGenerator) schemaOf(v interface{}) map[string]interface{} {
+	if v == nil {
+		return map[string]interface{}{}
+	}
+	return g.schema(reflect.TypeOf(v))
+}
+
+// schema returns the JSON Schema of the type t.
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType, t.Implements(marshalerType), reflect.PtrTo(t).Implements(marshalerType):
+		// Custom encoding, the shape is unknown
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := t.Name()
+		if _, ok := g.definitions[name]; !ok {
+			// Reserve the name before the fields to stop the recursion
+			g.definitions[name] = nil
+			g.definitions[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": g.refPrefix + name}
+	}
+	return map[string]interface{}{}
+}
+
+// structSchema returns the object schema of the struct type t using the json tags, the fields
+// without omitempty are required.
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	var required []string
+	g.structFields(t, props, &required)
+
+	s := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// structFields adds the fields of the struct type t to props, including the embedded ones.
+func (g *schemaGenerator) structFields(t reflect.Type, props map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			name, opts = tag[:i], tag[i:]
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			g.structFields(ft, props, required)
+			continue
+		}
+		if f.PkgPath != "" {
+			// Unexported field
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		props[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
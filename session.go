@@ -0,0 +1,223 @@
+package jrpc2go
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSessionClosed is returned when notifying a session that was closed or expired.
+var ErrSessionClosed = errors.New("jsonrpc: session closed")
+
+// ErrSessionFull is returned when notifying a session with too many pending notifications.
+var ErrSessionFull = errors.New("jsonrpc: session has too many pending notifications")
+
+// Notification represents a server-initiated JSON RPC notification sent to a client.
+//
+// Version - A String specifying the version of the JSON-RPC protocol. MUST be exactly "2.0".
+//
+// Method - A String containing the name of the method notified.
+//
+// Params - A Structured value with the notification parameters.
+type Notification struct {
+	Version string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// Notifier sends notifications to a connected client.
+type Notifier interface {
+	Notify(ctx context.Context, method string, params interface{}) error
+}
+
+// NotifierFromContext returns the Notifier of the client that sent the request, it's nil if the
+// transport doesn't support server-initiated notifications.
+func NotifierFromContext(ctx context.Context) Notifier {
+	n, _ := ctx.Value(notifierKey).(Notifier)
+	return n
+}
+
+// contextWithNotifier returns a copy of ctx with the Notifier of the client.
+func contextWithNotifier(ctx context.Context, n Notifier) context.Context {
+	return context.WithValue(ctx, notifierKey, n)
+}
+
+// Session keeps the notifications pending delivery to a client of a transport without a
+// persistent connection, like long polling.
+type Session struct {
+	id         string
+	maxPending int
+
+	mu       sync.Mutex
+	pending  []*Notification
+	ready    chan struct{}
+	lastSeen time.Time
+	closed   bool
+}
+
+// newSession returns an empty session with a random ID.
+func newSession(maxPending int) *Session {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("jsonrpc: fail to generate session id: " + err.Error())
+	}
+	return &Session{
+		id:         hex.EncodeToString(b),
+		maxPending: maxPending,
+		ready:      make(chan struct{}, 1),
+		lastSeen:   time.Now(),
+	}
+}
+
+// ID returns the unique session identifier.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Notify will queue the notification until the client fetches it.
+//
+// It returns ErrSessionFull if the client is not fetching the notifications and
+// ErrSessionClosed if the session is closed.
+func (s *Session) Notify(ctx context.Context, method string, params interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrSessionClosed
+	}
+	if s.maxPending > 0 && len(s.pending) >= s.maxPending {
+		return ErrSessionFull
+	}
+	s.pending = append(s.pending, &Notification{Version: version, Method: method, Params: params})
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// wait returns the pending notifications, if there are none it waits until a notification is
+// queued or the ctx is done.
+func (s *Session) wait(ctx context.Context) []*Notification {
+	for {
+		if n := s.take(); len(n) > 0 {
+			return n
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.ready:
+		}
+	}
+}
+
+// take returns and removes the pending notifications.
+func (s *Session) take() []*Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.pending
+	s.pending = nil
+	s.lastSeen = time.Now()
+	return n
+}
+
+// touch marks the session as used now.
+func (s *Session) touch() {
+	s.mu.Lock()
+	s.lastSeen = time.Now()
+	s.mu.Unlock()
+}
+
+// expired reports if the session was not used for the ttl.
+func (s *Session) expired(now time.Time, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return now.Sub(s.lastSeen) > ttl
+}
+
+// close will discard the pending notifications and reject new ones.
+func (s *Session) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.pending = nil
+	s.mu.Unlock()
+}
+
+// sessionRegistry keeps the live sessions and removes the expired ones.
+type sessionRegistry struct {
+	ttl         time.Duration
+	maxPending  int
+	maxSessions int
+
+	mu        sync.Mutex
+	sessions  map[string]*Session
+	lastSweep time.Time
+}
+
+// newSessionRegistry returns an empty registry where sessions expire after the ttl without use,
+// with at most maxSessions live sessions if it's greater than 0.
+func newSessionRegistry(ttl time.Duration, maxPending, maxSessions int) *sessionRegistry {
+	return &sessionRegistry{
+		ttl:         ttl,
+		maxPending:  maxPending,
+		maxSessions: maxSessions,
+		sessions:    make(map[string]*Session),
+		lastSweep:   time.Now(),
+	}
+}
+
+// create returns a new session, ok is false if the limit of live sessions is reached.
+func (r *sessionRegistry) create() (s *Session, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sweep()
+	if r.maxSessions > 0 && len(r.sessions) >= r.maxSessions {
+		// The expired sessions are removed before rejecting, even if the last sweep is recent
+		r.lastSweep = time.Time{}
+		if r.sweep(); len(r.sessions) >= r.maxSessions {
+			return nil, false
+		}
+	}
+	s = newSession(r.maxPending)
+	r.sessions[s.id] = s
+	return s, true
+}
+
+// get returns the session with the id if it didn't expire.
+func (r *sessionRegistry) get(id string) (*Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sweep()
+	s, ok := r.sessions[id]
+	if ok {
+		s.touch()
+	}
+	return s, ok
+}
+
+// sweep removes the expired sessions, it runs at most twice per ttl and r.mu must be held.
+func (r *sessionRegistry) sweep() {
+	now := time.Now()
+	if now.Sub(r.lastSweep) < r.ttl/2 {
+		return
+	}
+	r.lastSweep = now
+	for id, s := range r.sessions {
+		if s.expired(now, r.ttl) {
+			s.close()
+			delete(r.sessions, id)
+		}
+	}
+}
+
+// closeAll will close and remove all the sessions.
+func (r *sessionRegistry) closeAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, s := range r.sessions {
+		s.close()
+		delete(r.sessions, id)
+	}
+}
@@ -0,0 +1,48 @@
+package jrpc2go
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+// ErrInvalidSignature is returned by a Signer when the signature doesn't match the payload.
+var ErrInvalidSignature = errors.New("jsonrpc: invalid signature")
+
+// Signer signs and verifies detached signatures of the raw messages, so the integrity of the
+// requests and responses is guaranteed across untrusted intermediaries.
+type Signer interface {
+	// Sign returns the signature of the payload.
+	Sign(payload []byte) ([]byte, error)
+	// Verify returns ErrInvalidSignature if the signature doesn't match the payload.
+	Verify(payload, signature []byte) error
+}
+
+// hmacSigner is a Signer using HMAC-SHA256.
+type hmacSigner struct {
+	key []byte
+}
+
+// NewHMACSigner returns a Signer that uses HMAC-SHA256 with the shared key.
+func NewHMACSigner(key []byte) Signer {
+	if len(key) == 0 {
+		panic("jsonrpc: hmac key should not be empty")
+	}
+	return &hmacSigner{key: append([]byte(nil), key...)}
+}
+
+// Sign returns the HMAC-SHA256 of the payload.
+func (s *hmacSigner) Sign(payload []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, s.key)
+	_, _ = mac.Write(payload)
+	return mac.Sum(nil), nil
+}
+
+// Verify compares the signature with the HMAC-SHA256 of the payload in constant time.
+func (s *hmacSigner) Verify(payload, signature []byte) error {
+	expected, _ := s.Sign(payload)
+	if !hmac.Equal(expected, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package jrpc2go
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// RequestInfo describes a request that exceeded the slow request threshold.
+//
+// ParamsSize - The size in bytes of the raw params.
+type RequestInfo struct {
+	Method        string
+	ID            *json.RawMessage
+	CorrelationID string
+	ParamsSize    int
+	Elapsed       time.Duration
+	Error         *Error
+}
+
+// logSlowRequest is the default callback for the slow requests.
+func logSlowRequest(info RequestInfo) {
+	id := "null"
+	if info.ID != nil {
+		id = idString(*info.ID)
+	}
+	// The method and the ID are quoted since they are sent by the client
+	log.Printf("jrpc2go: slow request method=%q id=%q correlation=%s params=%dB elapsed=%v",
+		info.Method, id, info.CorrelationID, info.ParamsSize, info.Elapsed)
+}
+
+// reportSlow will invoke the slow request callback if the execution exceeded the threshold.
+func (m *Manager) reportSlow(ctx context.Context, req *Request, res *Response, elapsed time.Duration) {
+	if m.slowThreshold <= 0 || elapsed < m.slowThreshold {
+		return
+	}
+	info := RequestInfo{
+		Method:        req.Method,
+		ID:            req.ID,
+		CorrelationID: CorrelationIDFromContext(ctx),
+		Elapsed:       elapsed,
+		Error:         res.Error,
+	}
+	if req.Params != nil {
+		info.ParamsSize = len(*req.Params)
+	}
+	fn := m.slowFunc
+	if fn == nil {
+		fn = logSlowRequest
+	}
+	fn(info)
+}
@@ -0,0 +1,685 @@
+package jrpc2go
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrServerClosed is returned by Server.Serve after a call to Server.Shutdown.
+var ErrServerClosed = errors.New("jsonrpc: server closed")
+
+// ServerOption configures the Server.
+type ServerOption func(*Server)
+
+// WithMaxPipelined sets the maximum number of requests of one connection executed at the same time,
+// the connection is not read while the limit is reached. The responses are sent as soon as they
+// are ready, so they can be out of order, and each response is written atomically.
+//
+// Default is 16, 1 means the requests of a connection are executed sequentially.
+func WithMaxPipelined(n int) ServerOption {
+	return func(s *Server) {
+		if n > 0 {
+			s.maxPipelined = n
+		}
+	}
+}
+
+// WithRegistry sets the Registry where the connections are registered while they are open, so the
+// application can send notifications to them with Registry.Broadcast and Registry.SendTo.
+func WithRegistry(r *Registry) ServerOption {
+	return func(s *Server) {
+		s.registry = r
+	}
+}
+
+// WithHeartbeat enables the pings sent by the server to each connection every interval, a
+// connection that doesn't answer a ping before the next one is considered half-open and closed.
+// The round-trip latency is collected on the Heartbeat of Manager.Stats when enabled.
+//
+// The pings are rpc.ping requests with a string ID, the clients must reply them with any result.
+func WithHeartbeat(interval time.Duration) ServerOption {
+	return func(s *Server) {
+		s.heartbeat = interval
+	}
+}
+
+// WithReadTimeout sets the maximum time to read a request once its first byte is received, so a
+// peer that stalls in the middle of a request can't hold the connection. It's also the time to
+// wait for the next request if WithIdleTimeout is not set.
+//
+// Default is 0, which means no timeout.
+func WithReadTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.readTimeout = d
+	}
+}
+
+// WithWriteTimeout sets the maximum time to write each response or notification, so a peer that
+// isn't reading can't block the goroutines writing to the connection. The connection is closed
+// when a write times out.
+//
+// Default is 0, which means no timeout.
+func WithWriteTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.writeTimeout = d
+	}
+}
+
+// WithIdleTimeout sets the maximum time to wait for the next request, the connection is closed
+// after the requests being executed reply. If it's 0 the WithReadTimeout value is used.
+//
+// Default is 0, which means no timeout.
+func WithIdleTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.idleTimeout = d
+	}
+}
+
+// WithMaxConns sets the maximum number of connections open at the same time, the connections
+// accepted over the limit are closed and counted on the RejectedConns of Manager.Stats. While
+// the limit is reached the accepts are delayed with an increasing backoff, up to 1 second.
+//
+// Default is 0, which means no limit.
+func WithMaxConns(n int) ServerOption {
+	return func(s *Server) {
+		s.maxConns = n
+	}
+}
+
+// WithMaxConnsPerIP sets the maximum number of connections open at the same time from the same
+// remote IP, the connections over the limit are closed and counted on the RejectedConns of
+// Manager.Stats. It only applies to TCP connections.
+//
+// Default is 0, which means no limit.
+func WithMaxConnsPerIP(n int) ServerOption {
+	return func(s *Server) {
+		s.maxConnsPerIP = n
+	}
+}
+
+// ShuttingDownMethod is the default method of the notification sent by WithShutdownNotice.
+const ShuttingDownMethod = "rpc.shuttingDown"
+
+// ShutdownNotice is the params of the shutdown notification, Deadline is when the connections are
+// closed, it's omitted if the Shutdown ctx has no deadline.
+type ShutdownNotice struct {
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// WithShutdownNotice enables the notification sent to the connections at the start of Shutdown,
+// so the clients stop sending new requests and reconnect to another server before the deadline.
+// The method is ShuttingDownMethod if empty and the params are a ShutdownNotice.
+func WithShutdownNotice(method string) ServerOption {
+	return func(s *Server) {
+		if method == "" {
+			method = ShuttingDownMethod
+		}
+		s.shutdownMethod = method
+	}
+}
+
+// Server serves a Manager on stream connections like TCP or Unix sockets, the requests and
+// the responses are JSON values, each response is terminated by a newline.
+//
+// The methods can send notifications to the connection with the Notifier from NotifierFromContext.
+type Server struct {
+	m            *Manager
+	maxPipelined int
+	registry     *Registry
+	heartbeat    time.Duration
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	idleTimeout  time.Duration
+
+	maxConns      int
+	maxConnsPerIP int
+	proxyProtocol bool
+	allowedUIDs   map[uint32]bool
+	codecs        map[string]Codec
+	maxFrameSize  int
+	preFilter     PreFilter
+
+	shutdownMethod string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*serverConn]struct{}
+	perIP     map[string]int
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewServer returns a Server for the Manager.
+func NewServer(m *Manager, opts ...ServerOption) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		m:            m,
+		maxPipelined: 16,
+		maxFrameSize: defaultMaxFrameSize,
+		ctx:          ctx,
+		cancel:       cancel,
+		listeners:    make(map[net.Listener]struct{}),
+		conns:        make(map[*serverConn]struct{}),
+		perIP:        make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Serve will accept the connections on the listener and handle their requests, each connection
+// is handled on its own goroutine.
+//
+// It always returns an error, ErrServerClosed after a call to Shutdown.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.Close()
+	}()
+
+	var backoff time.Duration
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Temporary() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		sc := &serverConn{conn: c, srv: s, ip: remoteIP(c)}
+		sc.r = &deadlineReader{conn: c, read: s.readTimeout, idle: s.idleTimeout}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return ErrServerClosed
+		}
+		if full, ok := s.admit(sc); !ok {
+			s.mu.Unlock()
+			c.Close()
+			if s.m.stats != nil {
+				s.m.stats.addRejectedConn()
+			}
+			s.m.emit(ManagerEvent{Kind: OverloadRejected, RemoteAddr: c.RemoteAddr()})
+			if full {
+				backoff = nextBackoff(backoff)
+				time.Sleep(backoff)
+			}
+			continue
+		}
+		backoff = 0
+		s.conns[sc] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(sc)
+	}
+}
+
+// admit returns true if the connection is within the limits and counts it on its IP, full is
+// true if it was rejected by the maximum connections. The lock must be held.
+func (s *Server) admit(sc *serverConn) (full bool, ok bool) {
+	if s.maxConns > 0 && len(s.conns) >= s.maxConns {
+		return true, false
+	}
+	if sc.ip == "" {
+		return false, true
+	}
+	if s.maxConnsPerIP > 0 && s.perIP[sc.ip] >= s.maxConnsPerIP {
+		return false, false
+	}
+	s.perIP[sc.ip]++
+	return false, true
+}
+
+// nextBackoff returns the delay of the next accept, it doubles from 5ms up to 1s.
+func nextBackoff(d time.Duration) time.Duration {
+	if d == 0 {
+		return 5 * time.Millisecond
+	}
+	if d *= 2; d > time.Second {
+		d = time.Second
+	}
+	return d
+}
+
+// remoteIP returns the IP of the peer of a TCP connection or empty for the other networks.
+func remoteIP(c net.Conn) string {
+	if addr, ok := c.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP.String()
+	}
+	return ""
+}
+
+// tlsHandshakeTimeout is the maximum time to complete the TLS handshake of a connection.
+const tlsHandshakeTimeout = 10 * time.Second
+
+// connTransportInfo returns the description of the transport of the connection, the TLS
+// handshake is done first so its state is known.
+func connTransportInfo(c net.Conn) (TransportInfo, error) {
+	info := TransportInfo{Kind: "socket", Network: c.LocalAddr().Network(), Endpoint: c.LocalAddr().String()}
+	if tc, ok := c.(*tls.Conn); ok {
+		_ = tc.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+		defer tc.SetDeadline(time.Time{})
+		if err := tc.Handshake(); err != nil {
+			return info, err
+		}
+		st := tc.ConnectionState()
+		info.TLS = &st
+	}
+	return info, nil
+}
+
+// Shutdown will close the listeners, stop reading new requests and wait for the requests being
+// executed to reply. If the ctx is done before that the connections are closed and the ctx error
+// is returned.
+//
+// With WithShutdownNotice the connections are notified first with the ctx deadline, the notices
+// are sent concurrently and each one is written within the WithWriteTimeout, 5 seconds if none,
+// or the ctx deadline if it's sooner, so a peer that isn't reading can't block the shutdown.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	conns := make([]*serverConn, 0, len(s.conns))
+	var notified []*serverConn
+	for c := range s.conns {
+		conns = append(conns, c)
+		if !c.noNotice {
+			notified = append(notified, c)
+		}
+	}
+	s.mu.Unlock()
+
+	if s.shutdownMethod != "" {
+		s.notifyShutdown(ctx, notified)
+	}
+	for _, c := range conns {
+		// Unblock the connections waiting for the next request
+		_ = c.conn.SetReadDeadline(time.Now())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		s.mu.Lock()
+		for c := range s.conns {
+			c.conn.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// defaultNoticeTimeout is the maximum time to write the shutdown notice of a connection if the
+// Server has no write timeout.
+const defaultNoticeTimeout = 5 * time.Second
+
+// notifyShutdown will send the shutdown notice to the connections concurrently and wait for the
+// writes, each one has its own deadline.
+func (s *Server) notifyShutdown(ctx context.Context, conns []*serverConn) {
+	notice := ShutdownNotice{}
+	d, hasDeadline := ctx.Deadline()
+	if hasDeadline {
+		notice.Deadline = &d
+	}
+	timeout := s.writeTimeout
+	if timeout <= 0 {
+		timeout = defaultNoticeTimeout
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range conns {
+		deadline := time.Now().Add(timeout)
+		if hasDeadline && d.Before(deadline) {
+			deadline = d
+		}
+		// A write in progress to a peer that isn't reading can't hold the notice past the deadline
+		_ = c.conn.SetWriteDeadline(deadline)
+		wg.Add(1)
+		go func(c *serverConn) {
+			defer wg.Done()
+			if err := c.notifyBefore(deadline, s.shutdownMethod, notice); err != nil {
+				// The connection is broken, the responses can't be written either
+				c.conn.Close()
+			}
+		}(c)
+	}
+	wg.Wait()
+}
+
+// isClosed reports if Shutdown was called.
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// serveConn will read and handle the requests of the connection until it's closed, the
+// requests are executed concurrently up to the pipelining limit.
+func (s *Server) serveConn(sc *serverConn) {
+	var pending sync.WaitGroup
+	defer func() {
+		// Let the requests being executed reply before closing
+		pending.Wait()
+		sc.conn.Close()
+		s.mu.Lock()
+		delete(s.conns, sc)
+		if sc.ip != "" {
+			if s.perIP[sc.ip]--; s.perIP[sc.ip] == 0 {
+				delete(s.perIP, sc.ip)
+			}
+		}
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	ctx, ok := s.authorizePeer(s.ctx, sc.conn)
+	if !ok {
+		if s.m.stats != nil {
+			s.m.stats.addRejectedConn()
+		}
+		return
+	}
+	remote := sc.conn.RemoteAddr()
+	if s.proxyProtocol {
+		pc, err := newProxyConn(sc.conn)
+		if err != nil {
+			return
+		}
+		// Only the reads are done on the proxyConn, the writes and deadlines are on the same conn
+		sc.r.conn = pc
+		remote = pc.RemoteAddr()
+	}
+
+	s.m.emit(ManagerEvent{Kind: ConnectionOpened, RemoteAddr: remote})
+	defer func() {
+		pending.Wait()
+		sc.conn.Close()
+		s.m.emit(ManagerEvent{Kind: ConnectionClosed, RemoteAddr: remote})
+	}()
+
+	info, err := connTransportInfo(sc.conn)
+	if err != nil {
+		return
+	}
+	ctx = ContextWithTransportInfo(contextWithNotifier(ctx, sc), info)
+	ctx = ContextWithRemoteAddr(ctx, remote)
+	if s.registry != nil {
+		id, unregister := s.registry.Register(sc)
+		defer unregister()
+		ctx = context.WithValue(ctx, connIDKey, id)
+	}
+	if s.heartbeat > 0 {
+		sc.ping = &stopper{done: make(chan struct{})}
+		defer sc.ping.stop()
+		go s.ping(sc, sc.ping.done)
+	}
+
+	sem := make(chan struct{}, s.maxPipelined)
+	dec := json.NewDecoder(sc.r)
+	// Set by the negotiation, only the first message can start it
+	var codec Codec
+	var frames *bufio.Reader
+	first := true
+	for {
+		// The deadline is set before the check so it can't override the one set by Shutdown
+		sc.r.next()
+		if s.isClosed() {
+			return
+		}
+		var raw json.RawMessage
+		if codec != nil {
+			var err error
+			if raw, err = readFrame(frames, codec, s.maxFrameSize); err != nil {
+				var e *Error
+				if !errors.As(err, &e) {
+					return
+				}
+				_ = sc.writeValue(&Response{Version: version, Error: e})
+				continue
+			}
+		} else if err := dec.Decode(&raw); err != nil {
+			var se *json.SyntaxError
+			if errors.As(err, &se) {
+				// The stream can't be resynchronized after a syntax error
+				_ = sc.writeValue(&Response{Version: version, Error: newError(errCodeParseError, err.Error())})
+			}
+			return
+		}
+		if first {
+			first = false
+			if c, ok := s.negotiate(sc, raw); ok {
+				if sc.noCancel {
+					ctx = context.WithValue(ctx, noCancelKey, true)
+				}
+				if c != nil {
+					codec = c
+					frames = bufio.NewReader(io.MultiReader(dec.Buffered(), sc.r))
+				}
+				continue
+			}
+		}
+		if rtt, ok := sc.hb.answer(raw, time.Now()); ok {
+			if s.m.stats != nil {
+				s.m.stats.addPing(rtt)
+			}
+			continue
+		}
+
+		if s.preFilter != nil {
+			err := s.preFilter(&RawRequest{Body: raw, RemoteAddr: remote.String(), Transport: info})
+			var e *Error
+			if errors.As(err, &e) {
+				_ = sc.writeValue(&Response{Version: version, Error: e})
+				continue
+			}
+			if err != nil {
+				return
+			}
+		}
+
+		sem <- struct{}{}
+		pending.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				pending.Done()
+			}()
+			s.handle(ctx, sc, raw)
+		}()
+	}
+}
+
+// ping will send a ping to the connection every heartbeat interval until done is closed, the
+// connection is closed when a ping is not answered before the next one.
+func (s *Server) ping(sc *serverConn, done <-chan struct{}) {
+	t := time.NewTicker(s.heartbeat)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+		}
+		id, ok := sc.hb.next(time.Now())
+		if !ok {
+			if s.m.stats != nil {
+				s.m.stats.addPingTimeout()
+			}
+			sc.conn.Close()
+			return
+		}
+		raw := json.RawMessage(strconv.Quote(id))
+		if err := sc.writeValue(&Request{Version: version, Method: pingMethodName, ID: &raw}); err != nil {
+			sc.conn.Close()
+			return
+		}
+	}
+}
+
+// handle will execute the request and write the response to the connection.
+func (s *Server) handle(ctx context.Context, sc *serverConn, raw json.RawMessage) {
+	var out bytes.Buffer
+	if err := s.m.Handle(ctx, bytes.NewReader(raw), &out); err != nil {
+		// The malformed requests are replied by Handle
+		var e *Error
+		if !errors.As(err, &e) {
+			return
+		}
+	}
+	if err := sc.write(out.Bytes()); err != nil {
+		// The connection is broken, stop reading the next requests
+		sc.conn.Close()
+	}
+}
+
+// serverConn is a connection of the Server.
+type serverConn struct {
+	conn net.Conn
+	r    *deadlineReader
+	srv  *Server
+	ip   string
+	hb   heartbeat
+	ping *stopper
+
+	// noNotice is set by the negotiation and guarded by the Server mutex
+	noNotice bool
+	// noCancel is set by the negotiation, it's only used by the connection read loop
+	noCancel bool
+
+	wmu   sync.Mutex
+	codec Codec
+	// stopBy is the deadline of the writes once the shutdown notice was sent, guarded by wmu
+	stopBy time.Time
+}
+
+// write will write the encoded message to the connection terminated by a newline, or as a
+// compressed frame once negotiated, the writes are serialized so the messages are never
+// interleaved.
+func (sc *serverConn) write(b []byte) error {
+	sc.wmu.Lock()
+	defer sc.wmu.Unlock()
+	if d := sc.srv.writeTimeout; d > 0 {
+		deadline := time.Now().Add(d)
+		if !sc.stopBy.IsZero() && sc.stopBy.Before(deadline) {
+			deadline = sc.stopBy
+		}
+		_ = sc.conn.SetWriteDeadline(deadline)
+	}
+	return sc.writeMessage(b)
+}
+
+// writeMessage will write the message as a line or a compressed frame, sc.wmu must be held.
+func (sc *serverConn) writeMessage(b []byte) error {
+	if sc.codec != nil {
+		return writeFrame(sc.conn, sc.codec, b)
+	}
+	return writeLine(sc.conn, b)
+}
+
+// writeValue will encode the value with the Manager encoder and write it to the connection.
+func (sc *serverConn) writeValue(v interface{}) error {
+	var out bytes.Buffer
+	if err := sc.srv.m.encoder.encode(&out, v); err != nil {
+		return err
+	}
+	return sc.write(out.Bytes())
+}
+
+// Notify will send the notification to the connection.
+func (sc *serverConn) Notify(ctx context.Context, method string, params interface{}) error {
+	return sc.writeValue(&Notification{Version: version, Method: method, Params: params})
+}
+
+// notifyBefore will send the notification with the write deadline, the later writes can't go
+// past it, so the responses of the requests still being executed are written until then.
+func (sc *serverConn) notifyBefore(deadline time.Time, method string, params interface{}) error {
+	var out bytes.Buffer
+	if err := sc.srv.m.encoder.encode(&out, &Notification{Version: version, Method: method, Params: params}); err != nil {
+		return err
+	}
+	sc.wmu.Lock()
+	defer sc.wmu.Unlock()
+	// The deadline is kept until the connection is closed, so the responses written after the
+	// notice to a peer that isn't reading can't block the shutdown either
+	sc.stopBy = deadline
+	_ = sc.conn.SetWriteDeadline(deadline)
+	return sc.writeMessage(out.Bytes())
+}
+
+// deadlineReader reads the requests of a connection with the idle deadline while waiting for a
+// request and the read deadline once the request starts to be received.
+type deadlineReader struct {
+	conn    net.Conn
+	read    time.Duration
+	idle    time.Duration
+	reading bool
+}
+
+// next will set the deadline to wait for the next request.
+func (r *deadlineReader) next() {
+	r.reading = false
+	d := r.idle
+	if d == 0 {
+		d = r.read
+	}
+	if d > 0 {
+		_ = r.conn.SetReadDeadline(time.Now().Add(d))
+	}
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	n, err := r.conn.Read(p)
+	// The whitespace between the requests doesn't start a new one
+	if !r.reading && len(bytes.TrimSpace(p[:n])) > 0 {
+		r.reading = true
+		switch {
+		case r.read > 0:
+			_ = r.conn.SetReadDeadline(time.Now().Add(r.read))
+		case r.idle > 0:
+			_ = r.conn.SetReadDeadline(time.Time{})
+		}
+	}
+	return n, err
+}
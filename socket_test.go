@@ -0,0 +1,589 @@
+package jrpc2go_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+// startServer returns a connection to a Server of m listening on a random local port.
+func startServer(t *testing.T, m *jrpc.Manager, opts ...jrpc.ServerOption) (*jrpc.Server, net.Conn) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	srv := jrpc.NewServer(m, opts...)
+	go func() { _ = srv.Serve(l) }()
+
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() error = %v", err)
+	}
+	return srv, c
+}
+
+func TestServer(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Add("start", &notifyMethod{}).
+		Build()
+	srv, c := startServer(t, &m, jrpc.WithMaxPipelined(1))
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+	tests := []struct {
+		name  string
+		req   string
+		wantW []string
+	}{
+		{
+			name:  "Request",
+			req:   `{"jsonrpc":"2.0","method":"add","id":1,"params":{"v1":1,"v2":2}}`,
+			wantW: []string{`{"jsonrpc":"2.0","id":1,"result":3}`},
+		},
+		{
+			name:  "Notification From Method",
+			req:   `{"jsonrpc":"2.0","method":"start","id":2}`,
+			wantW: []string{`{"jsonrpc":"2.0","method":"progress","params":100}`, `{"jsonrpc":"2.0","id":2,"result":"started"}`},
+		},
+		{
+			name:  "Concatenated Requests",
+			req:   `{"jsonrpc":"2.0","method":"add","id":3,"params":{"v1":1,"v2":1}}{"jsonrpc":"2.0","method":"add","id":4,"params":{"v1":2,"v2":2}}`,
+			wantW: []string{`{"jsonrpc":"2.0","id":3,"result":2}`, `{"jsonrpc":"2.0","id":4,"result":4}`},
+		},
+		{
+			name:  "Invalid Request",
+			req:   `5`,
+			wantW: []string{`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request",`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Write([]byte(tt.req + "\n")); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			_ = c.SetReadDeadline(time.Now().Add(time.Second))
+			for _, want := range tt.wantW {
+				got, err := r.ReadString('\n')
+				if err != nil {
+					t.Fatalf("ReadString() error = %v", err)
+				}
+				if got != want+"\n" && !(strings.HasSuffix(want, ",") && strings.HasPrefix(got, want)) {
+					t.Errorf("Server response = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Errorf("Server.Shutdown() error = %v", err)
+	}
+}
+
+func TestServer_Pipelining(t *testing.T) {
+	block := &blockMethod{release: make(chan struct{})}
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		Add("block", block).
+		Build()
+	srv, c := startServer(t, &m)
+	defer srv.Shutdown(context.Background())
+	defer c.Close()
+
+	req := `{"jsonrpc":"2.0","method":"block","id":1}` + "\n" + `{"jsonrpc":"2.0","method":"add","id":2,"params":{"v1":1,"v2":1}}` + "\n"
+	if _, err := c.Write([]byte(req)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	r := bufio.NewReader(c)
+	_ = c.SetReadDeadline(time.Now().Add(time.Second))
+	for i, want := range []string{`{"jsonrpc":"2.0","id":2,"result":2}`, `{"jsonrpc":"2.0","id":1,"result":true}`} {
+		got, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("ReadString() error = %v", err)
+		}
+		if got != want+"\n" {
+			t.Errorf("Server response = %v, want %v", got, want)
+		}
+		if i == 0 {
+			close(block.release)
+		}
+	}
+}
+
+func TestServer_WithRegistry(t *testing.T) {
+	m := jrpc.NewManagerBuilder().Add("add", &addMethod{}).Build()
+	reg := jrpc.NewRegistry()
+	srv, c := startServer(t, &m, jrpc.WithRegistry(reg))
+	defer srv.Shutdown(context.Background())
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+	_ = c.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"add","id":1,"params":{"v1":1,"v2":1}}` + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := r.ReadString('\n'); err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+
+	if got := reg.Broadcast(context.Background(), "news", "hello"); got != 1 {
+		t.Errorf("Registry.Broadcast() = %v, want 1", got)
+	}
+	got, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+	if want := `{"jsonrpc":"2.0","method":"news","params":"hello"}` + "\n"; got != want {
+		t.Errorf("Server notification = %v, want %v", got, want)
+	}
+	if err := reg.SendTo(context.Background(), "unknown", "news", nil); err != jrpc.ErrConnNotFound {
+		t.Errorf("Registry.SendTo() error = %v, want %v", err, jrpc.ErrConnNotFound)
+	}
+}
+
+func TestServer_WithShutdownNotice(t *testing.T) {
+	m := jrpc.NewManagerBuilder().Add("add", &addMethod{}).Build()
+	srv, c := startServer(t, &m, jrpc.WithShutdownNotice(""))
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+	_ = c.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"add","id":1,"params":{"v1":1,"v2":1}}` + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := r.ReadString('\n'); err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+
+	deadline := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Errorf("Server.Shutdown() error = %v", err)
+	}
+	got, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+	if want := `{"jsonrpc":"2.0","method":"rpc.shuttingDown","params":{"deadline":"2030-01-02T03:04:05Z"}}` + "\n"; got != want {
+		t.Errorf("Server notification = %v, want %v", got, want)
+	}
+}
+
+func TestServer_WithShutdownNotice_StuckPeer(t *testing.T) {
+	tests := []struct {
+		name string
+		// afterNotice writes the response that the peer doesn't read after the shutdown notice
+		afterNotice bool
+	}{
+		{name: "Response Before Notice"},
+		{name: "Response After Notice", afterNotice: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			big := strings.Repeat("a", 64<<20)
+			started, release := make(chan struct{}), make(chan struct{})
+			m := jrpc.NewManagerBuilder().
+				Add("add", &addMethod{}).
+				Add("big", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+					close(started)
+					<-release
+					resp.Result = big
+				})).
+				Build()
+			srv, c := startServer(t, &m, jrpc.WithShutdownNotice(""))
+			defer c.Close()
+
+			r := bufio.NewReader(c)
+			_ = c.SetReadDeadline(time.Now().Add(time.Second))
+			if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"add","id":1,"params":{"v1":1,"v2":1}}` + "\n")); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if _, err := r.ReadString('\n'); err != nil {
+				t.Fatalf("ReadString() error = %v", err)
+			}
+
+			// The peer asks for a response larger than the socket buffers and never reads it
+			stuck, err := net.Dial("tcp", c.RemoteAddr().String())
+			if err != nil {
+				t.Fatalf("net.Dial() error = %v", err)
+			}
+			defer stuck.Close()
+			if _, err := stuck.Write([]byte(`{"jsonrpc":"2.0","method":"big","id":1}` + "\n")); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			<-started
+			if !tt.afterNotice {
+				close(release)
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			// Without a ctx deadline nor a write timeout the notices still have their own deadline
+			done := make(chan error, 1)
+			go func() { done <- srv.Shutdown(context.Background()) }()
+
+			// The notice isn't delayed by the peer that isn't reading
+			_ = c.SetReadDeadline(time.Now().Add(time.Second))
+			got, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("ReadString() error = %v", err)
+			}
+			if want := `{"jsonrpc":"2.0","method":"rpc.shuttingDown","params":{}}` + "\n"; got != want {
+				t.Errorf("Server notification = %v, want %v", got, want)
+			}
+			if tt.afterNotice {
+				close(release)
+			}
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Server.Shutdown() error = %v", err)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("Server.Shutdown() is blocked by the peer that isn't reading")
+			}
+		})
+	}
+}
+
+func TestServer_WithHeartbeat(t *testing.T) {
+	m := jrpc.NewManagerBuilder().EnablePing().EnableStats().Build()
+	srv, c := startServer(t, &m, jrpc.WithHeartbeat(20*time.Millisecond))
+	defer srv.Shutdown(context.Background())
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+	_ = c.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"rpc.ping","id":1}` + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+	if want := `{"jsonrpc":"2.0","id":1,"result":"pong"}` + "\n"; got != want {
+		t.Errorf("rpc.ping response = %v, want %v", got, want)
+	}
+
+	got, err = r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+	if want := `{"jsonrpc":"2.0","method":"rpc.ping","id":"ping-1"}` + "\n"; got != want {
+		t.Errorf("Server ping = %v, want %v", got, want)
+	}
+	if _, err := c.Write([]byte(`{"jsonrpc":"2.0","id":"ping-1","result":"pong"}` + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// The second ping is not answered so the connection is closed
+	if _, err := r.ReadString('\n'); err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+	if _, err := r.ReadString('\n'); err == nil {
+		t.Errorf("ReadString() error = nil, want the connection closed")
+	}
+	if hb := m.Stats().Heartbeat; hb.Pings != 1 || hb.Timeouts != 1 {
+		t.Errorf("Stats().Heartbeat = %+v, want 1 ping and 1 timeout", hb)
+	}
+}
+
+func TestServeActivated_NotActivated(t *testing.T) {
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	os.Setenv("LISTEN_FDS", "1")
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+
+	m := jrpc.NewManagerBuilder().Build()
+	if err := jrpc.ServeActivated(context.Background(), &m); err != jrpc.ErrNotActivated {
+		t.Errorf("ServeActivated() error = %v, want %v", err, jrpc.ErrNotActivated)
+	}
+}
+
+func TestServer_Timeouts(t *testing.T) {
+	m := jrpc.NewManagerBuilder().Add("add", &addMethod{}).Build()
+
+	tests := []struct {
+		name string
+		opts []jrpc.ServerOption
+		send string
+	}{
+		{
+			name: "Idle Connection",
+			opts: []jrpc.ServerOption{jrpc.WithIdleTimeout(50 * time.Millisecond)},
+		},
+		{
+			name: "Stalled Request",
+			opts: []jrpc.ServerOption{jrpc.WithIdleTimeout(time.Minute), jrpc.WithReadTimeout(50 * time.Millisecond)},
+			send: `{"jsonrpc":"2.0","method":`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, c := startServer(t, &m, tt.opts...)
+			defer srv.Shutdown(context.Background())
+			defer c.Close()
+
+			r := bufio.NewReader(c)
+			_ = c.SetReadDeadline(time.Now().Add(time.Second))
+			if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"add","params":{"v1":1,"v2":2},"id":1}` + "\n" + tt.send)); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got, err := r.ReadString('\n'); err != nil || !strings.Contains(got, `"result":3`) {
+				t.Fatalf("ReadString() = %v, %v, want the add result", got, err)
+			}
+
+			// The server closes the connection before the client read deadline
+			_, err := r.ReadString('\n')
+			if ne, ok := err.(net.Error); err == nil || ok && ne.Timeout() {
+				t.Errorf("ReadString() error = %v, want the connection closed", err)
+			}
+		})
+	}
+}
+
+func TestServer_WithMaxConnsPerIP(t *testing.T) {
+	m := jrpc.NewManagerBuilder().Add("add", &addMethod{}).EnableStats().Build()
+	srv, c := startServer(t, &m, jrpc.WithMaxConnsPerIP(1))
+	defer srv.Shutdown(context.Background())
+	defer c.Close()
+
+	// The first connection is admitted
+	_ = c.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"add","params":{"v1":1,"v2":2},"id":1}` + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := bufio.NewReader(c).ReadString('\n'); err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+
+	c2, err := net.Dial("tcp", c.RemoteAddr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() error = %v", err)
+	}
+	defer c2.Close()
+	_ = c2.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = bufio.NewReader(c2).ReadString('\n')
+	if ne, ok := err.(net.Error); err == nil || ok && ne.Timeout() {
+		t.Errorf("ReadString() error = %v, want the connection closed", err)
+	}
+	if got := m.Stats().RejectedConns; got != 1 {
+		t.Errorf("Stats().RejectedConns = %v, want 1", got)
+	}
+}
+
+func TestServer_TransportInfo(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("transport", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			info, _ := jrpc.TransportInfoFromContext(req.Context())
+			resp.Result = info.Kind + " " + info.Network + " " + info.Endpoint
+		})).
+		Build()
+	srv, c := startServer(t, &m)
+	defer srv.Shutdown(context.Background())
+	defer c.Close()
+
+	_ = c.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"transport","id":1}` + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got, err := bufio.NewReader(c).ReadString('\n')
+	if want := `"result":"socket tcp ` + c.RemoteAddr().String() + `"`; err != nil || !strings.Contains(got, want) {
+		t.Errorf("ReadString() = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestServer_WithCompression(t *testing.T) {
+	m := jrpc.NewManagerBuilder().Add("add", &addMethod{}).Build()
+	srv, c := startServer(t, &m, jrpc.WithCompression(jrpc.GzipCodec()))
+	defer srv.Shutdown(context.Background())
+	defer c.Close()
+	_ = c.SetDeadline(time.Now().Add(time.Second))
+
+	r := bufio.NewReader(c)
+	if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"rpc.compression","params":["zstd","gzip"],"id":0}` + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if got, err := r.ReadString('\n'); err != nil || got != `{"jsonrpc":"2.0","id":0,"result":"gzip"}`+"\n" {
+		t.Fatalf("ReadString() = %v, %v, want gzip selected", got, err)
+	}
+
+	var msg bytes.Buffer
+	zw := gzip.NewWriter(&msg)
+	_, _ = zw.Write([]byte(`{"jsonrpc":"2.0","method":"add","params":{"v1":1,"v2":2},"id":1}`))
+	_ = zw.Close()
+	frame := make([]byte, 4, 4+msg.Len())
+	binary.BigEndian.PutUint32(frame, uint32(msg.Len()))
+	if _, err := c.Write(append(frame, msg.Bytes()...)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if _, err := io.ReadFull(r, frame[:4]); err != nil {
+		t.Fatalf("ReadFull() error = %v", err)
+	}
+	zr, err := gzip.NewReader(io.LimitReader(r, int64(binary.BigEndian.Uint32(frame))))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	got, err := ioutil.ReadAll(zr)
+	if want := `{"jsonrpc":"2.0","id":1,"result":3}`; err != nil || string(got) != want {
+		t.Errorf("response = %s, %v, want %s", got, err, want)
+	}
+}
+
+func TestServer_WithMaxFrameSize(t *testing.T) {
+	m := jrpc.NewManagerBuilder().Add("add", &addMethod{}).Build()
+	srv, c := startServer(t, &m, jrpc.WithCompression(jrpc.GzipCodec()), jrpc.WithMaxFrameSize(1024))
+	defer srv.Shutdown(context.Background())
+	defer c.Close()
+	_ = c.SetDeadline(time.Now().Add(time.Second))
+
+	r := bufio.NewReader(c)
+	if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"rpc.compression","params":["gzip"],"id":0}` + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := r.ReadString('\n'); err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+
+	// The length over the limit closes the connection without waiting for the frame
+	frame := make([]byte, 4)
+	binary.BigEndian.PutUint32(frame, 1025)
+	if _, err := c.Write(frame); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Errorf("ReadByte() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestServer_Hello(t *testing.T) {
+	m := jrpc.NewManagerBuilder().Add("add", &addMethod{}).Enable(jrpc.Cancellation()).Build()
+
+	tests := []struct {
+		name     string
+		features string
+		want     string
+		wantPing bool
+	}{
+		{
+			name:     "Heartbeat Enabled",
+			features: `["heartbeat","cancellation","unknown"]`,
+			want:     `{"features":["heartbeat","cancellation"],"batchConcurrency":1,"maxPipelined":16}`,
+			wantPing: true,
+		},
+		{
+			name:     "Heartbeat Disabled",
+			features: `["cancellation"]`,
+			want:     `{"features":["cancellation"],"batchConcurrency":1,"maxPipelined":16}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, c := startServer(t, &m, jrpc.WithHeartbeat(20*time.Millisecond))
+			defer srv.Shutdown(context.Background())
+			defer c.Close()
+			_ = c.SetDeadline(time.Now().Add(time.Second))
+
+			r := bufio.NewReader(c)
+			if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"rpc.hello","params":{"features":` + tt.features + `},"id":0}` + "\n")); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			// A ping can be sent before the hello is read
+			var got string
+			var err error
+			for {
+				if got, err = r.ReadString('\n'); err != nil || !strings.Contains(got, "rpc.ping") {
+					break
+				}
+			}
+			if want := `{"jsonrpc":"2.0","id":0,"result":` + tt.want + "}\n"; err != nil || got != want {
+				t.Fatalf("ReadString() = %v, %v, want %v", got, err, want)
+			}
+
+			time.Sleep(60 * time.Millisecond)
+			if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"add","params":{"v1":1,"v2":2},"id":1}` + "\n")); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			got, err = r.ReadString('\n')
+			if ping := strings.Contains(got, "rpc.ping"); err != nil || ping != tt.wantPing {
+				t.Errorf("ReadString() = %v, %v, want ping %v", got, err, tt.wantPing)
+			}
+		})
+	}
+}
+
+func TestServer_Hello_Cancellation(t *testing.T) {
+	m := jrpc.NewManagerBuilder().Add("add", &addMethod{}).Enable(jrpc.Cancellation()).Build()
+
+	tests := []struct {
+		name     string
+		features string
+		want     string
+	}{
+		{name: "Requested", features: `["cancellation"]`, want: `{"jsonrpc":"2.0","id":1,"result":false}`},
+		{name: "Not Requested", features: `[]`, want: `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found","data":"rpc.cancel"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, c := startServer(t, &m)
+			defer srv.Shutdown(context.Background())
+			defer c.Close()
+			_ = c.SetDeadline(time.Now().Add(time.Second))
+
+			r := bufio.NewReader(c)
+			if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"rpc.hello","params":{"features":` + tt.features + `},"id":0}` + "\n")); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if _, err := r.ReadString('\n'); err != nil {
+				t.Fatalf("ReadString() error = %v", err)
+			}
+			if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"rpc.cancel","params":{"id":9},"id":1}` + "\n")); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got, err := r.ReadString('\n'); err != nil || got != tt.want+"\n" {
+				t.Errorf("ReadString() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_WithServerPreFilter(t *testing.T) {
+	m := jrpc.NewManagerBuilder().Add("add", &addMethod{}).Build()
+	srv, c := startServer(t, &m, jrpc.WithServerPreFilter(func(r *jrpc.RawRequest) error {
+		if bytes.Contains(r.Body, []byte(`"admin"`)) {
+			return jrpc.NewError(1001, "admin is not allowed")
+		}
+		return nil
+	}))
+	defer srv.Shutdown(context.Background())
+	defer c.Close()
+	_ = c.SetDeadline(time.Now().Add(time.Second))
+
+	r := bufio.NewReader(c)
+	if _, err := c.Write([]byte(`{"jsonrpc":"2.0","method":"admin","id":1}` + "\n" + `{"jsonrpc":"2.0","method":"add","params":{"v1":1,"v2":2},"id":2}` + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if got, err := r.ReadString('\n'); err != nil || !strings.Contains(got, `"code":1001`) {
+		t.Errorf("ReadString() = %v, %v, want the rejection", got, err)
+	}
+	if got, err := r.ReadString('\n'); err != nil || !strings.Contains(got, `"result":3`) {
+		t.Errorf("ReadString() = %v, %v, want the add result", got, err)
+	}
+}
@@ -0,0 +1,163 @@
+package jrpc2go
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// Stats are the counters of the requests executed by the Manager. The Methods are keyed by the
+// name the method was registered with, like the pattern of AddPattern or the name of AddVersion
+// without the version.
+type Stats struct {
+	Requests      int64                  `json:"requests"`
+	Errors        int64                  `json:"errors"`
+	Canceled      int64                  `json:"canceled"`
+	Deprecated    int64                  `json:"deprecated"`
+	RejectedConns int64                  `json:"rejectedConns"`
+	Methods       map[string]MethodStats `json:"methods"`
+	Heartbeat     HeartbeatStats         `json:"heartbeat"`
+}
+
+// MethodStats are the counters and the latency summary of a single method.
+type MethodStats struct {
+	Requests   int64            `json:"requests"`
+	Errors     int64            `json:"errors"`
+	Deprecated int64            `json:"deprecated,omitempty"`
+	Latency    LatencySummary   `json:"latency"`
+	Cost       map[string]int64 `json:"cost,omitempty"`
+}
+
+// LatencySummary summarizes the execution time of a method in milliseconds.
+type LatencySummary struct {
+	Total float64 `json:"total"`
+	Mean  float64 `json:"mean"`
+	Max   float64 `json:"max"`
+}
+
+// statsCollector aggregates the stats of the requests executed.
+type statsCollector struct {
+	mu         sync.Mutex
+	requests   int64
+	errors     int64
+	canceled   int64
+	deprecated int64
+	rejected   int64
+	methods    map[string]*MethodStats
+	heartbeat  HeartbeatStats
+}
+
+// newStatsCollector returns an empty collector.
+func newStatsCollector() *statsCollector {
+	return &statsCollector{methods: make(map[string]*MethodStats)}
+}
+
+// add will count the request on the totals and, when the method exists, on the stats of the
+// method registered with the name, with the cost reported by it.
+func (s *statsCollector) add(name string, res *Response, elapsed time.Duration, costs map[string]int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if res.Error != nil {
+		s.errors++
+		if res.Error.Code == errCodeClientCanceled {
+			s.canceled++
+		}
+	}
+	//! Unknown methods are only counted on the totals to keep the map bounded
+	if res.Error != nil && (res.Error.Code == errCodeMethodNotFound || res.Error.Code == errCodeInvalidRPCVersion ||
+		res.Error.Code == errCodeInvalidRequest) {
+		return
+	}
+
+	ms := s.method(name)
+	ms.Requests++
+	if res.Error != nil {
+		ms.Errors++
+	}
+	d := float64(elapsed) / float64(time.Millisecond)
+	ms.Latency.Total += d
+	ms.Latency.Mean = ms.Latency.Total / float64(ms.Requests)
+	if d > ms.Latency.Max {
+		ms.Latency.Max = d
+	}
+	if len(costs) > 0 && ms.Cost == nil {
+		ms.Cost = make(map[string]int64, len(costs))
+	}
+	for kind, n := range costs {
+		ms.Cost[kind] += n
+	}
+}
+
+// addDeprecated will count a call of the deprecated method.
+func (s *statsCollector) addDeprecated(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deprecated++
+	s.method(name).Deprecated++
+}
+
+// addRejectedConn will count a connection rejected by the Server admission limits.
+func (s *statsCollector) addRejectedConn() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejected++
+}
+
+// method returns the stats of the method with the name, the lock must be held.
+func (s *statsCollector) method(name string) *MethodStats {
+	ms, ok := s.methods[name]
+	if !ok {
+		ms = &MethodStats{}
+		s.methods[name] = ms
+	}
+	return ms
+}
+
+// snapshot returns a copy of the stats collected.
+func (s *statsCollector) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := Stats{
+		Requests:      s.requests,
+		Errors:        s.errors,
+		Canceled:      s.canceled,
+		Deprecated:    s.deprecated,
+		RejectedConns: s.rejected,
+		Methods:       make(map[string]MethodStats, len(s.methods)),
+		Heartbeat:     s.heartbeat,
+	}
+	for name, ms := range s.methods {
+		cp := *ms
+		if ms.Cost != nil {
+			cp.Cost = make(map[string]int64, len(ms.Cost))
+			for kind, n := range ms.Cost {
+				cp.Cost[kind] = n
+			}
+		}
+		st.Methods[name] = cp
+	}
+	return st
+}
+
+// publish will publish the stats on expvar as prefix+"requests", prefix+"errors" and prefix+"methods".
+func (s *statsCollector) publish(prefix string) {
+	expvar.Publish(prefix+"requests", expvar.Func(func() interface{} {
+		return s.snapshot().Requests
+	}))
+	expvar.Publish(prefix+"errors", expvar.Func(func() interface{} {
+		return s.snapshot().Errors
+	}))
+	expvar.Publish(prefix+"methods", expvar.Func(func() interface{} {
+		return s.snapshot().Methods
+	}))
+}
+
+// Stats returns the counters and latency summaries of the requests executed, they are only
+// collected when ManagerBuilder.EnableStats or ManagerBuilder.PublishExpvar is used.
+func (m *Manager) Stats() Stats {
+	if m.stats == nil {
+		return Stats{Methods: map[string]MethodStats{}}
+	}
+	return m.stats.snapshot()
+}
@@ -0,0 +1,128 @@
+package jrpc2go_test
+
+import (
+	"context"
+	"expvar"
+	"regexp"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestManager_Stats(t *testing.T) {
+	manager := jrpc.NewManagerBuilder().
+		PublishExpvar("stats_test.").
+		Add("echo", &echoMethod{}).
+		Build()
+
+	reqs := []string{
+		`{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`,
+		`{"jsonrpc":"2.0","method":"echo","params":1,"id":2}`,
+		`{"jsonrpc":"2.0","method":"unknown","id":3}`,
+	}
+	for _, r := range reqs {
+		var out strings.Builder
+		if err := manager.Handle(context.Background(), strings.NewReader(r), &out); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+
+	st := manager.Stats()
+	if st.Requests != 3 || st.Errors != 2 {
+		t.Errorf("Stats() = %v requests %v errors, want 3 requests 2 errors", st.Requests, st.Errors)
+	}
+	if len(st.Methods) != 1 {
+		t.Fatalf("Stats() methods = %v, want only echo", st.Methods)
+	}
+	if echo := st.Methods["echo"]; echo.Requests != 2 || echo.Errors != 1 {
+		t.Errorf("Stats() echo = %+v, want 2 requests 1 error", echo)
+	}
+
+	if got := expvar.Get("stats_test.requests").String(); got != "3" {
+		t.Errorf("expvar requests = %v, want 3", got)
+	}
+	if got := expvar.Get("stats_test.methods").String(); !strings.Contains(got, `"echo"`) {
+		t.Errorf("expvar methods = %v, want echo", got)
+	}
+}
+
+func TestManager_Stats_RegisteredName(t *testing.T) {
+	manager := jrpc.NewManagerBuilder().
+		EnableStats().
+		AddPattern("users.*", &echoMethod{}).
+		AddPrefix("admin/", &echoMethod{}).
+		AddRegexp(regexp.MustCompile(`^v[0-9]+\.echo$`), &echoMethod{}).
+		AddVersion("sum", "v1", 1, &addMethod{}).
+		AddVersion("sum", "v2", 1, &addMethod{}).
+		Build()
+
+	reqs := []string{
+		`{"jsonrpc":"2.0","method":"users.get","params":"a","id":1}`,
+		`{"jsonrpc":"2.0","method":"users.list","params":"a","id":2}`,
+		`{"jsonrpc":"2.0","method":"admin/reset","params":"a","id":3}`,
+		`{"jsonrpc":"2.0","method":"v1.echo","params":"a","id":4}`,
+		`{"jsonrpc":"2.0","method":"v2.echo","params":"a","id":5}`,
+		`{"jsonrpc":"2.0","method":"sum@v1","params":{"v1":1,"v2":2},"id":6}`,
+		`{"jsonrpc":"2.0","method":"sum@v2","params":{"v1":1,"v2":2},"id":7}`,
+	}
+	for _, r := range reqs {
+		var out strings.Builder
+		if err := manager.Handle(context.Background(), strings.NewReader(r), &out); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+
+	want := map[string]int64{"users.*": 2, "admin/": 1, `^v[0-9]+\.echo$`: 2, "sum": 2}
+	st := manager.Stats()
+	if len(st.Methods) != len(want) {
+		t.Errorf("Stats() methods = %v, want %v", st.Methods, want)
+	}
+	for name, n := range want {
+		if got := st.Methods[name].Requests; got != n {
+			t.Errorf("Stats() %s requests = %v, want %v", name, got, n)
+		}
+	}
+}
+
+func TestManager_Stats_Cost(t *testing.T) {
+	var reports []jrpc.CostReport
+	manager := jrpc.NewManagerBuilder().
+		EnableStats().
+		OnCost(func(ctx context.Context, r jrpc.CostReport) { reports = append(reports, r) }).
+		Add("scan", jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			jrpc.AddCost(req.Context(), "rows", 10)
+			jrpc.AddCost(req.Context(), "rows", 5)
+			jrpc.AddCost(req.Context(), "bytes", 512)
+			resp.Result = true
+		})).
+		Add("echo", &echoMethod{}).
+		Build()
+
+	reqs := []string{
+		`{"jsonrpc":"2.0","method":"scan","id":1}`,
+		`{"jsonrpc":"2.0","method":"scan","id":2}`,
+		`{"jsonrpc":"2.0","method":"echo","params":"a","id":3}`,
+	}
+	for _, r := range reqs {
+		var out strings.Builder
+		if err := manager.Handle(context.Background(), strings.NewReader(r), &out); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+
+	if len(reports) != 2 {
+		t.Fatalf("OnCost() reports = %v, want 2", reports)
+	}
+	if r := reports[0]; r.Method != "scan" || string(*r.ID) != "1" || r.Costs["rows"] != 15 || r.Costs["bytes"] != 512 {
+		t.Errorf("OnCost() report = %+v, want scan 1 with 15 rows and 512 bytes", r)
+	}
+
+	st := manager.Stats()
+	if cost := st.Methods["scan"].Cost; cost["rows"] != 30 || cost["bytes"] != 1024 {
+		t.Errorf("Stats() scan cost = %v, want 30 rows and 1024 bytes", cost)
+	}
+	if cost := st.Methods["echo"].Cost; cost != nil {
+		t.Errorf("Stats() echo cost = %v, want nil", cost)
+	}
+}
@@ -0,0 +1,85 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// HandleStream will read a sequence of requests from r, newline-delimited or concatenated JSON
+// values, and write the response of each one to w as soon as it's executed, terminated by a
+// newline. It's meant for persistent transports like pipes or the standard input and output.
+//
+// The requests are executed in order and the invalid ones are replied with an error response.
+// It returns nil when r reaches the end, the error if r or w fail, or the parse error replied
+// for a malformed JSON value since the stream can't be resynchronized after it.
+func (m *Manager) HandleStream(ctx context.Context, r io.Reader, w io.Writer) error {
+	if r == nil {
+		return ErrNilReader
+	}
+
+	if w == nil {
+		return ErrNilWriter
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = withTransportInfo(ctx, TransportInfo{Kind: "stream"})
+
+	dec := json.NewDecoder(r)
+	var out bytes.Buffer
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			return nil
+		} else if err != nil {
+			var se *json.SyntaxError
+			if !errors.As(err, &se) && err != io.ErrUnexpectedEOF {
+				return err
+			}
+			perr := newError(errCodeParseError, err.Error())
+			if err := m.writeStream(w, &out, &Response{Version: version, Error: perr}); err != nil {
+				return err
+			}
+			return perr
+		}
+
+		out.Reset()
+		if err := m.Handle(ctx, bytes.NewReader(raw), &out); err != nil {
+			// The malformed requests are replied by Handle
+			var e *Error
+			if !errors.As(err, &e) {
+				return err
+			}
+		}
+		if err := writeLine(w, out.Bytes()); err != nil {
+			return err
+		}
+	}
+}
+
+// writeStream will encode the value with the Manager encoder into buf and write it to w.
+func (m *Manager) writeStream(w io.Writer, buf *bytes.Buffer, v interface{}) error {
+	buf.Reset()
+	if err := m.encoder.encode(buf, v); err != nil {
+		return err
+	}
+	return writeLine(w, buf.Bytes())
+}
+
+// writeLine will write b to w terminated by a newline and flush it, nothing is written if b is empty.
+func writeLine(w io.Writer, b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	if b[len(b)-1] != '\n' {
+		b = append(b, '\n')
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	return flush(w)
+}
@@ -0,0 +1,102 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestManager_HandleStream(t *testing.T) {
+	manager := jrpc.NewManagerBuilder().
+		SetTrailingNewline(false).
+		Add("echo", &echoMethod{}).
+		Build()
+
+	tests := []struct {
+		name    string
+		r       string
+		wantW   string
+		wantErr bool
+	}{
+		{
+			name: "Newline Delimited",
+			r: `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}` + "\n" +
+				`{"jsonrpc":"2.0","method":"echo","params":"b"}` + "\n" +
+				`[{"jsonrpc":"2.0","method":"echo","params":"c","id":2},{"jsonrpc":"2.0","method":"echo","params":"d","id":3}]` + "\n",
+			wantW: `{"jsonrpc":"2.0","id":1,"result":"a"}` + "\n" +
+				`[{"jsonrpc":"2.0","id":2,"result":"c"},{"jsonrpc":"2.0","id":3,"result":"d"}]` + "\n",
+		},
+		{
+			name: "Concatenated",
+			r:    `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}{"jsonrpc":"2.0","method":"echo","params":"b","id":2}`,
+			wantW: `{"jsonrpc":"2.0","id":1,"result":"a"}` + "\n" +
+				`{"jsonrpc":"2.0","id":2,"result":"b"}` + "\n",
+		},
+		{
+			name: "Invalid Request Continues",
+			r:    `[]{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`,
+			wantW: `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request","data":"no methods specified"}}` + "\n" +
+				`{"jsonrpc":"2.0","id":1,"result":"a"}` + "\n",
+		},
+		{
+			name:    "Malformed Stops",
+			r:       `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}{"jsonrpc"}`,
+			wantW:   `{"jsonrpc":"2.0","id":1,"result":"a"}` + "\n" + `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w bytes.Buffer
+			err := manager.HandleStream(context.Background(), strings.NewReader(tt.r), &w)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HandleStream() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.HasPrefix(w.String(), tt.wantW) {
+					t.Errorf("HandleStream() = %v, want prefix %v", w.String(), tt.wantW)
+				}
+				return
+			}
+			if w.String() != tt.wantW {
+				t.Errorf("HandleStream() = %v, want %v", w.String(), tt.wantW)
+			}
+		})
+	}
+}
+
+type flushWriter struct {
+	bytes.Buffer
+	flushed []string
+}
+
+func (w *flushWriter) Flush() error {
+	w.flushed = append(w.flushed, w.String())
+	return nil
+}
+
+func TestManager_HandleStream_Flush(t *testing.T) {
+	manager := jrpc.NewManagerBuilder().
+		Add("echo", &echoMethod{}).
+		Build()
+
+	var w flushWriter
+	r := `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}` + "\n" + `{"jsonrpc":"2.0","method":"echo","params":"b","id":2}` + "\n"
+	if err := manager.HandleStream(context.Background(), strings.NewReader(r), &w); err != nil {
+		t.Fatalf("HandleStream() error = %v", err)
+	}
+
+	first := `{"jsonrpc":"2.0","id":1,"result":"a"}` + "\n"
+	want := []string{first, first + `{"jsonrpc":"2.0","id":2,"result":"b"}` + "\n"}
+	if len(w.flushed) != len(want) {
+		t.Fatalf("HandleStream() flushes = %q, want %q", w.flushed, want)
+	}
+	for i := range want {
+		if w.flushed[i] != want[i] {
+			t.Errorf("HandleStream() flush %d = %q, want %q", i, w.flushed[i], want[i])
+		}
+	}
+}
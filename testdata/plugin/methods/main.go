@@ -0,0 +1,28 @@
+package main
+
+import (
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+type addMethod struct{}
+
+type addMethodParams struct {
+	V1 int64 `json:"v1"`
+	V2 int64 `json:"v2"`
+}
+
+func (m *addMethod) Execute(req *jrpc.Request, resp *jrpc.Response) {
+	var p addMethodParams
+	if err := req.ParseParams(&p); err != nil {
+		resp.Error = err
+		return
+	}
+	resp.Result = p.V1 + p.V2
+}
+
+// NewMethods is the constructor loaded by jrpc.LoadPlugin.
+func NewMethods() map[string]jrpc.Method {
+	return map[string]jrpc.Method{
+		"plugin/add": &addMethod{},
+	}
+}
@@ -0,0 +1,6 @@
+package main
+
+// NewMethods has the name of the constructor loaded by jrpc.LoadPlugin but not its signature.
+func NewMethods() map[string]interface{} {
+	return nil
+}
@@ -0,0 +1,97 @@
+package jrpc2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ParamsTransform rewrites the raw params of a legacy shape into the current one, the params are
+// nil when the request doesn't have them.
+//
+// An *Error returned is replied as it is, any other error is replied as ErrInvalidParams.
+type ParamsTransform func(params json.RawMessage) (json.RawMessage, error)
+
+// TransformParams returns a Method that rewrites the params with the transforms, in order, before
+// executing h, so the legacy versions of a method can be served by the current implementation:
+//
+//	mb.AddVersion("search", "v1", 0, jrpc.TransformParams(search, jrpc.RenameParams(map[string]string{"q": "query"})))
+func TransformParams(h Method, transforms ...ParamsTransform) Method {
+	if h == nil {
+		panic("jsonrpc: method should not be nil")
+	}
+	return MethodFunc(func(req *Request, resp *Response) {
+		var params json.RawMessage
+		if req.Params != nil {
+			params = *req.Params
+		}
+		for _, t := range transforms {
+			var err error
+			if params, err = t(params); err != nil {
+				var e *Error
+				if errors.As(err, &e) {
+					resp.Error = e
+					return
+				}
+				resp.Error = newError(errCodeInvalidParams, err.Error())
+				return
+			}
+		}
+
+		r2 := new(Request)
+		*r2 = *req
+		r2.Params = nil
+		if params != nil {
+			r2.Params = &params
+		}
+		h.Execute(r2, resp)
+	})
+}
+
+// RenameParams returns a ParamsTransform that renames the fields of the named params from the
+// old names, the keys, to the new names, the values. A field is not renamed if the new name is
+// already present, the other params are not changed.
+func RenameParams(names map[string]string) ParamsTransform {
+	return func(params json.RawMessage) (json.RawMessage, error) {
+		obj, ok := decodeObject(params)
+		if !ok {
+			return params, nil
+		}
+		for old, name := range names {
+			v, ok := obj[old]
+			if !ok {
+				continue
+			}
+			if _, exists := obj[name]; !exists {
+				obj[name] = v
+			}
+			delete(obj, old)
+		}
+		return json.Marshal(obj)
+	}
+}
+
+// NamePositionalParams returns a ParamsTransform that wraps the positional params, a JSON array,
+// into named params with the names in order, the other params are not changed.
+//
+// It fails if there are more params than names.
+func NamePositionalParams(names ...string) ParamsTransform {
+	return func(params json.RawMessage) (json.RawMessage, error) {
+		if b := bytes.TrimSpace(params); len(b) == 0 || b[0] != '[' {
+			return params, nil
+		}
+		var values []json.RawMessage
+		if err := json.Unmarshal(params, &values); err != nil {
+			return nil, err
+		}
+		if len(values) > len(names) {
+			return nil, fmt.Errorf("expected at most %d params, got %d", len(names), len(values))
+		}
+		obj := make(map[string]json.RawMessage, len(values))
+		for i, v := range values {
+			obj[names[i]] = v
+		}
+		return json.Marshal(obj)
+	}
+}
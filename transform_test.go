@@ -0,0 +1,51 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestTransformParams(t *testing.T) {
+	m := jrpc.NewManagerBuilder().
+		Add("add", &addMethod{}).
+		AddVersion("sum", "v1", 0, jrpc.TransformParams(&addMethod{}, jrpc.RenameParams(map[string]string{"a": "v1", "b": "v2"}))).
+		AddVersion("sum", "v0", 0, jrpc.TransformParams(&addMethod{}, jrpc.NamePositionalParams("v1", "v2"))).
+		Build()
+
+	tests := []struct {
+		name  string
+		r     string
+		wantW string
+	}{
+		{
+			name:  "Renamed",
+			r:     `{"jsonrpc":"2.0","method":"sum@v1","id":1,"params":{"a":1,"b":2}}`,
+			wantW: `{"jsonrpc":"2.0","id":1,"result":3}`,
+		},
+		{
+			name:  "Positional",
+			r:     `{"jsonrpc":"2.0","method":"sum@v0","id":2,"params":[2,2]}`,
+			wantW: `{"jsonrpc":"2.0","id":2,"result":4}`,
+		},
+		{
+			name:  "Too Many Positional",
+			r:     `{"jsonrpc":"2.0","method":"sum@v0","id":3,"params":[1,2,3]}`,
+			wantW: `{"jsonrpc":"2.0","id":3,"error":{"code":-32602,"message":"Invalid method parameter(s)","data":"expected at most 2 params, got 3"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			if err := m.Handle(context.Background(), strings.NewReader(tt.r), w); err != nil {
+				t.Fatalf("Manager.Handle() error = %v", err)
+			}
+			if gotW := strings.TrimSpace(w.String()); gotW != tt.wantW {
+				t.Errorf("Manager.Handle() result = %v, want %v", gotW, tt.wantW)
+			}
+		})
+	}
+}
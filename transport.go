@@ -0,0 +1,62 @@
+package jrpc2go
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+)
+
+// TransportInfo describes the transport that received the request, so middleware can apply
+// different policies per transport, like rejecting the admin methods over plain TCP.
+//
+// Kind - The transport that received the request: "http", "rest", "graphql", "longpoll",
+// "socket", "stream" or "queue".
+//
+// Network - The network of the socket connection, "tcp" or "unix", empty for the other kinds.
+//
+// Endpoint - The local address or the URL the request was received on, empty if unknown.
+//
+// TLS - The state of the TLS connection, nil if the transport is not encrypted.
+type TransportInfo struct {
+	Kind     string
+	Network  string
+	Endpoint string
+	TLS      *tls.ConnectionState
+}
+
+// ContextWithTransportInfo returns a copy of ctx with the description of the transport, the
+// transports of this package set it on their requests unless it's already set.
+func ContextWithTransportInfo(ctx context.Context, info TransportInfo) context.Context {
+	return context.WithValue(ctx, transportInfoKey, info)
+}
+
+// TransportInfoFromContext returns the description of the transport that received the request,
+// ok is false if it's unknown.
+func TransportInfoFromContext(ctx context.Context) (info TransportInfo, ok bool) {
+	info, ok = ctx.Value(transportInfoKey).(TransportInfo)
+	return info, ok
+}
+
+// withTransportInfo returns ctx with the info if it doesn't have one.
+func withTransportInfo(ctx context.Context, info TransportInfo) context.Context {
+	if _, ok := TransportInfoFromContext(ctx); ok {
+		return ctx
+	}
+	return ContextWithTransportInfo(ctx, info)
+}
+
+// transportKind returns the kind of the transport that received the request, empty if unknown.
+func transportKind(ctx context.Context) string {
+	info, _ := TransportInfoFromContext(ctx)
+	return info.Kind
+}
+
+// httpTransportInfo returns the description of the transport of the HTTP request received by
+// the handler of the kind, like "http" or "rest".
+func httpTransportInfo(r *http.Request, kind string) TransportInfo {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return TransportInfo{Kind: kind, Endpoint: scheme + "://" + r.Host + r.URL.Path, TLS: r.TLS}
+}
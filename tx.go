@@ -0,0 +1,60 @@
+package jrpc2go
+
+import "context"
+
+// Tx is a resource with commit and rollback semantics opened for the execution of a method,
+// like a *sql.Tx.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// TxBeginner opens the Tx for the execution of the request.
+type TxBeginner func(ctx context.Context, req *Request) (Tx, error)
+
+// TxFromContext returns the Tx opened by the TxInterceptor for the request or nil if none.
+func TxFromContext(ctx context.Context) Tx {
+	tx, _ := ctx.Value(txKey).(Tx)
+	return tx
+}
+
+// TxInterceptor returns a Middleware that opens a Tx with begin before the execution of the
+// method, stores it on the request context, available with TxFromContext, and commits it if
+// the method succeeds or rolls it back if the method replies an error or panics.
+//
+// The Tx is also rolled back if the request context is done when the method returns, since the
+// client already received the timeout or the cancellation error.
+//
+// If begin or the commit fail the method replies an internal error.
+func TxInterceptor(begin TxBeginner) Middleware {
+	if begin == nil {
+		panic("jsonrpc: transaction beginner should not be nil")
+	}
+	return func(next Method) Method {
+		return MethodFunc(func(req *Request, resp *Response) {
+			tx, err := begin(req.Context(), req)
+			if err != nil {
+				resp.Error = newError(errCodeInternal, err.Error())
+				return
+			}
+
+			committed := false
+			defer func() {
+				if !committed {
+					_ = tx.Rollback()
+				}
+			}()
+
+			req = req.WithContext(context.WithValue(req.Context(), txKey, tx))
+			next.Execute(req, resp)
+			if resp.Error != nil || req.Context().Err() != nil {
+				return
+			}
+
+			committed = true
+			if err := tx.Commit(); err != nil {
+				resp.SetError(newError(errCodeInternal, err.Error()))
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+type fakeTx struct {
+	commitErr error
+	state     string
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.state = "committed"
+	return tx.commitErr
+}
+
+func (tx *fakeTx) Rollback() error {
+	if tx.state == "" {
+		tx.state = "rolled back"
+	}
+	return nil
+}
+
+func TestTxInterceptor(t *testing.T) {
+	tests := []struct {
+		name      string
+		r         string
+		beginErr  error
+		commitErr error
+		wantState string
+		wantW     string
+	}{
+		{
+			name:      "Commit",
+			r:         `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`,
+			wantState: "committed",
+			wantW:     `{"jsonrpc":"2.0","id":1,"result":"a"}`,
+		},
+		{
+			name:      "Rollback",
+			r:         `{"jsonrpc":"2.0","method":"echo","params":1,"id":1}`,
+			wantState: "rolled back",
+			wantW:     `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid method parameter(s)"`,
+		},
+		{
+			name:      "Commit Failure",
+			r:         `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`,
+			commitErr: errors.New("conflict"),
+			wantState: "committed",
+			wantW:     `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"Internal error","data":"conflict"}}`,
+		},
+		{
+			name:     "Begin Failure",
+			r:        `{"jsonrpc":"2.0","method":"echo","params":"a","id":1}`,
+			beginErr: errors.New("no connection"),
+			wantW:    `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"Internal error","data":"no connection"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &fakeTx{commitErr: tt.commitErr}
+			begin := func(ctx context.Context, req *jrpc.Request) (jrpc.Tx, error) {
+				if tt.beginErr != nil {
+					return nil, tt.beginErr
+				}
+				return tx, nil
+			}
+			echo := jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+				if jrpc.TxFromContext(req.Context()) != tx {
+					resp.Error = &jrpc.Error{Code: 1, Message: "missing transaction"}
+					return
+				}
+				(&echoMethod{}).Execute(req, resp)
+			})
+			m := jrpc.NewManagerBuilder().
+				Use(jrpc.TxInterceptor(begin)).
+				Add("echo", echo).
+				Build()
+
+			var out bytes.Buffer
+			if err := m.Handle(context.Background(), strings.NewReader(tt.r), &out); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if !strings.HasPrefix(out.String(), tt.wantW) {
+				t.Errorf("Handle() = %v, want %v", out.String(), tt.wantW)
+			}
+			if tx.state != tt.wantState {
+				t.Errorf("Tx state = %q, want %q", tx.state, tt.wantState)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package jrpc2go
+
+import "sync"
+
+// values is the value store of a request, shared by the copies of the request.
+type values struct {
+	mu sync.RWMutex
+	m  map[string]interface{}
+}
+
+// newValues returns an empty value store, it's set when the request is created so the goroutines
+// of the methods can share it without a race.
+func newValues() *values {
+	return &values{m: make(map[string]interface{})}
+}
+
+// clone returns a copy of the store, nil if v is nil.
+func (v *values) clone() *values {
+	if v == nil {
+		return nil
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	c := &values{m: make(map[string]interface{}, len(v.m))}
+	for k, val := range v.m {
+		c.m[k] = val
+	}
+	return c
+}
+
+// Set stores the value v with the key on the request, so middleware can pass derived data, like
+// the authenticated user, to the methods without defining context key types. It's safe to call
+// from multiple goroutines.
+//
+// The values are shared by the copies of the request made with WithContext. A Request created
+// with a literal, like in the tests of a method, gets the store on the first Set instead, so it
+// must be called before the request is shared with other goroutines.
+func (r *Request) Set(key string, v interface{}) {
+	if r.values == nil {
+		r.values = newValues()
+	}
+	r.values.mu.Lock()
+	r.values.m[key] = v
+	r.values.mu.Unlock()
+}
+
+// Get returns the value stored with the key on the request and if it exists, the value must be
+// asserted to its type, like `u, ok := v.(*User)`.
+func (r *Request) Get(key string) (interface{}, bool) {
+	if r.values == nil {
+		return nil, false
+	}
+	r.values.mu.RLock()
+	defer r.values.mu.RUnlock()
+	v, ok := r.values.m[key]
+	return v, ok
+}
+
+// GetString returns the string stored with the key on the request or empty if it doesn't exist
+// or it's not a string.
+func (r *Request) GetString(key string) string {
+	v, _ := r.Get(key)
+	s, _ := v.(string)
+	return s
+}
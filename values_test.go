@@ -0,0 +1,105 @@
+package jrpc2go_test
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	jrpc "github.com/fabiodcorreia/jrpc2go"
+)
+
+func TestRequest_Values(t *testing.T) {
+	auth := func(next jrpc.Method) jrpc.Method {
+		return jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+			req.Set("user", "alice")
+			req.Set("roles", []string{"admin"})
+			next.Execute(req.WithContext(req.Context()), resp)
+		})
+	}
+	whoami := jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+		roles, _ := req.Get("roles")
+		if _, ok := req.Get("missing"); ok {
+			resp.Error = &jrpc.Error{Code: 1, Message: "unexpected value"}
+			return
+		}
+		resp.Result = req.GetString("user") + ":" + roles.([]string)[0]
+	})
+	m := jrpc.NewManagerBuilder().
+		Use(auth).
+		Add("whoami", whoami).
+		Build()
+
+	var out bytes.Buffer
+	if err := m.Handle(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","method":"whoami","id":1}`), &out); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if want := `{"jsonrpc":"2.0","id":1,"result":"alice:admin"}` + "\n"; out.String() != want {
+		t.Errorf("Handle() = %v, want %v", out.String(), want)
+	}
+}
+
+func TestRequest_Values_Concurrent(t *testing.T) {
+	// The goroutines of the method share the request, the first Set must not race with the others
+	fanout := jrpc.MethodFunc(func(req *jrpc.Request, resp *jrpc.Response) {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				key := strconv.Itoa(i)
+				req.Set(key, i)
+				_, _ = req.Get(key)
+			}(i)
+		}
+		wg.Wait()
+
+		n := 0
+		for i := 0; i < 8; i++ {
+			if v, ok := req.Get(strconv.Itoa(i)); ok && v == i {
+				n++
+			}
+		}
+		resp.Result = n
+	})
+	m := jrpc.NewManagerBuilder().
+		Add("fanout", fanout).
+		Build()
+
+	tests := []struct {
+		name string
+		r    string
+	}{
+		{name: "Fast Path", r: `{"jsonrpc":"2.0","method":"fanout","id":1}`},
+		{name: "Unmarshal", r: `{"jsonrpc":"2.0","method":"fanout","id":1,"extra":{"a":[1]}}`},
+		{name: "Batch", r: `[{"jsonrpc":"2.0","method":"fanout","id":1}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := m.Handle(context.Background(), strings.NewReader(tt.r), &out); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if !strings.Contains(out.String(), `"id":1,"result":8}`) {
+				t.Errorf("Handle() = %v, want 8 values", out.String())
+			}
+		})
+	}
+}
+
+func TestRequest_Values_Literal(t *testing.T) {
+	req := &jrpc.Request{Method: "whoami"}
+	if _, ok := req.Get("user"); ok {
+		t.Errorf("Request.Get() ok = true, want false before Set")
+	}
+	req.Set("user", "alice")
+	if got := req.WithContext(context.Background()).GetString("user"); got != "alice" {
+		t.Errorf("Request.GetString() = %v, want alice", got)
+	}
+	req.Set("user", 1)
+	if got := req.GetString("user"); got != "" {
+		t.Errorf("Request.GetString() = %v, want empty for a non string", got)
+	}
+}
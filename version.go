@@ -0,0 +1,44 @@
+package jrpc2go
+
+import "strings"
+
+// VersionCheck is how the jsonrpc member of the requests is validated.
+type VersionCheck int
+
+const (
+	// VersionStrict accepts only the requests with the jsonrpc member exactly "2.0".
+	VersionStrict VersionCheck = iota
+	// VersionLenient also accepts "2.0" surrounded by whitespace and the notifications without
+	// the jsonrpc member, which some clients send.
+	VersionLenient
+	// VersionOff accepts the requests with any jsonrpc member or without it.
+	VersionOff
+)
+
+// SetVersionCheck specifies how the jsonrpc member of the requests is validated, the responses
+// of the accepted requests always have the jsonrpc member "2.0". The requests rejected are
+// replied with the error selected by SetStrictVersion.
+//
+// Default is VersionStrict.
+func (mb *ManagerBuilder) SetVersionCheck(vc VersionCheck) *ManagerBuilder {
+	mb.versionCheck = vc
+	return mb
+}
+
+// WithVersionCheck overrides how the jsonrpc member of the requests is validated.
+func WithVersionCheck(vc VersionCheck) Option {
+	return func(m *Manager) {
+		m.versionCheck = vc
+	}
+}
+
+// accept returns true if the version of the request is accepted.
+func (vc VersionCheck) accept(req *Request) bool {
+	switch vc {
+	case VersionLenient:
+		return strings.TrimSpace(req.Version) == version || req.Version == "" && req.ID == nil
+	case VersionOff:
+		return true
+	}
+	return req.Version == version
+}
@@ -0,0 +1,27 @@
+package jrpc2go
+
+import (
+	"io"
+	"net/http"
+)
+
+// ResponseWriter is a writer of a streaming transport, like SSE, chunked HTTP or WebSocket, that
+// buffers the data until it's flushed.
+//
+// Handle and HandleStream flush the writer after each response so it reaches the client
+// promptly. The http.Flusher of the http.ResponseWriter is also supported.
+type ResponseWriter interface {
+	io.Writer
+	Flush() error
+}
+
+// flush will send the buffered data of w to the client if w supports it.
+func flush(w io.Writer) error {
+	switch f := w.(type) {
+	case ResponseWriter:
+		return f.Flush()
+	case http.Flusher:
+		f.Flush()
+	}
+	return nil
+}